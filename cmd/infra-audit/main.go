@@ -0,0 +1,54 @@
+// infra-audit 审计日志工具
+//
+// 用法:
+//
+//	infra-audit verify [-key 密钥] 文件...
+//
+// 未指定-key时从环境变量AUDIT_SIGNING_KEY读取签名密钥。
+// 每个文件需要有同目录下的<文件>.head文件头，输出中的序号可与保存在其他系统的文件头比对。
+// 所有文件校验通过时退出码为0，否则为1。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/space-ark-x/infra-common/log/audit"
+)
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "verify" {
+		fmt.Fprintln(os.Stderr, "usage: infra-audit verify [-key key] file...")
+		os.Exit(2)
+	}
+	os.Exit(verify(os.Args[2:]))
+}
+
+// verify 校验审计文件并返回退出码
+func verify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	key := fs.String("key", os.Getenv("AUDIT_SIGNING_KEY"), "checkpoint signing key")
+	_ = fs.Parse(args)
+
+	if *key == "" {
+		fmt.Fprintln(os.Stderr, "infra-audit: signing key not set, use -key or AUDIT_SIGNING_KEY")
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "infra-audit: no files to verify")
+		return 2
+	}
+
+	code := 0
+	for _, path := range fs.Args() {
+		report, err := audit.VerifyFile(path, []byte(*key))
+		if err != nil {
+			fmt.Printf("FAIL %s: %v\n", path, err)
+			code = 1
+			continue
+		}
+		fmt.Printf("OK   %s: %d entries, %d checkpoints, head at sequence %d\n", path, report.Entries, report.Checkpoints, report.Seq)
+	}
+	return code
+}
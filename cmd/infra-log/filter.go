@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Record 一行解析后的日志
+type Record struct {
+	Raw    string         // 原始行
+	File   string         // 来源文件
+	Module string         // 模块名，优先取logger字段，否则由文件名推断
+	Level  zapcore.Level  // 日志级别
+	Time   time.Time      // 记录时间，无法解析时为零值
+	Fields map[string]any // 全部字段
+}
+
+// Filter 日志过滤条件
+type Filter struct {
+	MinLevel *zapcore.Level // 最低级别
+	Modules  []string       // 模块名，任一匹配即可
+	TraceId  string         // 追踪ID
+	Since    time.Time      // 起始时间(含)
+	Until    time.Time      // 结束时间(不含)
+	Where    []Expr         // 字段表达式，需全部满足
+}
+
+// Match 判断记录是否满足所有过滤条件
+func (f *Filter) Match(r *Record) bool {
+	if f.MinLevel != nil && r.Level < *f.MinLevel {
+		return false
+	}
+	if len(f.Modules) > 0 && !contains(f.Modules, r.Module) {
+		return false
+	}
+	if f.TraceId != "" && traceIdOf(r) != f.TraceId {
+		return false
+	}
+	if !f.Since.IsZero() && (r.Time.IsZero() || r.Time.Before(f.Since)) {
+		return false
+	}
+	if !f.Until.IsZero() && (r.Time.IsZero() || !r.Time.Before(f.Until)) {
+		return false
+	}
+	for _, expr := range f.Where {
+		if !expr.Match(r.Fields) {
+			return false
+		}
+	}
+	return true
+}
+
+// traceIdKeys 追踪ID可能使用的字段名
+var traceIdKeys = []string{"traceId", "trace_id"}
+
+// traceIdOf 获取记录的追踪ID
+func traceIdOf(r *Record) string {
+	for _, key := range traceIdKeys {
+		if v, ok := r.Fields[key]; ok {
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
+
+// contains 判断字符串是否在列表中
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// operators 表达式支持的操作符，较长的操作符需排在前面
+var operators = []string{"!=", ">=", "<=", "=", ">", "<", "~"}
+
+// Expr 字段表达式，例如 status=500、latency>=1.5、error.message~timeout
+type Expr struct {
+	Key   string         // 字段路径，嵌套字段以"."分隔
+	Op    string         // 操作符
+	Value string         // 比较值
+	re    *regexp.Regexp // ~操作符使用的正则
+}
+
+// ParseExpr 解析字段表达式
+func ParseExpr(s string) (Expr, error) {
+	for i := 0; i < len(s); i++ {
+		for _, op := range operators {
+			if !strings.HasPrefix(s[i:], op) {
+				continue
+			}
+			expr := Expr{Key: strings.TrimSpace(s[:i]), Op: op, Value: strings.TrimSpace(s[i+len(op):])}
+			if expr.Key == "" {
+				return Expr{}, fmt.Errorf("invalid expression %q: missing field", s)
+			}
+			if op == "~" {
+				re, err := regexp.Compile(expr.Value)
+				if err != nil {
+					return Expr{}, fmt.Errorf("invalid expression %q: %w", s, err)
+				}
+				expr.re = re
+			}
+			return expr, nil
+		}
+	}
+	return Expr{}, fmt.Errorf("invalid expression %q: missing operator", s)
+}
+
+// Match 判断字段是否满足表达式，字段不存在时只有!=成立
+func (e Expr) Match(fields map[string]any) bool {
+	v, ok := lookup(fields, e.Key)
+	if !ok {
+		return e.Op == "!="
+	}
+	s := stringify(v)
+
+	switch e.Op {
+	case "=":
+		return s == e.Value
+	case "!=":
+		return s != e.Value
+	case "~":
+		return e.re.MatchString(s)
+	}
+
+	// 大小比较优先按数值比较，否则按字符串比较
+	cmp := strings.Compare(s, e.Value)
+	if a, err := strconv.ParseFloat(s, 64); err == nil {
+		if b, err := strconv.ParseFloat(e.Value, 64); err == nil {
+			cmp = compareFloat(a, b)
+		}
+	}
+	switch e.Op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	default:
+		return cmp <= 0
+	}
+}
+
+// compareFloat 比较两个浮点数
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// lookup 按"."分隔的路径查找嵌套字段
+// 完整的key存在时优先使用，以支持本身带"."的字段名
+func lookup(fields map[string]any, path string) (any, bool) {
+	if v, ok := fields[path]; ok {
+		return v, true
+	}
+	head, rest, found := strings.Cut(path, ".")
+	if !found {
+		return nil, false
+	}
+	nested, ok := fields[head].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	return lookup(nested, rest)
+}
+
+// stringify 将字段值转换为用于比较的字符串
+func stringify(v any) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// ParseTime 解析时间参数
+// 支持RFC3339、日期(2006-01-02)以及相对当前时间的时长(如30m、2h)
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
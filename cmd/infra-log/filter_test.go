@@ -0,0 +1,253 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/space-ark-x/infra-common/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestExprMatch(t *testing.T) {
+	fields := map[string]any{
+		"status":  float64(503),
+		"path":    "/api/users",
+		"latency": 1.5,
+		"error":   map[string]any{"message": "dial tcp: i/o timeout"},
+		"a.b":     "dotted",
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{expr: "status=503", want: true},
+		{expr: "status!=503", want: false},
+		{expr: "status>=500", want: true},
+		{expr: "status<500", want: false},
+		{expr: "latency>1", want: true},
+		{expr: "latency<=1.5", want: true},
+		{expr: "path=/api/users", want: true},
+		{expr: "path~^/api/", want: true},
+		{expr: "error.message~timeout", want: true},
+		{expr: "error.code=1", want: false},
+		{expr: "missing!=x", want: true},
+		{expr: "a.b=dotted", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := ParseExpr(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, expr.Match(fields))
+		})
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	for _, s := range []string{"status", "=5", "path~("} {
+		_, err := ParseExpr(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestParseRecord(t *testing.T) {
+	line := `{"level":"error","ts":"2026-01-02T10:00:00.000+0800","logger":"order","caller":"x.go:1","msg":"","pid":1,"traceId":"t-1"}`
+	r, ok := ParseRecord(line, "log/log_2026-01-02_order.log")
+	require.True(t, ok)
+	assert.Equal(t, "order", r.Module)
+	assert.Equal(t, "ERROR", r.Level.CapitalString())
+	assert.Equal(t, time.Date(2026, 1, 2, 2, 0, 0, 0, time.UTC), r.Time.UTC())
+	assert.Equal(t, "t-1", traceIdOf(r))
+
+	// 没有logger字段时由文件名推断模块
+	r, ok = ParseRecord(`{"level":"info"}`, "log/log_2026-01-02.log.1.gz")
+	require.True(t, ok)
+	assert.Equal(t, "default", r.Module)
+
+	_, ok = ParseRecord("not json", "x.log")
+	assert.False(t, ok)
+}
+
+func TestReadFilesAcrossModules(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "log_2026-01-02_order.log"),
+		`{"level":"info","ts":"2026-01-02T10:00:02.000Z","traceId":"t-1","step":2}`+"\n"+
+			`{"level":"info","ts":"2026-01-02T10:00:03.000Z","traceId":"t-2","step":9}`+"\n")
+
+	gz, err := os.Create(filepath.Join(dir, "log_2026-01-02_user.log.1.gz"))
+	require.NoError(t, err)
+	zw := gzip.NewWriter(gz)
+	_, err = zw.Write([]byte(`{"level":"warn","ts":"2026-01-02T10:00:01.000Z","traceId":"t-1","step":1}` + "\n"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	require.NoError(t, gz.Close())
+
+	files, err := ExpandFiles([]string{dir})
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	records := readAll(t, files, &Filter{TraceId: "t-1"})
+	require.Len(t, records, 2)
+	assert.Equal(t, "user", records[0].Module)
+	assert.Equal(t, "order", records[1].Module)
+
+	filter, err := buildFilter("warn", "", "2026-01-02T00:00:00Z", "", nil, nil)
+	require.NoError(t, err)
+	records = readAll(t, files, filter)
+	require.Len(t, records, 1)
+	assert.Equal(t, float64(1), records[0].Fields["step"])
+}
+
+func TestFollowRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "log_2026-01-02.log")
+	writeFile(t, path, `{"level":"info","step":0}`+"\n")
+
+	var mu sync.Mutex
+	steps := make([]any, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Follow(ctx, []string{dir}, 5*time.Millisecond, false, &Filter{}, func(r *Record) {
+			mu.Lock()
+			defer mu.Unlock()
+			steps = append(steps, r.Fields["step"])
+		})
+	}()
+	waitFor := func(n int) {
+		require.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(steps) >= n
+		}, time.Second, 5*time.Millisecond)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	appendFile(t, path, `{"level":"info","step":1}`+"\n")
+	waitFor(1)
+
+	// 轮转：旧文件改名，新建同名文件与次日文件
+	require.NoError(t, os.Rename(path, path+".1"))
+	writeFile(t, path, `{"level":"info","step":2}`+"\n")
+	writeFile(t, filepath.Join(dir, "log_2026-01-03.log"), `{"level":"info","step":3}`+"\n")
+	waitFor(3)
+
+	cancel()
+	require.NoError(t, <-done)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.ElementsMatch(t, []any{float64(1), float64(2), float64(3)}, steps)
+}
+
+func TestFollowHistory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "log_2026-01-02_order.log"),
+		`{"level":"error","ts":"2026-01-02T10:00:02.000Z","step":2}`+"\n"+
+			`{"level":"info","ts":"2026-01-02T10:00:03.000Z","step":9}`+"\n")
+	writeFile(t, filepath.Join(dir, "log_2026-01-02_user.log"), `{"level":"error","ts":"2026-01-02T10:00:01.000Z","step":1}`+"\n")
+
+	var mu sync.Mutex
+	steps := make([]any, 0)
+	level := zapcore.ErrorLevel
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Follow(ctx, []string{dir}, 5*time.Millisecond, true, &Filter{MinLevel: &level}, func(r *Record) {
+			mu.Lock()
+			defer mu.Unlock()
+			steps = append(steps, r.Fields["step"])
+		})
+	}()
+
+	// 历史记录按时间顺序输出后继续跟踪
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(steps) == 2
+	}, time.Second, 5*time.Millisecond)
+	appendFile(t, filepath.Join(dir, "log_2026-01-02_user.log"), `{"level":"error","ts":"2026-01-02T10:00:04.000Z","step":3}`+"\n")
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(steps) == 3
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	assert.Equal(t, []any{float64(1), float64(2), float64(3)}, steps)
+}
+
+func TestFollowClosesRotatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "log_2026-01-02.log")
+	writeFile(t, path, `{"level":"info"}`+"\n")
+	tail, err := openTail(path, true)
+	require.NoError(t, err)
+	defer tail.file.Close()
+
+	// 被移走后，读完剩余数据才关闭
+	appendFile(t, path, `{"level":"info"}`+"\n")
+	require.NoError(t, os.Rename(path, path+".1"))
+	tail.read(&Filter{}, func(*Record) {})
+	assert.NoError(t, tail.reopenIfRotated(&Filter{}, func(*Record) {}))
+	tail.read(&Filter{}, func(*Record) {})
+	assert.ErrorIs(t, tail.reopenIfRotated(&Filter{}, func(*Record) {}), errRemoved)
+
+	// 已有次日文件且没有新数据的旧文件被关闭
+	tails := map[string]*tailFile{
+		"log/log_2026-01-02_order.log": {},
+		"log/log_2026-01-03_order.log": {},
+		"log/log_2026-01-02_user.log":  {},
+		"log/log_2026-01-01.log":       {active: true},
+	}
+	files := []string{"log/log_2026-01-01.log", "log/log_2026-01-02.log", "log/log_2026-01-02_order.log", "log/log_2026-01-03_order.log", "log/log_2026-01-02_user.log"}
+	assert.Equal(t, []string{"log/log_2026-01-02_order.log"}, supersededFiles(tails, files))
+}
+
+// readAll 读取所有满足条件的记录
+func readAll(t *testing.T, files []string, filter *Filter) []*Record {
+	t.Helper()
+	records := make([]*Record, 0)
+	require.NoError(t, ReadFiles(files, filter, func(r *Record) {
+		records = append(records, r)
+	}))
+	return records
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+}
+
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.WriteString(data)
+	require.NoError(t, err)
+}
+
+func TestPretty(t *testing.T) {
+	r, ok := ParseRecord(`{"level":"error","ts":"2026-01-02T10:00:00.000Z","logger":"order","caller":"x.go:1","msg":"failed","traceId":"t-1","status":500}`, "log/log_2026-01-02_order.log")
+	require.True(t, ok)
+
+	want := log.ConsoleRecord{
+		Level:   log.ErrorLevel,
+		Time:    r.Time.Local(),
+		Module:  "order",
+		Message: "failed",
+		Fields:  map[string]any{"traceId": "t-1", "status": float64(500)},
+		Caller:  "x.go:1",
+	}.Text(false)
+	assert.Equal(t, want, Pretty(r, false))
+	assert.Contains(t, want, "[t-1] failed status=500 x.go:1")
+}
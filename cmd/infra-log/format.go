@@ -0,0 +1,30 @@
+package main
+
+import "github.com/space-ark-x/infra-common/log"
+
+// standardKeys 由Pretty单独展示的字段，追踪ID字段由控制台格式单独展示
+var standardKeys = map[string]bool{
+	"level": true, "ts": true, "logger": true, "caller": true, "msg": true, "stacktrace": true,
+}
+
+// Pretty 将记录格式化为便于阅读的单行文本，与日志库的控制台格式一致
+// 格式: 级别 时间 [模块] [追踪ID] 消息 key=value ... 调用位置
+func Pretty(r *Record, color bool) string {
+	record := log.ConsoleRecord{
+		Level:  r.Level,
+		Module: r.Module,
+		Fields: make(map[string]any, len(r.Fields)),
+	}
+	if !r.Time.IsZero() {
+		record.Time = r.Time.Local()
+	}
+	for k, v := range r.Fields {
+		if !standardKeys[k] {
+			record.Fields[k] = v
+		}
+	}
+	record.Message, _ = r.Fields["msg"].(string)
+	record.Caller, _ = r.Fields["caller"].(string)
+	record.Stack, _ = r.Fields["stacktrace"].(string)
+	return record.Text(color)
+}
@@ -0,0 +1,164 @@
+// infra-log 日志查看工具
+//
+// 读取或跟踪log目录下的JSON日志(log/log_<日期>_<模块>.log)，支持轮转后的.gz文件。
+//
+// 用法:
+//
+//	infra-log [选项] [文件或目录...]
+//
+// 示例:
+//
+//	infra-log -trace 5f1c... log/                  跨模块查看一个请求的所有日志
+//	infra-log -level error -since 1h -f            输出最近一小时的错误后继续跟踪新的错误
+//	infra-log -module order -where 'status>=500'   按字段表达式过滤
+//
+// 未指定文件时读取./log目录。-f只输出启动后新增的记录，同时指定-since时先输出该时间以来的记录。
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// stringList 可重复的字符串参数
+type stringList []string
+
+// String 实现flag.Value
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+// Set 实现flag.Value，支持逗号分隔的多个值
+func (s *stringList) Set(v string) error {
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*s = append(*s, item)
+		}
+	}
+	return nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run 解析参数并执行，返回退出码
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("infra-log", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var (
+		level    = fs.String("level", "", "minimum level: debug, info, warn, error, fatal")
+		traceId  = fs.String("trace", "", "only entries with this trace ID")
+		since    = fs.String("since", "", "start time: RFC3339, date or duration ago (e.g. 2h)")
+		until    = fs.String("until", "", "end time: RFC3339, date or duration ago")
+		follow   = fs.Bool("f", false, "follow files, including files created by rotation; with -since, print earlier entries first")
+		output   = fs.String("o", "pretty", "output format: pretty or raw")
+		noColor  = fs.Bool("no-color", false, "disable colors in pretty output")
+		interval = fs.Duration("interval", time.Second, "poll interval when following")
+		modules  stringList
+		where    stringList
+	)
+	fs.Var(&modules, "module", "module name, repeatable or comma separated")
+	fs.Var(&where, "where", "field expression such as status>=500 or error.message~timeout, repeatable")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	filter, err := buildFilter(*level, *traceId, *since, *until, modules, where)
+	if err != nil {
+		fmt.Fprintln(stderr, "infra-log:", err)
+		return 2
+	}
+
+	var printer func(r *Record)
+	switch *output {
+	case "raw":
+		printer = func(r *Record) { fmt.Fprintln(stdout, r.Raw) }
+	case "pretty":
+		color := !*noColor && isTerminal(stdout)
+		printer = func(r *Record) { fmt.Fprintln(stdout, Pretty(r, color)) }
+	default:
+		fmt.Fprintf(stderr, "infra-log: unknown output format %q\n", *output)
+		return 2
+	}
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{"log"}
+	}
+
+	if *follow {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := Follow(ctx, paths, *interval, *since != "", filter, printer); err != nil {
+			fmt.Fprintln(stderr, "infra-log:", err)
+			return 1
+		}
+		return 0
+	}
+
+	files, err := ExpandFiles(paths)
+	if err != nil {
+		fmt.Fprintln(stderr, "infra-log:", err)
+		return 1
+	}
+	if err := ReadFiles(files, filter, printer); err != nil {
+		fmt.Fprintln(stderr, "infra-log:", err)
+		return 1
+	}
+	return 0
+}
+
+// buildFilter 由命令行参数构建过滤条件
+func buildFilter(level, traceId, since, until string, modules, where []string) (*Filter, error) {
+	filter := &Filter{Modules: modules, TraceId: traceId}
+	now := time.Now()
+
+	if level != "" {
+		var l zapcore.Level
+		if err := l.UnmarshalText([]byte(level)); err != nil {
+			return nil, err
+		}
+		filter.MinLevel = &l
+	}
+	if since != "" {
+		t, err := ParseTime(since, now)
+		if err != nil {
+			return nil, err
+		}
+		filter.Since = t
+	}
+	if until != "" {
+		t, err := ParseTime(until, now)
+		if err != nil {
+			return nil, err
+		}
+		filter.Until = t
+	}
+	for _, s := range where {
+		expr, err := ParseExpr(s)
+		if err != nil {
+			return nil, err
+		}
+		filter.Where = append(filter.Where, expr)
+	}
+	return filter, nil
+}
+
+// isTerminal 判断输出是否为终端
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
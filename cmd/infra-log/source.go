@@ -0,0 +1,426 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"container/heap"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// fileNamePattern 日志文件名格式 log_<日期>[_<模块>].log[.N][.gz]
+var fileNamePattern = regexp.MustCompile(`^log_(\d{4}-\d{2}-\d{2})(?:_(.+?))?\.log(?:\.\d+)?(?:\.gz)?$`)
+
+// timeLayouts 日志时间字段可能使用的格式
+var timeLayouts = []string{"2006-01-02T15:04:05.000Z0700", time.RFC3339Nano}
+
+// ExpandFiles 展开文件参数，目录会展开为其中的日志文件，支持通配符
+// 结果按文件名排序，同一模块的文件按日期排列
+func ExpandFiles(args []string) ([]string, error) {
+	seen := make(map[string]bool)
+	files := make([]string, 0)
+	for _, arg := range args {
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			candidates := []string{match}
+			if info.IsDir() {
+				candidates, err = filepath.Glob(filepath.Join(match, "log_*.log*"))
+				if err != nil {
+					return nil, err
+				}
+			}
+			for _, file := range candidates {
+				if !seen[file] {
+					seen[file] = true
+					files = append(files, file)
+				}
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// moduleFromFileName 由文件名推断模块名，无模块后缀的文件属于default模块
+func moduleFromFileName(path string) string {
+	m := fileNamePattern.FindStringSubmatch(filepath.Base(path))
+	if m == nil {
+		return ""
+	}
+	if m[2] == "" {
+		return "default"
+	}
+	return m[2]
+}
+
+// ParseRecord 解析一行JSON日志，无法解析的行返回false
+func ParseRecord(line string, file string) (*Record, bool) {
+	fields := make(map[string]any)
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return nil, false
+	}
+
+	r := &Record{Raw: line, File: file, Fields: fields}
+	if module, ok := fields["logger"].(string); ok && module != "" {
+		r.Module = module
+	} else {
+		r.Module = moduleFromFileName(file)
+	}
+	if level, ok := fields["level"].(string); ok {
+		_ = r.Level.UnmarshalText([]byte(level))
+	}
+	switch ts := fields["ts"].(type) {
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, ts); err == nil {
+				r.Time = t
+				break
+			}
+		}
+	case float64:
+		sec, frac := int64(ts), ts-float64(int64(ts))
+		r.Time = time.Unix(sec, int64(frac*1e9))
+	}
+	return r, true
+}
+
+// openFile 打开日志文件，.gz文件自动解压
+func openFile(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return file, nil
+	}
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{gz, file}, nil
+}
+
+// ReadFiles 按时间顺序回调所有文件中满足条件的记录
+// 各文件逐行读取并按时间归并，不会将文件整体读入内存
+func ReadFiles(files []string, filter *Filter, fn func(r *Record)) error {
+	streams := make([]*recordStream, 0, len(files))
+	defer func() {
+		for _, s := range streams {
+			s.closer.Close()
+		}
+	}()
+	for _, path := range files {
+		reader, err := openFile(path)
+		if err != nil {
+			return err
+		}
+		streams = append(streams, newRecordStream(path, reader, reader))
+	}
+	return mergeRecords(streams, filter, fn)
+}
+
+// recordStream 逐行读取一个文件的记录
+type recordStream struct {
+	path    string
+	index   int
+	scanner *bufio.Scanner
+	closer  io.Closer
+	next    *Record
+}
+
+// newRecordStream 创建逐行读取reader的记录流，closer可以为nil
+func newRecordStream(path string, reader io.Reader, closer io.Closer) *recordStream {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	if closer == nil {
+		closer = io.NopCloser(nil)
+	}
+	return &recordStream{path: path, scanner: scanner, closer: closer}
+}
+
+// advance 读取下一条满足条件的记录，读完时next为nil
+func (s *recordStream) advance(filter *Filter) {
+	s.next = nil
+	for s.scanner.Scan() {
+		if r, ok := ParseRecord(s.scanner.Text(), s.path); ok && filter.Match(r) {
+			s.next = r
+			return
+		}
+	}
+}
+
+// recordHeap 按下一条记录的时间排序的记录流，时间相同时按文件顺序
+type recordHeap []*recordStream
+
+func (h recordHeap) Len() int { return len(h) }
+func (h recordHeap) Less(i, j int) bool {
+	ti, tj := h[i].next.Time, h[j].next.Time
+	if ti.Equal(tj) {
+		return h[i].index < h[j].index
+	}
+	return ti.Before(tj)
+}
+func (h recordHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *recordHeap) Push(x any)   { *h = append(*h, x.(*recordStream)) }
+func (h *recordHeap) Pop() any {
+	old := *h
+	s := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return s
+}
+
+// mergeRecords 按时间归并多个记录流，每个文件内的记录假定已按时间排列
+func mergeRecords(streams []*recordStream, filter *Filter, fn func(r *Record)) error {
+	h := make(recordHeap, 0, len(streams))
+	for i, s := range streams {
+		s.index = i
+		if s.advance(filter); s.next != nil {
+			h = append(h, s)
+		}
+	}
+	heap.Init(&h)
+	for h.Len() > 0 {
+		s := h[0]
+		fn(s.next)
+		if s.advance(filter); s.next != nil {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+
+	for _, s := range streams {
+		if err := s.scanner.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tailFile 正在跟踪的文件
+type tailFile struct {
+	path    string
+	file    *os.File
+	info    os.FileInfo
+	offset  int64
+	partial string
+	active  bool // 最近一次读取是否有新数据
+}
+
+// errRemoved 跟踪的文件已被移走且没有新数据
+var errRemoved = errors.New("file removed")
+
+// Follow 持续跟踪匹配的文件，定期重新展开参数以发现按日期轮转产生的新文件
+// 只跟踪仍在写入的.log文件；history为true时先按时间顺序输出启动时已有的记录，再输出新增的记录。
+// 文件被重命名或重建时读完旧文件后从头读取新文件，被截断时从头读取；
+// 被移走的文件以及已有次日文件的旧文件在没有新数据后关闭
+func Follow(ctx context.Context, args []string, interval time.Duration, history bool, filter *Filter, fn func(r *Record)) error {
+	tails := make(map[string]*tailFile)
+	// superseded 已被次日文件取代并关闭的文件，不再重新打开
+	superseded := make(map[string]bool)
+	defer func() {
+		for _, t := range tails {
+			t.file.Close()
+		}
+	}()
+
+	files, err := ExpandFiles(args)
+	if err != nil {
+		return err
+	}
+	// 启动时已存在的文件从末尾开始跟踪，末尾之前的内容作为历史记录
+	for _, path := range files {
+		if !strings.HasSuffix(path, ".log") {
+			continue
+		}
+		if t, err := openTail(path, true); err == nil {
+			tails[path] = t
+		}
+	}
+	if history {
+		if err := readHistory(files, tails, filter, fn); err != nil {
+			return err
+		}
+	}
+
+	for {
+		for path, t := range tails {
+			t.read(filter, fn)
+			if err := t.reopenIfRotated(filter, fn); err != nil {
+				t.file.Close()
+				delete(tails, path)
+			}
+		}
+		for _, path := range supersededFiles(tails, files) {
+			tails[path].file.Close()
+			delete(tails, path)
+			superseded[path] = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+
+		files, err = ExpandFiles(args)
+		if err != nil {
+			return err
+		}
+		for _, path := range files {
+			if !strings.HasSuffix(path, ".log") || tails[path] != nil || superseded[path] {
+				continue
+			}
+			// 启动后出现的文件从头开始
+			t, err := openTail(path, false)
+			if err != nil {
+				continue
+			}
+			tails[path] = t
+		}
+	}
+}
+
+// readHistory 按时间顺序输出启动时已有的记录，正在跟踪的文件只读到开始跟踪的位置
+func readHistory(files []string, tails map[string]*tailFile, filter *Filter, fn func(r *Record)) error {
+	streams := make([]*recordStream, 0, len(files))
+	defer func() {
+		for _, s := range streams {
+			s.closer.Close()
+		}
+	}()
+	for _, path := range files {
+		if t := tails[path]; t != nil {
+			streams = append(streams, newRecordStream(path, io.NewSectionReader(t.file, 0, t.offset), nil))
+			continue
+		}
+		if strings.HasSuffix(path, ".log") {
+			// 无法跟踪的文件
+			continue
+		}
+		reader, err := openFile(path)
+		if err != nil {
+			return err
+		}
+		streams = append(streams, newRecordStream(path, reader, reader))
+	}
+	return mergeRecords(streams, filter, fn)
+}
+
+// supersededFiles 返回没有新数据且同一模块已有更新日期文件的跟踪文件
+func supersededFiles(tails map[string]*tailFile, files []string) []string {
+	latest := make(map[string]string)
+	for _, path := range files {
+		m := fileNamePattern.FindStringSubmatch(filepath.Base(path))
+		if m != nil && strings.HasSuffix(path, ".log") && m[1] > latest[m[2]] {
+			latest[m[2]] = m[1]
+		}
+	}
+
+	var result []string
+	for path, t := range tails {
+		m := fileNamePattern.FindStringSubmatch(filepath.Base(path))
+		if m != nil && !t.active && m[1] < latest[m[2]] {
+			result = append(result, path)
+		}
+	}
+	return result
+}
+
+// openTail 打开需要跟踪的文件，atEnd为true时从文件末尾开始
+func openTail(path string, atEnd bool) (*tailFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	t := &tailFile{path: path, file: file, info: info}
+	if atEnd {
+		t.offset, err = file.Seek(0, io.SeekEnd)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// read 读取新增的完整行，不完整的行留到下次读取
+func (t *tailFile) read(filter *Filter, fn func(r *Record)) {
+	t.active = false
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := t.file.Read(buf)
+		if n > 0 {
+			t.active = true
+			t.offset += int64(n)
+			data := t.partial + string(buf[:n])
+			lines := strings.Split(data, "\n")
+			t.partial = lines[len(lines)-1]
+			for _, line := range lines[:len(lines)-1] {
+				if r, ok := ParseRecord(line, t.path); ok && filter.Match(r) {
+					fn(r)
+				}
+			}
+		}
+		if err != nil || n == 0 {
+			return
+		}
+	}
+}
+
+// reopenIfRotated 检查文件是否被轮转或截断，轮转时先读完旧文件
+func (t *tailFile) reopenIfRotated(filter *Filter, fn func(r *Record)) error {
+	info, err := os.Stat(t.path)
+	if errors.Is(err, os.ErrNotExist) {
+		// 文件被移走且尚未重建，读完写入方切换前的数据后关闭
+		if t.active {
+			return nil
+		}
+		return errRemoved
+	}
+	if err != nil {
+		return err
+	}
+	if !os.SameFile(info, t.info) {
+		file, err := os.Open(t.path)
+		if err != nil {
+			return err
+		}
+		t.read(filter, fn)
+		t.file.Close()
+		// 新文件尚未读取，视为有新数据
+		t.file, t.info, t.offset, t.partial, t.active = file, info, 0, "", true
+		return nil
+	}
+	if info.Size() < t.offset {
+		if _, err := t.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		t.offset, t.partial, t.active = 0, "", true
+	}
+	return nil
+}
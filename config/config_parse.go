@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -11,30 +12,53 @@ var config = &Type{
 	Record: map[string]string{},
 }
 
+// ErrNoEnv 未设置Env环境变量，没有可加载的配置文件
+var ErrNoEnv = errors.New("env variable not set")
+
+// LoadConfig 加载Env对应的配置文件，无法加载时panic
 func LoadConfig() *Type {
+	cfg, err := Load()
+	if err != nil {
+		panic(err)
+	}
+	return cfg
+}
+
+// Load 加载Env对应的配置文件，未设置Env时返回ErrNoEnv，文件无法读取或解析时返回错误
+// ConfigInject为true时不读取文件，返回已注入的配置
+func Load() (*Type, error) {
+	if os.Getenv("ConfigInject") == "true" {
+		return config, nil
+	}
 	env := os.Getenv("Env")
-	configInject := os.Getenv("ConfigInject")
-	injectMode := configInject == "true"
-	if !injectMode {
-		config.env = env
-		if env == "" {
-			panic("env variable not set")
-		}
-		reader, err := os.ReadFile(fmt.Sprintf("./config/%s.yaml", env))
-		if err != nil {
-			panic(err)
-		}
-		err = yaml.Unmarshal(reader, &config)
-		if err != nil {
-			panic(err)
-		}
-		err = yaml.Unmarshal(reader, &config.Record)
-		if err != nil {
-			panic(err)
-		}
-		return config
-	}
-	return config
+	config.env = env
+	if env == "" {
+		return nil, ErrNoEnv
+	}
+	reader, err := os.ReadFile(fmt.Sprintf("./config/%s.yaml", env))
+	if err != nil {
+		return nil, err
+	}
+	if err = yaml.Unmarshal(reader, &config); err != nil {
+		return nil, err
+	}
+	if err = yaml.Unmarshal(reader, &config.Record); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Lookup 读取配置项，环境变量优先，其次为配置文件，都没有时返回defaultValue
+// 环境变量未设置且配置文件无法加载时返回错误，不会panic
+func Lookup(record string, defaultValue string) (string, error) {
+	if value := os.Getenv(record); value != "" {
+		return value, nil
+	}
+	cfg, err := Load()
+	if err != nil {
+		return "", fmt.Errorf("config: %s: %w", record, err)
+	}
+	return cfg.Get(record, defaultValue), nil
 }
 
 func RefreshConfig() error {
@@ -0,0 +1,208 @@
+package dto
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// AggFunc 聚合函数
+type AggFunc string
+
+const (
+	AggCount AggFunc = "count" // 记录数，不需要字段
+	AggSum   AggFunc = "sum"   // 求和，字段为数值类型
+	AggAvg   AggFunc = "avg"   // 平均值，字段为数值类型
+	AggMin   AggFunc = "min"   // 最小值
+	AggMax   AggFunc = "max"   // 最大值
+)
+
+// Aggregation 聚合，agg参数中为count或sum(amount)的形式
+type Aggregation struct {
+	Func  AggFunc `json:"func"`            // 聚合函数
+	Field string  `json:"field,omitempty"` // 聚合的字段，count为空
+}
+
+// String 返回agg参数中的形式，同时是聚合结果与排序中使用的名称
+func (a Aggregation) String() string {
+	if a.Field == "" {
+		return string(a.Func)
+	}
+	return string(a.Func) + "(" + a.Field + ")"
+}
+
+// parseAggregations 解析agg参数，格式为count,sum(amount)
+// 格式错误时Func保留原文，由Validate报告
+func parseAggregations(value string) []Aggregation {
+	var aggs []Aggregation
+	for _, item := range parseList(value) {
+		name, field, ok := strings.Cut(item, "(")
+		if ok && strings.HasSuffix(field, ")") {
+			aggs = append(aggs, Aggregation{
+				Func:  AggFunc(strings.TrimSpace(name)),
+				Field: strings.TrimSpace(strings.TrimSuffix(field, ")")),
+			})
+			continue
+		}
+		aggs = append(aggs, Aggregation{Func: AggFunc(item)})
+	}
+	return aggs
+}
+
+// IsAggregate 是否为分组聚合请求，即包含group_by或agg参数
+func (qr *QueryRequest) IsAggregate() bool {
+	return len(qr.GroupBy) > 0 || len(qr.Agg) > 0
+}
+
+// Aggregations 返回需要计算的聚合，没有agg参数时为count
+func (qr *QueryRequest) Aggregations() []Aggregation {
+	if len(qr.Agg) == 0 {
+		return []Aggregation{{Func: AggCount}}
+	}
+	return qr.Agg
+}
+
+// validateAggregate 校验分组字段、聚合与聚合结果的排序
+func validateAggregate(schema *QuerySchema, qr *QueryRequest) ValidationErrors {
+	var errs ValidationErrors
+	for _, key := range qr.GroupBy {
+		if field, ok := schema.Fields[key]; !ok || !field.Groupable {
+			errs = append(errs, &FieldError{
+				Param:   "group_by",
+				Field:   key,
+				Reason:  ReasonGroupNotAllowed,
+				Message: fmt.Sprintf("grouping by %q is not allowed", key),
+			})
+		}
+	}
+	for _, agg := range qr.Agg {
+		if err := validateAggregation(schema, agg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// 分组聚合的结果只能按分组字段或聚合排序
+	names := make(map[string]bool, len(qr.GroupBy)+len(qr.Agg))
+	for _, key := range qr.GroupBy {
+		names[key] = true
+	}
+	for _, agg := range qr.Aggregations() {
+		names[agg.String()] = true
+	}
+	for _, order := range qr.Order {
+		if !names[order.Key] {
+			errs = append(errs, &FieldError{
+				Param:   "sort",
+				Field:   order.Key,
+				Reason:  ReasonSortNotAllowed,
+				Message: fmt.Sprintf("sorting by %q is not allowed, aggregations can only be sorted by group_by fields or agg", order.Key),
+			})
+		}
+	}
+	if qr.Cursor != "" {
+		errs = append(errs, &FieldError{
+			Param:   "cursor",
+			Reason:  ReasonInvalidValue,
+			Message: "cursor pagination is not supported for aggregations",
+		})
+	}
+	return errs
+}
+
+// validateAggregation 校验单个聚合的函数与字段
+func validateAggregation(schema *QuerySchema, agg Aggregation) *FieldError {
+	fieldErr := func(reason, format string, args ...any) *FieldError {
+		return &FieldError{
+			Param:   "agg",
+			Field:   agg.Field,
+			Reason:  reason,
+			Message: fmt.Sprintf(format, args...),
+		}
+	}
+
+	switch agg.Func {
+	case AggCount:
+		if agg.Field != "" {
+			return fieldErr(ReasonAggregateNotAllowed, "count does not take a field")
+		}
+		return nil
+	case AggSum, AggAvg, AggMin, AggMax:
+	default:
+		return fieldErr(ReasonAggregateNotAllowed, "unknown aggregation %q", agg.String())
+	}
+
+	field, ok := schema.Fields[agg.Field]
+	if !ok {
+		return fieldErr(ReasonUnknownField, "field %q is not allowed", agg.Field)
+	}
+	numeric := field.Type == TypeInt || field.Type == TypeFloat
+	if field.Array || (!numeric && (agg.Func == AggSum || agg.Func == AggAvg)) ||
+		field.Type == TypeBool || field.Type == TypeObjectID {
+		return fieldErr(ReasonAggregateNotAllowed, "%s is not allowed for field %q", agg.Func, agg.Field)
+	}
+	return nil
+}
+
+// NewAggregateBucket 创建分组聚合的一组结果，keys与qr.GroupBy、values与qr.Aggregations()一一对应
+// 值按字段类型规范化：count为int64，avg为float64，sum与字段类型一致，整数为int64、浮点数为float64，min、max与字段类型一致
+func NewAggregateBucket(qr *QueryRequest, schema *QuerySchema, keys, values []any) AggregateBucket {
+	bucket := AggregateBucket{
+		Key:    make(map[string]any, len(qr.GroupBy)),
+		Values: make(map[string]any, len(values)),
+	}
+	for i, key := range qr.GroupBy {
+		if i < len(keys) {
+			bucket.Key[key] = normalize(reflect.ValueOf(keys[i]))
+		}
+	}
+	for i, agg := range qr.Aggregations() {
+		if i < len(values) {
+			bucket.Values[agg.String()] = aggregateValue(schema.Fields[agg.Field], agg.Func, normalize(reflect.ValueOf(values[i])))
+		}
+	}
+	return bucket
+}
+
+// aggregateValue 将规范化后的聚合值转换为聚合结果的类型
+func aggregateValue(field FieldSchema, fn AggFunc, value any) any {
+	switch fn {
+	case AggCount:
+		if f, ok := value.(float64); ok {
+			return int64(f)
+		}
+	case AggAvg:
+		if n, ok := value.(int64); ok {
+			return float64(n)
+		}
+	case AggSum, AggMin, AggMax:
+		switch v := value.(type) {
+		case int64:
+			if field.Type == TypeFloat {
+				return float64(v)
+			}
+		case float64:
+			if field.Type == TypeInt {
+				return int64(v)
+			}
+		}
+	}
+	return value
+}
+
+// Int 返回整数类型的聚合值，例如count，不是整数时返回0
+func (b AggregateBucket) Int(name string) int64 {
+	n, _ := b.Values[name].(int64)
+	return n
+}
+
+// Float 返回数值类型的聚合值，整数转换为float64，不是数值时返回0
+func (b AggregateBucket) Float(name string) float64 {
+	switch v := b.Values[name].(type) {
+	case float64:
+		return v
+	case int64:
+		return float64(v)
+	default:
+		return 0
+	}
+}
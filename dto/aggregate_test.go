@@ -0,0 +1,149 @@
+package dto
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// aggregateSchema 分组聚合测试使用的查询定义
+var aggregateSchema = &QuerySchema{
+	Fields: map[string]FieldSchema{
+		"city":      {Type: TypeString, Groupable: true},
+		"name":      {Type: TypeString},
+		"amount":    {Type: TypeFloat},
+		"age":       {Type: TypeInt, Sortable: true},
+		"createdAt": {Type: TypeTime},
+		"active":    {Type: TypeBool, Groupable: true},
+		"tags":      {Type: TypeString, Array: true},
+	},
+}
+
+func TestParseAggregations(t *testing.T) {
+	qr, err := ParseQueryRequest("group_by=city,active&agg=count,sum(amount), avg( age ),median(age),max(")
+	if err != nil {
+		t.Fatalf("ParseQueryRequest() error = %v", err)
+	}
+	if want := []string{"city", "active"}; !reflect.DeepEqual(qr.GroupBy, want) {
+		t.Errorf("GroupBy = %v, want %v", qr.GroupBy, want)
+	}
+	want := []Aggregation{
+		{Func: AggCount},
+		{Func: AggSum, Field: "amount"},
+		{Func: AggAvg, Field: "age"},
+		{Func: "median", Field: "age"},
+		{Func: "max("},
+	}
+	if !reflect.DeepEqual(qr.Agg, want) {
+		t.Errorf("Agg = %+v, want %+v", qr.Agg, want)
+	}
+	if len(qr.Condition) != 0 {
+		t.Errorf("Condition = %+v, want none", qr.Condition)
+	}
+
+	if got := (&QueryRequest{GroupBy: []string{"city"}}).Aggregations(); !reflect.DeepEqual(got, []Aggregation{{Func: AggCount}}) {
+		t.Errorf("Aggregations() = %+v, want count", got)
+	}
+}
+
+func TestValidateAggregate(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantErrors []FieldError
+	}{
+		{
+			name:  "valid aggregation sorted by result",
+			query: "group_by=city&agg=count,sum(amount),min(createdAt),max(name)&sort=sum(amount).desc,city.asc&age_gt=18",
+		},
+		{
+			name:  "implicit count",
+			query: "group_by=active&sort=count.desc",
+		},
+		{
+			name:  "group and aggregate not allowed",
+			query: "group_by=name,secret&agg=sum(name),avg(tags),max(active),count(age),median(age),sum(secret)",
+			wantErrors: []FieldError{
+				{Param: "group_by", Field: "name", Reason: ReasonGroupNotAllowed},
+				{Param: "group_by", Field: "secret", Reason: ReasonGroupNotAllowed},
+				{Param: "agg", Field: "name", Reason: ReasonAggregateNotAllowed},
+				{Param: "agg", Field: "tags", Reason: ReasonAggregateNotAllowed},
+				{Param: "agg", Field: "active", Reason: ReasonAggregateNotAllowed},
+				{Param: "agg", Field: "age", Reason: ReasonAggregateNotAllowed},
+				{Param: "agg", Field: "age", Reason: ReasonAggregateNotAllowed},
+				{Param: "agg", Field: "secret", Reason: ReasonUnknownField},
+			},
+		},
+		{
+			name:  "sort by document fields and cursor",
+			query: "group_by=city&sort=age.asc&cursor=abc",
+			wantErrors: []FieldError{
+				{Param: "sort", Field: "age", Reason: ReasonSortNotAllowed},
+				{Param: "cursor", Reason: ReasonInvalidValue},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qr, err := ParseQueryRequest(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQueryRequest() error = %v", err)
+			}
+			err = qr.Validate(aggregateSchema)
+			if len(tt.wantErrors) == 0 {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+
+			var validationErrs ValidationErrors
+			if !errors.As(err, &validationErrs) {
+				t.Fatalf("Validate() error = %v, want ValidationErrors", err)
+			}
+			got := make([]FieldError, 0, len(validationErrs))
+			for _, fieldErr := range validationErrs {
+				got = append(got, FieldError{Param: fieldErr.Param, Field: fieldErr.Field, Reason: fieldErr.Reason})
+			}
+			if !reflect.DeepEqual(got, tt.wantErrors) {
+				t.Errorf("Validate() errors = %+v, want %+v", got, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestNewAggregateBucket(t *testing.T) {
+	qr, err := ParseQueryRequest("group_by=city,active&agg=count,sum(amount),sum(age),avg(age),min(createdAt),max(age)")
+	if err != nil {
+		t.Fatalf("ParseQueryRequest() error = %v", err)
+	}
+	created := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	bucket := NewAggregateBucket(qr, aggregateSchema,
+		[]any{"NY", true},
+		[]any{int32(3), int32(10), float64(61), int32(20), primitive.NewDateTimeFromTime(created), int32(30)},
+	)
+	want := AggregateBucket{
+		Key: map[string]any{"city": "NY", "active": true},
+		Values: map[string]any{
+			"count":          int64(3),
+			"sum(amount)":    float64(10),
+			"sum(age)":       int64(61),
+			"avg(age)":       float64(20),
+			"min(createdAt)": created.Local(),
+			"max(age)":       int64(30),
+		},
+	}
+	if !reflect.DeepEqual(bucket, want) {
+		t.Errorf("NewAggregateBucket() = %+v, want %+v", bucket, want)
+	}
+	if got := bucket.Int("count"); got != 3 {
+		t.Errorf("Int(count) = %d, want 3", got)
+	}
+	if got := bucket.Float("sum(age)"); got != 61 {
+		t.Errorf("Float(sum(age)) = %v, want 61", got)
+	}
+}
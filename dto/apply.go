@@ -0,0 +1,488 @@
+package dto
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Apply 在内存中按查询请求过滤、排序并分页，语义与MongoDB查询一致
+// 字段按bson标签、json标签、字段名的顺序匹配，a.b形式的键读取嵌套字段，元素可以是结构体、结构体指针或map[string]any
+// 未经Validate的条件值为字符串时按元素字段的类型转换后比较，没有时区的时间按tz参数的时区解析；Total为分页前匹配的数量
+// 排序或分页时与MongoDB查询相同，追加_id升序作为最后的排序字段
+func Apply[T any](items []T, qr *QueryRequest) ListResponse[T] {
+	loc, err := qr.Location()
+	if err != nil {
+		loc = time.UTC
+	}
+	expr := qr.Expr()
+	matched := make([]T, 0, len(items))
+	for _, item := range items {
+		if matchExpr(reflect.ValueOf(item), expr, loc) {
+			matched = append(matched, item)
+		}
+	}
+
+	if len(qr.Order) > 0 || qr.PageSize > 0 {
+		orders := applyOrders(qr.Order)
+		slices.SortStableFunc(matched, func(a, b T) int {
+			return compareOrder(reflect.ValueOf(a), reflect.ValueOf(b), orders)
+		})
+	}
+
+	total := int64(len(matched))
+	if qr.PageSize > 0 {
+		offset := min((max(qr.Page, 1)-1)*qr.PageSize, len(matched))
+		end := min(offset+qr.PageSize, len(matched))
+		matched = matched[offset:end]
+	}
+	return ListResponse[T]{List: matched, Total: total}
+}
+
+// applyOrders 返回排序条件，未包含_id时追加_id升序
+func applyOrders(orders []Order) []Order {
+	for _, order := range orders {
+		if order.Key == CursorIDKey {
+			return orders
+		}
+	}
+	return append(slices.Clone(orders), Order{Key: CursorIDKey, Direction: Ascending})
+}
+
+// structField 结构体字段的索引
+type structField struct {
+	index     []int // reflect.Value.FieldByIndex的索引
+	omitEmpty bool  // bson标签是否有omitempty，零值视为字段不存在
+}
+
+// structFieldsCache 各结构体类型的字段名到字段索引
+var structFieldsCache sync.Map // map[reflect.Type]map[string]structField
+
+// structFields 返回结构体类型的字段名到字段索引，结果按类型缓存
+func structFields(t reflect.Type) map[string]structField {
+	if cached, ok := structFieldsCache.Load(t); ok {
+		return cached.(map[string]structField)
+	}
+
+	type namedField struct {
+		names [3]string // bson标签名、json标签名、小写字段名
+		field structField
+	}
+	var collect func(t reflect.Type, prefix []int) []namedField
+	collect = func(t reflect.Type, prefix []int) []namedField {
+		var result []namedField
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			index := append(slices.Clone(prefix), i)
+			bsonName, bsonOpts, _ := strings.Cut(f.Tag.Get("bson"), ",")
+			jsonName, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if bsonName == "-" || (jsonName == "-" && bsonName == "") {
+				continue
+			}
+			if f.Anonymous && f.Type.Kind() == reflect.Struct && bsonName == "" && jsonName == "" {
+				result = append(result, collect(f.Type, index)...)
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+			result = append(result, namedField{
+				names: [3]string{bsonName, jsonName, strings.ToLower(f.Name)},
+				field: structField{index: index, omitEmpty: strings.Contains(bsonOpts, "omitempty")},
+			})
+		}
+		return result
+	}
+
+	fields := make(map[string]structField)
+	named := collect(t, nil)
+	for i := range 3 {
+		for _, nf := range named {
+			if name := nf.names[i]; name != "" {
+				if _, ok := fields[name]; !ok {
+					fields[name] = nf.field
+				}
+			}
+		}
+	}
+	structFieldsCache.Store(t, fields)
+	return fields
+}
+
+// lookupField 读取键对应的值，found为false表示字段不存在
+func lookupField(v reflect.Value, key string) (value any, found bool) {
+	for _, name := range strings.Split(key, ".") {
+		v = indirect(v)
+		switch {
+		case !v.IsValid():
+			return nil, false
+		case v.Kind() == reflect.Struct:
+			f, ok := structFields(v.Type())[name]
+			if !ok {
+				return nil, false
+			}
+			v = v.FieldByIndex(f.index)
+			if f.omitEmpty && v.IsZero() {
+				return nil, false
+			}
+		case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+			if !v.IsValid() {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+	}
+	return normalize(v), true
+}
+
+// indirect 解开指针与接口，nil时返回无效值
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+	dateTimeType = reflect.TypeOf(primitive.DateTime(0))
+)
+
+// normalize 将值转换为便于比较的形式
+// 整数为int64，浮点数为float64，字符串类型为string，数组与切片为[]any
+func normalize(v reflect.Value) any {
+	v = indirect(v)
+	if !v.IsValid() {
+		return nil
+	}
+	switch v.Type() {
+	case timeType, objectIDType:
+		return v.Interface()
+	case dateTimeType:
+		return v.Interface().(primitive.DateTime).Time()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.String:
+		return v.String()
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		values := make([]any, v.Len())
+		for i := range values {
+			values[i] = normalize(v.Index(i))
+		}
+		return values
+	default:
+		return v.Interface()
+	}
+}
+
+// coerce 条件值为字符串而字段值不是时，按字段值的类型转换条件值，无法转换时原样返回
+// 没有时区的时间按loc解析
+func coerce(field, value any, loc *time.Location) any {
+	s, ok := value.(string)
+	if !ok {
+		return value
+	}
+	var (
+		converted any
+		err       error
+	)
+	switch field.(type) {
+	case int64:
+		if converted, err = strconv.ParseInt(s, 10, 64); err != nil {
+			converted, err = strconv.ParseFloat(s, 64)
+		}
+	case float64:
+		converted, err = strconv.ParseFloat(s, 64)
+	case bool:
+		converted, err = strconv.ParseBool(s)
+	case time.Time:
+		converted, err = ParseTime(s, timeNow(), loc)
+	case primitive.ObjectID:
+		converted, err = primitive.ObjectIDFromHex(s)
+	default:
+		return value
+	}
+	if err != nil {
+		return value
+	}
+	return converted
+}
+
+// compareValues 比较两个同类值，ok为false表示类型不同无法比较
+func compareValues(a, b any) (result int, ok bool) {
+	switch x := a.(type) {
+	case nil:
+		return 0, b == nil
+	case int64:
+		switch y := b.(type) {
+		case int64:
+			return cmp.Compare(x, y), true
+		case float64:
+			return cmp.Compare(float64(x), y), true
+		}
+	case float64:
+		switch y := b.(type) {
+		case int64:
+			return cmp.Compare(x, float64(y)), true
+		case float64:
+			return cmp.Compare(x, y), true
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0, true
+			case y:
+				return -1, true
+			default:
+				return 1, true
+			}
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y), true
+		}
+	case primitive.ObjectID:
+		if y, ok := b.(primitive.ObjectID); ok {
+			return bytes.Compare(x[:], y[:]), true
+		}
+	}
+	return 0, false
+}
+
+// equalValues 判断字段值与条件值是否相等
+func equalValues(field, value any, loc *time.Location) bool {
+	result, ok := compareValues(field, coerce(field, value, loc))
+	return ok && result == 0
+}
+
+// matchAny 字段值为数组时任一元素满足即匹配，与MongoDB对数组字段的查询语义一致
+func matchAny(field any, match func(any) bool) bool {
+	if values, ok := field.([]any); ok {
+		return slices.ContainsFunc(values, match)
+	}
+	return match(field)
+}
+
+// matchExpr 判断元素是否满足过滤表达式，expr为nil时总是满足
+func matchExpr(item reflect.Value, expr *Expr, loc *time.Location) bool {
+	if expr == nil {
+		return true
+	}
+	switch expr.Kind {
+	case ExprAnd:
+		for _, child := range expr.Children {
+			if !matchExpr(item, child, loc) {
+				return false
+			}
+		}
+		return true
+	case ExprOr:
+		for _, child := range expr.Children {
+			if matchExpr(item, child, loc) {
+				return true
+			}
+		}
+		return false
+	case ExprNot:
+		return !matchExpr(item, expr.Children[0], loc)
+	default:
+		return matchCondition(item, expr.Condition, loc)
+	}
+}
+
+// matchCondition 判断元素是否满足单个条件
+func matchCondition(item reflect.Value, c *Condition, loc *time.Location) bool {
+	field, found := lookupField(item, c.Key)
+	compare := func(accept func(int) bool) bool {
+		return found && matchAny(field, func(elem any) bool {
+			result, ok := compareValues(elem, coerce(elem, c.Value, loc))
+			return ok && accept(result)
+		})
+	}
+	matchString := func(match func(s, pattern string) bool) bool {
+		pattern := fmt.Sprint(c.Value)
+		return found && matchAny(field, func(elem any) bool {
+			s, ok := elem.(string)
+			return ok && match(s, pattern)
+		})
+	}
+	in := func() bool {
+		values := conditionList(c.Value)
+		return found && matchAny(field, func(elem any) bool {
+			return slices.ContainsFunc(values, func(v any) bool { return equalValues(elem, v, loc) })
+		})
+	}
+
+	switch c.Op {
+	case Equal, Or, Contains:
+		return compare(func(r int) bool { return r == 0 })
+	case NotEqual:
+		return !compare(func(r int) bool { return r == 0 })
+	case Greater:
+		return compare(func(r int) bool { return r > 0 })
+	case GreaterOrEqual:
+		return compare(func(r int) bool { return r >= 0 })
+	case Less:
+		return compare(func(r int) bool { return r < 0 })
+	case LessOrEqual:
+		return compare(func(r int) bool { return r <= 0 })
+	case In:
+		return in()
+	case NotIn:
+		return !in()
+	case Like:
+		return matchString(strings.Contains)
+	case ILike:
+		return matchString(func(s, pattern string) bool {
+			return strings.Contains(strings.ToLower(s), strings.ToLower(pattern))
+		})
+	case StartsWith:
+		return matchString(strings.HasPrefix)
+	case EndsWith:
+		return matchString(strings.HasSuffix)
+	case Between:
+		r, ok := c.Value.(Range)
+		if !ok || !found {
+			return false
+		}
+		return matchAny(field, func(elem any) bool {
+			if r.Min != nil {
+				if result, ok := compareValues(elem, coerce(elem, r.Min, loc)); !ok || result < 0 {
+					return false
+				}
+			}
+			if r.Max != nil {
+				if result, ok := compareValues(elem, coerce(elem, r.Max, loc)); !ok || result > 0 {
+					return false
+				}
+			}
+			return true
+		})
+	case Exists:
+		return found == conditionBool(c.Value)
+	case IsNull:
+		return (!found || field == nil) == conditionBool(c.Value)
+	case All:
+		values := conditionList(c.Value)
+		if !found || len(values) == 0 {
+			return false
+		}
+		for _, v := range values {
+			if !matchAny(field, func(elem any) bool { return equalValues(elem, v, loc) }) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// conditionList 返回列表形式的条件值
+func conditionList(value any) []any {
+	if values, ok := normalize(reflect.ValueOf(value)).([]any); ok {
+		return values
+	}
+	return []any{value}
+}
+
+// conditionBool 返回布尔形式的条件值，字符串按strconv.ParseBool解析
+func conditionBool(value any) bool {
+	b, _ := coerce(false, value, time.UTC).(bool)
+	return b
+}
+
+// compareOrder 按排序条件比较两个元素
+func compareOrder(a, b reflect.Value, orders []Order) int {
+	for _, order := range orders {
+		desc := order.Direction == Descending
+		x, _ := lookupField(a, order.Key)
+		y, _ := lookupField(b, order.Key)
+		result := compareSortKeys(sortKey(x, desc), sortKey(y, desc))
+		if desc {
+			result = -result
+		}
+		if result != 0 {
+			return result
+		}
+	}
+	return 0
+}
+
+// sortKey 返回排序使用的值，数组升序时取最小元素，降序时取最大元素
+func sortKey(value any, desc bool) any {
+	values, ok := value.([]any)
+	if !ok {
+		return value
+	}
+	var key any
+	for i, v := range values {
+		if i == 0 {
+			key = v
+			continue
+		}
+		result := compareSortKeys(v, key)
+		if (desc && result > 0) || (!desc && result < 0) {
+			key = v
+		}
+	}
+	return key
+}
+
+// compareSortKeys 比较排序值，不同类型按MongoDB的类型顺序比较
+func compareSortKeys(a, b any) int {
+	if result, ok := compareValues(a, b); ok {
+		return result
+	}
+	return cmp.Compare(typeRank(a), typeRank(b))
+}
+
+// typeRank MongoDB排序时的类型顺序
+func typeRank(value any) int {
+	switch value.(type) {
+	case nil:
+		return 1
+	case int64, float64:
+		return 2
+	case string:
+		return 3
+	case primitive.ObjectID:
+		return 7
+	case bool:
+		return 8
+	case time.Time:
+		return 9
+	default:
+		return 4
+	}
+}
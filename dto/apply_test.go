@@ -0,0 +1,149 @@
+package dto
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// applyOwner 测试使用的嵌套结构
+type applyOwner struct {
+	Name string `bson:"name"`
+}
+
+// applyBase 测试使用的嵌入结构
+type applyBase struct {
+	ID int `json:"id" bson:"_id"`
+}
+
+// applyItem 测试使用的元素类型
+type applyItem struct {
+	applyBase
+	Name      string      `json:"name" bson:"name"`
+	Age       int         `json:"age" bson:"age"`
+	Score     float64     `json:"score,omitempty" bson:"score,omitempty"`
+	Tags      []string    `json:"tags" bson:"tags"`
+	CreatedAt time.Time   `json:"createdAt" bson:"created_at"`
+	Owner     *applyOwner `json:"owner" bson:"owner"`
+	Secret    string      `json:"-" bson:"-"`
+}
+
+func TestApply(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	items := []applyItem{
+		{applyBase{1}, "alice", 30, 9.5, []string{"go", "db"}, day(1), &applyOwner{"ops"}, "s"},
+		{applyBase{2}, "Bob", 25, 0, []string{"go"}, day(2), nil, "s"},
+		{applyBase{3}, "carol_x", 35, 7, nil, day(3), &applyOwner{"dev"}, "s"},
+		{applyBase{4}, "dave", 25, 8, []string{"db"}, day(4), &applyOwner{"ops"}, "s"},
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantIDs   []int
+		wantTotal int64
+	}{
+		{name: "no conditions", query: "", wantIDs: []int{1, 2, 3, 4}, wantTotal: 4},
+		{name: "equal converts strings", query: "age=25", wantIDs: []int{2, 4}, wantTotal: 2},
+		{name: "bson and json names", query: "_id_in=1,3&id_ne=1", wantIDs: []int{3}, wantTotal: 1},
+		{name: "comparison", query: "age_gt=25&age_lte=35", wantIDs: []int{1, 3}, wantTotal: 2},
+		{name: "between", query: "age_between=26,", wantIDs: []int{1, 3}, wantTotal: 2},
+		{name: "nin", query: "age_nin=25,30", wantIDs: []int{3}, wantTotal: 1},
+		{name: "like is literal", query: "name_like=_x", wantIDs: []int{3}, wantTotal: 1},
+		{name: "ilike", query: "name_ilike=BO", wantIDs: []int{2}, wantTotal: 1},
+		{name: "prefix and suffix", query: "name_startswith=da&name_endswith=ve", wantIDs: []int{4}, wantTotal: 1},
+		{name: "time", query: "created_at_gte=2024-01-03", wantIDs: []int{3, 4}, wantTotal: 2},
+		{name: "nested field", query: "owner.name=ops", wantIDs: []int{1, 4}, wantTotal: 2},
+		{name: "nil pointer is null", query: "owner_isnull=true", wantIDs: []int{2}, wantTotal: 1},
+		{name: "omitempty zero is missing", query: "score_exists=false", wantIDs: []int{2}, wantTotal: 1},
+		{name: "ne matches missing", query: "score_ne=9.5", wantIDs: []int{2, 3, 4}, wantTotal: 3},
+		{name: "array contains", query: "tags_contains=db", wantIDs: []int{1, 4}, wantTotal: 2},
+		{name: "array equal matches element", query: "tags=go", wantIDs: []int{1, 2}, wantTotal: 2},
+		{name: "array all", query: "tags_all=go,db", wantIDs: []int{1}, wantTotal: 1},
+		{name: "ignored field", query: "secret=s", wantIDs: []int{}, wantTotal: 0},
+		{name: "or", query: "name_or=alice&name_or=dave", wantIDs: []int{1, 4}, wantTotal: 2},
+		{name: "filter expression", query: "filter=" + "not (age eq 25) or name eq 'Bob'", wantIDs: []int{1, 2, 3}, wantTotal: 3},
+		{name: "multi-key sort", query: "sort=age.asc,name.desc", wantIDs: []int{4, 2, 1, 3}, wantTotal: 4},
+		{name: "sort nulls first", query: "sort=score.asc", wantIDs: []int{2, 3, 4, 1}, wantTotal: 4},
+		{name: "paginate", query: "sort=_id.desc&page=2&page_size=3", wantIDs: []int{1}, wantTotal: 4},
+		{name: "page past end", query: "page=5&page_size=3", wantIDs: []int{}, wantTotal: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qr, err := ParseQueryRequest(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQueryRequest() error = %v", err)
+			}
+
+			result := Apply(items, qr)
+			ids := make([]int, 0, len(result.List))
+			for _, item := range result.List {
+				ids = append(ids, item.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("Apply() ids = %v, want %v", ids, tt.wantIDs)
+			}
+			if result.Total != tt.wantTotal {
+				t.Errorf("Apply() total = %d, want %d", result.Total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestApplyTimeZoneAndTies(t *testing.T) {
+	hour := func(h int) time.Time { return time.Date(2024, 1, 1, h, 0, 0, 0, time.UTC) }
+	items := []applyItem{
+		{applyBase: applyBase{3}, Age: 25, CreatedAt: hour(18)},
+		{applyBase: applyBase{1}, Age: 25, CreatedAt: hour(12)},
+		{applyBase: applyBase{2}, Age: 30, CreatedAt: hour(20)},
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		wantIDs []int
+	}{
+		{name: "ties ordered by _id", query: "sort=age.asc&page=1&page_size=2", wantIDs: []int{1, 3}},
+		{name: "ties on next page", query: "sort=age.desc&page=2&page_size=1", wantIDs: []int{1}},
+		{name: "paging without sort", query: "page=1&page_size=2", wantIDs: []int{1, 2}},
+		{name: "time in utc", query: "created_at_gte=2024-01-02", wantIDs: []int{}},
+		{name: "time in tz", query: "created_at_gte=2024-01-02&tz=Asia/Shanghai&sort=_id.asc", wantIDs: []int{2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qr, err := ParseQueryRequest(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQueryRequest() error = %v", err)
+			}
+
+			ids := make([]int, 0, len(items))
+			for _, item := range Apply(items, qr).List {
+				ids = append(ids, item.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("Apply() ids = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestApplyMaps(t *testing.T) {
+	items := []map[string]any{
+		{"name": "a", "age": 3},
+		{"name": "b", "age": 1.5},
+		{"name": "c"},
+	}
+	qr := &QueryRequest{
+		Condition: []Condition{{Key: "age", Op: GreaterOrEqual, Value: int64(1)}},
+		Order:     []Order{{Key: "age", Direction: Ascending}},
+	}
+
+	result := Apply(items, qr)
+	names := make([]any, 0, len(result.List))
+	for _, item := range result.List {
+		names = append(names, item["name"])
+	}
+	if want := []any{"b", "a"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Apply() names = %v, want %v", names, want)
+	}
+}
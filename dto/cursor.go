@@ -0,0 +1,170 @@
+package dto
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CursorIDKey 游标分页的唯一字段，排序值相同时按该字段升序
+const CursorIDKey = "_id"
+
+// ErrInvalidCursor 游标无法解码、签名不正确或与当前排序不一致
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorSignatureSize 游标签名的字节数
+const cursorSignatureSize = 16
+
+var (
+	cursorSecret     atomic.Pointer[[]byte]
+	cursorSecretOnce sync.Once
+)
+
+// SetCursorSecret 设置游标签名的密钥
+// 未设置时使用环境变量QUERY_CURSOR_SECRET，仍为空时使用进程内随机密钥，多实例部署时需要设置相同的密钥
+func SetCursorSecret(secret []byte) {
+	cursorSecretOnce.Do(func() {})
+	cursorSecret.Store(&secret)
+}
+
+// getCursorSecret 返回游标签名的密钥
+func getCursorSecret() []byte {
+	cursorSecretOnce.Do(func() {
+		secret := []byte(os.Getenv("QUERY_CURSOR_SECRET"))
+		if len(secret) == 0 {
+			secret = make([]byte, 32)
+			_, _ = rand.Read(secret)
+		}
+		cursorSecret.Store(&secret)
+	})
+	return *cursorSecret.Load()
+}
+
+// Cursor 游标，记录上一页最后一条记录的排序字段值
+type Cursor struct {
+	Keys   []string // 排序字段的存储路径，降序字段以-开头，最后一个为_id
+	Values []any    // 与Keys一一对应的字段值
+}
+
+// cursorPayload 游标的编码内容
+type cursorPayload struct {
+	Keys   []string `bson:"k"`
+	Values bson.A   `bson:"v"`
+}
+
+// Encode 编码为签名后的base64字符串
+func (c *Cursor) Encode() (string, error) {
+	payload, err := bson.Marshal(cursorPayload{Keys: c.Keys, Values: c.Values})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(append(payload, signCursor(payload)...)), nil
+}
+
+// DecodeCursor 校验签名并解码游标，时间值解码为time.Time，整数解码为int64
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(data) <= cursorSignatureSize {
+		return nil, ErrInvalidCursor
+	}
+	payload, signature := data[:len(data)-cursorSignatureSize], data[len(data)-cursorSignatureSize:]
+	if !hmac.Equal(signature, signCursor(payload)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var decoded cursorPayload
+	if err = bson.Unmarshal(payload, &decoded); err != nil || len(decoded.Keys) != len(decoded.Values) {
+		return nil, ErrInvalidCursor
+	}
+	values := make([]any, len(decoded.Values))
+	for i, v := range decoded.Values {
+		switch v := v.(type) {
+		case primitive.DateTime:
+			values[i] = v.Time()
+		case int32:
+			values[i] = int64(v)
+		default:
+			values[i] = v
+		}
+	}
+	return &Cursor{Keys: decoded.Keys, Values: values}, nil
+}
+
+// signCursor 计算游标内容的签名
+func signCursor(payload []byte) []byte {
+	mac := hmac.New(sha256.New, getCursorSecret())
+	mac.Write(payload)
+	return mac.Sum(nil)[:cursorSignatureSize]
+}
+
+// CursorKeys 返回游标分页使用的排序字段：Order中各字段的存储路径，降序以-开头，未包含_id时追加_id
+func (qr *QueryRequest) CursorKeys(schema *QuerySchema) []string {
+	keys := make([]string, 0, len(qr.Order)+1)
+	hasID := false
+	for _, order := range qr.Order {
+		path := schema.Path(order.Key)
+		hasID = hasID || path == CursorIDKey
+		if order.Direction == Descending {
+			path = "-" + path
+		}
+		keys = append(keys, path)
+	}
+	if !hasID {
+		keys = append(keys, CursorIDKey)
+	}
+	return keys
+}
+
+// DecodeCursor 解码查询请求的游标，没有游标时返回nil
+// 游标的排序字段与当前排序不一致时返回ErrInvalidCursor
+func (qr *QueryRequest) DecodeCursor(schema *QuerySchema) (*Cursor, error) {
+	if qr.Cursor == "" {
+		return nil, nil
+	}
+	cursor, err := DecodeCursor(qr.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Equal(cursor.Keys, qr.CursorKeys(schema)) {
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// NewCursorPage 由按CursorKeys排序、最多PageSize+1条的查询结果创建游标分页响应
+// 结果多于PageSize条时截断为PageSize条，并以最后一条记录的排序字段值生成NextCursor
+func NewCursorPage[T any](items []T, qr *QueryRequest, schema *QuerySchema) (CursorListResponse[T], error) {
+	page := CursorListResponse[T]{List: items}
+	if page.List == nil {
+		page.List = make([]T, 0)
+	}
+	if qr.PageSize <= 0 || len(items) <= qr.PageSize {
+		return page, nil
+	}
+
+	page.List = items[:qr.PageSize]
+	page.HasMore = true
+	last := reflect.ValueOf(page.List[len(page.List)-1])
+	cursor := &Cursor{Keys: qr.CursorKeys(schema)}
+	for _, key := range cursor.Keys {
+		value, _ := lookupField(last, strings.TrimPrefix(key, "-"))
+		cursor.Values = append(cursor.Values, value)
+	}
+	next, err := cursor.Encode()
+	if err != nil {
+		return page, err
+	}
+	page.NextCursor = next
+	return page, nil
+}
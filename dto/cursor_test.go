@@ -0,0 +1,136 @@
+package dto
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	SetCursorSecret([]byte("test-secret"))
+	id := primitive.NewObjectID()
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 6e6, time.UTC)
+	cursor := &Cursor{
+		Keys:   []string{"-age", "name", "price", "active", "created_at", "deleted_at", "_id"},
+		Values: []any{int64(30), "bob", 9.5, true, createdAt, nil, id},
+	}
+
+	encoded, err := cursor.Encode()
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	decoded, err := DecodeCursor(encoded)
+	if err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded.Keys, cursor.Keys) {
+		t.Errorf("DecodeCursor() keys = %v, want %v", decoded.Keys, cursor.Keys)
+	}
+	if !decoded.Values[4].(time.Time).Equal(createdAt) {
+		t.Errorf("DecodeCursor() time = %v, want %v", decoded.Values[4], createdAt)
+	}
+	decoded.Values[4] = createdAt
+	if !reflect.DeepEqual(decoded.Values, cursor.Values) {
+		t.Errorf("DecodeCursor() values = %#v, want %#v", decoded.Values, cursor.Values)
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	SetCursorSecret([]byte("test-secret"))
+	cursor := &Cursor{Keys: []string{"_id"}, Values: []any{int64(1)}}
+	encoded, err := cursor.Encode()
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	data, _ := base64.RawURLEncoding.DecodeString(encoded)
+	data[len(data)-cursorSignatureSize-2] ^= 1
+	tampered := base64.RawURLEncoding.EncodeToString(data)
+
+	SetCursorSecret([]byte("other-secret"))
+	otherSecret, err := cursor.Encode()
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	SetCursorSecret([]byte("test-secret"))
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "not base64", cursor: "%%%"},
+		{name: "too short", cursor: "abc"},
+		{name: "tampered", cursor: tampered},
+		{name: "other secret", cursor: otherSecret},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeCursor(tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeCursor() error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestQueryRequestCursor(t *testing.T) {
+	SetCursorSecret([]byte("test-secret"))
+	schema := &QuerySchema{Fields: map[string]FieldSchema{
+		"age":       {Type: TypeInt, Sortable: true},
+		"createdAt": {Type: TypeTime, Sortable: true, Path: "created_at"},
+	}}
+
+	qr, err := ParseQueryRequest("sort=age.desc,createdAt.asc&page_size=2")
+	if err != nil {
+		t.Fatalf("ParseQueryRequest() error = %v", err)
+	}
+	if want := []string{"-age", "created_at", "_id"}; !reflect.DeepEqual(qr.CursorKeys(schema), want) {
+		t.Fatalf("CursorKeys() = %v, want %v", qr.CursorKeys(schema), want)
+	}
+
+	type row struct {
+		ID        int       `bson:"_id"`
+		Age       int       `bson:"age"`
+		CreatedAt time.Time `bson:"created_at"`
+	}
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	page, err := NewCursorPage([]row{{1, 40, day}, {2, 30, day}, {3, 30, day}}, qr, schema)
+	if err != nil {
+		t.Fatalf("NewCursorPage() error = %v", err)
+	}
+	if len(page.List) != 2 || !page.HasMore || page.NextCursor == "" {
+		t.Fatalf("NewCursorPage() = %+v, want 2 items with a next cursor", page)
+	}
+
+	next, err := ParseQueryRequest("sort=age.desc,createdAt.asc&page_size=2&cursor=" + page.NextCursor)
+	if err != nil {
+		t.Fatalf("ParseQueryRequest() error = %v", err)
+	}
+	if err = next.Validate(schema); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	cursor, err := next.DecodeCursor(schema)
+	if err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+	if cursor.Values[0] != int64(30) || cursor.Values[2] != int64(2) {
+		t.Errorf("DecodeCursor() values = %v, want age 30 and _id 2", cursor.Values)
+	}
+
+	// 排序改变后游标失效
+	changed, _ := ParseQueryRequest("sort=age.asc&cursor=" + page.NextCursor)
+	var validationErrs ValidationErrors
+	if err = changed.Validate(schema); !errors.As(err, &validationErrs) || validationErrs[0].Param != "cursor" {
+		t.Errorf("Validate() error = %v, want cursor error", err)
+	}
+
+	last, err := NewCursorPage([]row{{4, 20, day}}, next, schema)
+	if err != nil {
+		t.Fatalf("NewCursorPage() error = %v", err)
+	}
+	if len(last.List) != 1 || last.HasMore || last.NextCursor != "" {
+		t.Errorf("NewCursorPage() = %+v, want last page", last)
+	}
+}
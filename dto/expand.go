@@ -0,0 +1,193 @@
+package dto
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// MaxExpandDepth expand参数允许的最大嵌套层数，owner.team为两层
+// 限制层数同时避免了关联之间循环引用导致的无限展开
+const MaxExpandDepth = 3
+
+// LoadFunc 按键批量加载关联记录，keys已去重
+type LoadFunc func(ctx context.Context, keys []any) ([]map[string]any, error)
+
+// Relation 可以通过expand参数展开的关联
+type Relation struct {
+	LocalField   string       // 主记录中引用关联记录的存储字段，值为单个键或键的数组
+	ForeignField string       // 关联记录中与LocalField对应的字段，为空时为_id
+	Schema       *QuerySchema // 关联记录的查询定义，嵌套展开时使用其中的Relations，可以为nil
+	Load         LoadFunc     // 批量加载关联记录
+}
+
+// foreignField 返回关联记录中用于匹配的字段
+func (r *Relation) foreignField() string {
+	if r.ForeignField == "" {
+		return CursorIDKey
+	}
+	return r.ForeignField
+}
+
+// validateExpand 校验expand参数中的关联路径
+func validateExpand(schema *QuerySchema, path string) *FieldError {
+	names := strings.Split(path, ".")
+	if len(names) > MaxExpandDepth {
+		return &FieldError{
+			Param:   "expand",
+			Field:   path,
+			Reason:  ReasonExpandTooDeep,
+			Message: fmt.Sprintf("expand %q is deeper than %d levels", path, MaxExpandDepth),
+		}
+	}
+	current := schema
+	for i, name := range names {
+		var relation *Relation
+		if current != nil {
+			relation = current.Relations[name]
+		}
+		if relation == nil {
+			return &FieldError{
+				Param:   "expand",
+				Field:   path,
+				Reason:  ReasonUnknownRelation,
+				Message: fmt.Sprintf("relation %q is not allowed", strings.Join(names[:i+1], ".")),
+			}
+		}
+		current = relation.Schema
+	}
+	return nil
+}
+
+// expandTree expand参数按层级组成的树
+type expandTree map[string]expandTree
+
+// newExpandTree 由关联路径创建展开树
+func newExpandTree(paths []string) expandTree {
+	tree := expandTree{}
+	for _, path := range paths {
+		node := tree
+		for _, name := range strings.Split(path, ".") {
+			if node[name] == nil {
+				node[name] = expandTree{}
+			}
+			node = node[name]
+		}
+	}
+	return tree
+}
+
+// Expand 按qr.Expand加载关联记录，并以关联名为键写入docs
+// 每一层的每个关联只调用一次Load，关联字段为数组时写入[]map[string]any，否则写入map[string]any，找不到时为nil
+// 关联路径应已通过Validate校验，未注册的关联或超过MaxExpandDepth时返回错误
+func Expand(ctx context.Context, docs []map[string]any, qr *QueryRequest, schema *QuerySchema) error {
+	for _, path := range qr.Expand {
+		if fieldErr := validateExpand(schema, path); fieldErr != nil {
+			return fieldErr
+		}
+	}
+	return expandLevel(ctx, docs, schema, newExpandTree(qr.Expand), "")
+}
+
+// expandLevel 展开一层关联，再以加载的记录展开下一层，prefix为上层的关联路径
+func expandLevel(ctx context.Context, docs []map[string]any, schema *QuerySchema, tree expandTree, prefix string) error {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		relation := schema.Relations[name]
+		related, err := loadRelation(ctx, docs, relation)
+		if err != nil {
+			return fmt.Errorf("expand %s%s: %w", prefix, name, err)
+		}
+
+		index := make(map[any]map[string]any, len(related))
+		for _, doc := range related {
+			if key, ok := relationKey(doc, relation.foreignField()); ok {
+				index[key] = doc
+			}
+		}
+		for _, doc := range docs {
+			doc[name] = resolveRelation(doc, relation.LocalField, index)
+		}
+
+		if len(tree[name]) > 0 && len(related) > 0 {
+			if err = expandLevel(ctx, related, relation.Schema, tree[name], prefix+name+"."); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// loadRelation 收集docs中的去重键并批量加载关联记录
+func loadRelation(ctx context.Context, docs []map[string]any, relation *Relation) ([]map[string]any, error) {
+	var keys []any
+	seen := make(map[any]struct{})
+	for _, doc := range docs {
+		value, found := lookupField(reflect.ValueOf(doc), relation.LocalField)
+		if !found {
+			continue
+		}
+		// lookupField已将bson.A等各类切片转换为[]any
+		values, ok := value.([]any)
+		if !ok {
+			values = []any{value}
+		}
+		for _, v := range values {
+			if v == nil || !reflect.TypeOf(v).Comparable() {
+				continue
+			}
+			if _, ok := seen[v]; !ok {
+				seen[v] = struct{}{}
+				keys = append(keys, v)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return relation.Load(ctx, keys)
+}
+
+// relationKey 返回记录中字段的可比较形式
+func relationKey(doc map[string]any, field string) (any, bool) {
+	value, found := lookupField(reflect.ValueOf(doc), field)
+	if !found || value == nil || !reflect.TypeOf(value).Comparable() {
+		return nil, false
+	}
+	return value, true
+}
+
+// resolveRelation 返回记录对应的关联记录
+func resolveRelation(doc map[string]any, localField string, index map[any]map[string]any) any {
+	value, found := lookupField(reflect.ValueOf(doc), localField)
+	if !found {
+		return nil
+	}
+	values, ok := value.([]any)
+	if !ok {
+		if value == nil || !reflect.TypeOf(value).Comparable() {
+			return nil
+		}
+		if related, ok := index[value]; ok {
+			return related
+		}
+		return nil
+	}
+	list := make([]map[string]any, 0, len(values))
+	for _, v := range values {
+		if v == nil || !reflect.TypeOf(v).Comparable() {
+			continue
+		}
+		if related, ok := index[v]; ok {
+			list = append(list, related)
+		}
+	}
+	return list
+}
@@ -0,0 +1,205 @@
+package dto
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryLoader 从内存中的记录按field加载，记录每次加载的键
+type memoryLoader struct {
+	docs  []map[string]any
+	field string
+	calls [][]any
+}
+
+// load 实现LoadFunc
+func (l *memoryLoader) load(_ context.Context, keys []any) ([]map[string]any, error) {
+	l.calls = append(l.calls, keys)
+	var result []map[string]any
+	for _, doc := range l.docs {
+		for _, key := range keys {
+			if reflect.DeepEqual(doc[l.field], key) {
+				result = append(result, doc)
+			}
+		}
+	}
+	return result, nil
+}
+
+// expandSchemas 测试使用的关联定义：post.owner -> user，user.team -> team，post.tags -> tag，user.posts -> post
+func expandSchemas() (posts *QuerySchema, users, teams, tags *memoryLoader) {
+	teams = &memoryLoader{field: "_id", docs: []map[string]any{{"_id": "t1", "name": "core"}}}
+	users = &memoryLoader{field: "_id", docs: []map[string]any{
+		{"_id": int64(1), "name": "alice", "teamId": "t1"},
+		{"_id": int64(2), "name": "bob", "teamId": "t1"},
+	}}
+	tags = &memoryLoader{field: "slug", docs: []map[string]any{{"slug": "go"}, {"slug": "db"}}}
+
+	userSchema := &QuerySchema{Fields: map[string]FieldSchema{"name": {Type: TypeString}}}
+	postSchema := &QuerySchema{
+		Fields: map[string]FieldSchema{
+			"title":   {Type: TypeString, Sortable: true},
+			"address": {Type: TypeString, Path: "addr"},
+		},
+	}
+	userSchema.Relations = map[string]*Relation{
+		"team":  {LocalField: "teamId", Load: teams.load},
+		"posts": {LocalField: "_id", ForeignField: "ownerId", Schema: postSchema, Load: func(context.Context, []any) ([]map[string]any, error) { return nil, nil }},
+	}
+	postSchema.Relations = map[string]*Relation{
+		"owner": {LocalField: "ownerId", Schema: userSchema, Load: users.load},
+		"tags":  {LocalField: "tagSlugs", ForeignField: "slug", Load: tags.load},
+	}
+	return postSchema, users, teams, tags
+}
+
+func TestExpand(t *testing.T) {
+	schema, users, teams, tags := expandSchemas()
+	docs := []map[string]any{
+		{"_id": 10, "ownerId": 1, "tagSlugs": []string{"go", "db"}},
+		{"_id": 11, "ownerId": 2, "tagSlugs": []string{"go"}},
+		{"_id": 12, "ownerId": 1},
+		{"_id": 13, "ownerId": 9},
+	}
+	qr, err := ParseQueryRequest("expand=owner.team,tags")
+	if err != nil {
+		t.Fatalf("ParseQueryRequest() error = %v", err)
+	}
+	if err = qr.Validate(schema); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	if err = Expand(context.Background(), docs, qr, schema); err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+
+	// 每层每个关联只加载一次，键已去重
+	if want := [][]any{{int64(1), int64(2), int64(9)}}; !reflect.DeepEqual(users.calls, want) {
+		t.Errorf("users loaded with %v, want %v", users.calls, want)
+	}
+	if want := [][]any{{"t1"}}; !reflect.DeepEqual(teams.calls, want) {
+		t.Errorf("teams loaded with %v, want %v", teams.calls, want)
+	}
+	if want := [][]any{{"go", "db"}}; !reflect.DeepEqual(tags.calls, want) {
+		t.Errorf("tags loaded with %v, want %v", tags.calls, want)
+	}
+
+	owner := docs[0]["owner"].(map[string]any)
+	if owner["name"] != "alice" || owner["team"].(map[string]any)["name"] != "core" {
+		t.Errorf("docs[0].owner = %v, want alice in team core", owner)
+	}
+	if got := docs[0]["tags"].([]map[string]any); len(got) != 2 || got[0]["slug"] != "go" {
+		t.Errorf("docs[0].tags = %v, want go and db", got)
+	}
+	if docs[2]["tags"] != nil {
+		t.Errorf("docs[2].tags = %v, want nil", docs[2]["tags"])
+	}
+	if docs[3]["owner"] != nil {
+		t.Errorf("docs[3].owner = %v, want nil", docs[3]["owner"])
+	}
+}
+
+func TestExpandBSONArrays(t *testing.T) {
+	schema, _, _, tags := expandSchemas()
+	// MongoDB解码到map[string]any时数组为bson.A，与primitive.A为同一类型
+	docs := []map[string]any{
+		{"_id": 10, "tagSlugs": bson.A{"go", "db"}},
+		{"_id": 11, "tagSlugs": primitive.A{"db", "missing"}},
+		{"_id": 12, "tagSlugs": bson.A{}},
+	}
+	qr, err := ParseQueryRequest("expand=tags")
+	if err != nil {
+		t.Fatalf("ParseQueryRequest() error = %v", err)
+	}
+	if err = Expand(context.Background(), docs, qr, schema); err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+
+	if want := [][]any{{"go", "db", "missing"}}; !reflect.DeepEqual(tags.calls, want) {
+		t.Errorf("tags loaded with %v, want %v", tags.calls, want)
+	}
+	tests := []struct {
+		doc      int
+		expected []map[string]any
+	}{
+		{doc: 0, expected: []map[string]any{{"slug": "go"}, {"slug": "db"}}},
+		{doc: 1, expected: []map[string]any{{"slug": "db"}}},
+		{doc: 2, expected: []map[string]any{}},
+	}
+	for _, tt := range tests {
+		if got := docs[tt.doc]["tags"]; !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("docs[%d].tags = %v, want %v", tt.doc, got, tt.expected)
+		}
+	}
+}
+
+func TestValidateFieldsAndExpand(t *testing.T) {
+	schema, _, _, _ := expandSchemas()
+	tests := []struct {
+		name       string
+		query      string
+		projection []string
+		wantErrors []FieldError
+	}{
+		{
+			name:       "fields map to paths and include relation keys",
+			query:      "fields=address.city&sort=title.asc&expand=owner.team",
+			projection: []string{"addr.city", "title", "ownerId"},
+		},
+		{
+			name:  "no fields reads everything",
+			query: "expand=tags",
+		},
+		{
+			name:  "unknown field and relation",
+			query: "fields=title,password&expand=owner.secret,comments",
+			wantErrors: []FieldError{
+				{Param: "fields", Field: "password", Reason: ReasonUnknownField},
+				{Param: "expand", Field: "owner.secret", Reason: ReasonUnknownRelation},
+				{Param: "expand", Field: "comments", Reason: ReasonUnknownRelation},
+			},
+		},
+		{
+			name:  "cycles stop at the depth limit",
+			query: "expand=owner.posts.owner.posts",
+			wantErrors: []FieldError{
+				{Param: "expand", Field: "owner.posts.owner.posts", Reason: ReasonExpandTooDeep},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qr, err := ParseQueryRequest(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQueryRequest() error = %v", err)
+			}
+			err = qr.Validate(schema)
+			if len(tt.wantErrors) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				if got := qr.Projection(schema); !reflect.DeepEqual(got, tt.projection) {
+					t.Errorf("Projection() = %v, want %v", got, tt.projection)
+				}
+				return
+			}
+
+			var validationErrs ValidationErrors
+			if !errors.As(err, &validationErrs) {
+				t.Fatalf("Validate() error = %v, want ValidationErrors", err)
+			}
+			got := make([]FieldError, 0, len(validationErrs))
+			for _, fieldErr := range validationErrs {
+				got = append(got, FieldError{Param: fieldErr.Param, Field: fieldErr.Field, Reason: fieldErr.Reason})
+			}
+			if !reflect.DeepEqual(got, tt.wantErrors) {
+				t.Errorf("Validate() errors = %+v, want %+v", got, tt.wantErrors)
+			}
+		})
+	}
+}
@@ -0,0 +1,516 @@
+package dto
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ExprKind 过滤表达式节点类型
+type ExprKind string
+
+const (
+	ExprAnd  ExprKind = "and"  // 所有子节点都满足
+	ExprOr   ExprKind = "or"   // 任一子节点满足
+	ExprNot  ExprKind = "not"  // 唯一的子节点不满足
+	ExprCond ExprKind = "cond" // 单个条件
+)
+
+// Expr 过滤表达式语法树节点
+type Expr struct {
+	Kind      ExprKind   `json:"kind"`                // 节点类型
+	Children  []*Expr    `json:"children,omitempty"`  // and、or、not的子节点
+	Condition *Condition `json:"condition,omitempty"` // cond节点的条件
+}
+
+// And 创建and节点，只有一个子节点时直接返回该子节点，没有子节点时返回nil
+func And(children ...*Expr) *Expr {
+	return combine(ExprAnd, children)
+}
+
+// OrExpr 创建or节点，只有一个子节点时直接返回该子节点，没有子节点时返回nil
+func OrExpr(children ...*Expr) *Expr {
+	return combine(ExprOr, children)
+}
+
+// Not 创建not节点
+func Not(child *Expr) *Expr {
+	return &Expr{Kind: ExprNot, Children: []*Expr{child}}
+}
+
+// Cond 创建条件节点
+func Cond(key string, op Operator, value any) *Expr {
+	return &Expr{Kind: ExprCond, Condition: &Condition{Key: key, Op: op, Value: value}}
+}
+
+// combine 合并子节点，忽略nil
+func combine(kind ExprKind, children []*Expr) *Expr {
+	nonNil := make([]*Expr, 0, len(children))
+	for _, child := range children {
+		if child != nil {
+			nonNil = append(nonNil, child)
+		}
+	}
+	switch len(nonNil) {
+	case 0:
+		return nil
+	case 1:
+		return nonNil[0]
+	}
+	return &Expr{Kind: kind, Children: nonNil}
+}
+
+// Conditions 按出现顺序返回表达式中的所有条件，修改条件会修改表达式
+func (e *Expr) Conditions() []*Condition {
+	if e == nil {
+		return nil
+	}
+	if e.Kind == ExprCond {
+		return []*Condition{e.Condition}
+	}
+	var conditions []*Condition
+	for _, child := range e.Children {
+		conditions = append(conditions, child.Conditions()...)
+	}
+	return conditions
+}
+
+// String 以filter参数的语法输出表达式
+func (e *Expr) String() string {
+	if e == nil {
+		return ""
+	}
+	switch e.Kind {
+	case ExprCond:
+		if r, ok := e.Condition.Value.(Range); ok {
+			return e.Condition.Key + " " + string(e.Condition.Op) + " " + formatFilterValue(r.Min) + " and " + formatFilterValue(r.Max)
+		}
+		return e.Condition.Key + " " + string(e.Condition.Op) + " " + formatFilterValue(e.Condition.Value)
+	case ExprNot:
+		return "not " + e.Children[0].operand(ExprNot)
+	default:
+		parts := make([]string, 0, len(e.Children))
+		for _, child := range e.Children {
+			parts = append(parts, child.operand(e.Kind))
+		}
+		return strings.Join(parts, " "+string(e.Kind)+" ")
+	}
+}
+
+// operand 作为parent的子节点输出，优先级较低时加括号
+func (e *Expr) operand(parent ExprKind) string {
+	needParens := (e.Kind == ExprOr && parent != ExprOr) || (e.Kind == ExprAnd && parent == ExprNot)
+	if needParens {
+		return "(" + e.String() + ")"
+	}
+	return e.String()
+}
+
+// formatFilterValue 以filter参数的语法输出值
+func formatFilterValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return fmt.Sprint(v)
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case []string:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, formatFilterValue(item))
+		}
+		return "(" + strings.Join(items, ", ") + ")"
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, formatFilterValue(item))
+		}
+		return "(" + strings.Join(items, ", ") + ")"
+	case time.Time:
+		return formatFilterValue(v.Format(time.RFC3339Nano))
+	case fmt.Stringer:
+		return formatFilterValue(v.String())
+	default:
+		return formatFilterValue(fmt.Sprint(v))
+	}
+}
+
+// Expr 返回整个查询的过滤表达式
+// 后缀形式的条件以and连接，其中所有_or条件组成一个or节点，再与filter参数的表达式以and连接
+func (qr *QueryRequest) Expr() *Expr {
+	var and, or []*Expr
+	for _, c := range qr.Condition {
+		if c.Op == Or {
+			or = append(or, Cond(c.Key, Equal, c.Value))
+			continue
+		}
+		and = append(and, Cond(c.Key, c.Op, c.Value))
+	}
+	return And(append(and, OrExpr(or...), qr.Filter)...)
+}
+
+// SyntaxError filter参数的语法错误
+type SyntaxError struct {
+	Pos int    `json:"pos"` // 出错位置，从1开始的字符序号
+	Msg string `json:"msg"` // 错误描述
+}
+
+// Error 实现error接口
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("filter: syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+// filterOperators filter参数支持的操作符
+var filterOperators = map[string]Operator{
+	string(Equal):          Equal,
+	string(NotEqual):       NotEqual,
+	string(Greater):        Greater,
+	string(Less):           Less,
+	string(GreaterOrEqual): GreaterOrEqual,
+	string(LessOrEqual):    LessOrEqual,
+	string(In):             In,
+	string(Like):           Like,
+	string(NotIn):          NotIn,
+	string(Between):        Between,
+	string(Exists):         Exists,
+	string(IsNull):         IsNull,
+	string(StartsWith):     StartsWith,
+	string(EndsWith):       EndsWith,
+	string(ILike):          ILike,
+	string(Contains):       Contains,
+	string(All):            All,
+}
+
+// ParseFilter 解析filter参数
+//
+//	(status eq 'active' or age gt 30) and city in ('NY','SF')
+//	price between 10 and 100 and not deleted exists true
+//
+// in、nin与all的值为括号中的列表，between的值为"下限 and 上限"，null表示该端不限制；
+// 关键字不区分大小写，优先级从高到低为not、and、or；字符串使用单引号，两个单引号表示一个单引号；
+// 与后缀形式一致，值保留为字符串，in的值为[]string，由Validate按字段类型转换；
+// now-7d、startOfDay+8h等相对时间无需引号，作为一个值读取
+func ParseFilter(s string) (*Expr, error) {
+	p := &filterParser{input: []rune(s)}
+	p.next()
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+	return expr, nil
+}
+
+// tokenKind 词法单元类型
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+// token 词法单元
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// String 用于错误信息
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of input"
+	case tokString:
+		return fmt.Sprintf("string '%s'", t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// maxFilterNesting 解析时括号与not的最大嵌套层数，避免过深的递归
+// 可以配置的层数限制为QueryLimits.MaxFilterDepth，由Validate检查
+const maxFilterNesting = 100
+
+// filterParser 递归下降解析器
+type filterParser struct {
+	input   []rune
+	pos     int
+	tok     token
+	err     *SyntaxError
+	nesting int
+}
+
+// errorf 在当前词法单元位置生成语法错误
+func (p *filterParser) errorf(format string, args ...any) error {
+	if p.err != nil {
+		return p.err
+	}
+	return &SyntaxError{Pos: p.tok.pos + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// keyword 判断当前词法单元是否为关键字
+func (p *filterParser) keyword(word string) bool {
+	return p.tok.kind == tokIdent && strings.EqualFold(p.tok.text, word)
+}
+
+// parseOr or := and ('or' and)*
+func (p *filterParser) parseOr() (*Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	children := []*Expr{left}
+	for p.keyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, right)
+	}
+	return OrExpr(children...), nil
+}
+
+// parseAnd and := unary ('and' unary)*
+func (p *filterParser) parseAnd() (*Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	children := []*Expr{left}
+	for p.keyword("and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, right)
+	}
+	return And(children...), nil
+}
+
+// parseUnary unary := 'not' unary | '(' or ')' | condition
+func (p *filterParser) parseUnary() (*Expr, error) {
+	if p.keyword("not") || p.tok.kind == tokLParen {
+		if p.nesting++; p.nesting > maxFilterNesting {
+			return nil, p.errorf("filter is nested deeper than %d levels", maxFilterNesting)
+		}
+		defer func() { p.nesting-- }()
+	}
+	if p.keyword("not") {
+		p.next()
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(child), nil
+	}
+	if p.tok.kind == tokLParen {
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, p.errorf("expected ')' but found %s", p.tok)
+		}
+		p.next()
+		return expr, nil
+	}
+	return p.parseCondition()
+}
+
+// parseCondition condition := field operator value
+func (p *filterParser) parseCondition() (*Expr, error) {
+	if p.tok.kind != tokIdent {
+		return nil, p.errorf("expected field name but found %s", p.tok)
+	}
+	key := p.tok.text
+	p.next()
+
+	if p.tok.kind != tokIdent {
+		return nil, p.errorf("expected operator after %q but found %s", key, p.tok)
+	}
+	op, ok := filterOperators[strings.ToLower(p.tok.text)]
+	if !ok {
+		return nil, p.errorf("unknown operator %q", p.tok.text)
+	}
+	p.next()
+
+	value, err := p.parseValue(op)
+	if err != nil {
+		return nil, err
+	}
+	return Cond(key, op, value), nil
+}
+
+// parseValue 按操作符解析条件的值，值的形式与后缀形式的参数一致
+func (p *filterParser) parseValue(op Operator) (any, error) {
+	switch op {
+	case In, NotIn, All:
+		return p.parseList(op)
+	case Between:
+		return p.parseRange()
+	case Exists, IsNull:
+		value, err := p.parseScalar()
+		if err != nil {
+			return nil, err
+		}
+		return parseConditionValue(op, value), nil
+	default:
+		return p.parseScalar()
+	}
+}
+
+// parseRange 解析between的"下限 and 上限"
+func (p *filterParser) parseRange() (any, error) {
+	bound := func() (any, error) {
+		if p.keyword("null") {
+			p.next()
+			return nil, nil
+		}
+		return p.parseScalar()
+	}
+	lower, err := bound()
+	if err != nil {
+		return nil, err
+	}
+	if !p.keyword("and") {
+		return nil, p.errorf("expected and in between but found %s", p.tok)
+	}
+	p.next()
+	upper, err := bound()
+	if err != nil {
+		return nil, err
+	}
+	return Range{Min: lower, Max: upper}, nil
+}
+
+// parseList 解析括号中逗号分隔的列表
+func (p *filterParser) parseList(op Operator) (any, error) {
+	if p.tok.kind != tokLParen {
+		return nil, p.errorf("expected '(' after %s but found %s", op, p.tok)
+	}
+	p.next()
+	values := make([]string, 0)
+	for {
+		value, err := p.parseScalar()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if p.tok.kind == tokRParen {
+			p.next()
+			return values, nil
+		}
+		if p.tok.kind != tokComma {
+			return nil, p.errorf("expected ',' or ')' but found %s", p.tok)
+		}
+		p.next()
+	}
+}
+
+// parseScalar 解析字符串、数字或true、false等单词
+func (p *filterParser) parseScalar() (string, error) {
+	switch p.tok.kind {
+	case tokString, tokNumber, tokIdent:
+		value := p.tok.text
+		p.next()
+		return value, nil
+	default:
+		return "", p.errorf("expected value but found %s", p.tok)
+	}
+}
+
+// next 读取下一个词法单元
+func (p *filterParser) next() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.input) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+
+	c := p.input[p.pos]
+	switch {
+	case c == '(':
+		p.pos++
+		p.tok = token{kind: tokLParen, text: "(", pos: start}
+	case c == ')':
+		p.pos++
+		p.tok = token{kind: tokRParen, text: ")", pos: start}
+	case c == ',':
+		p.pos++
+		p.tok = token{kind: tokComma, text: ",", pos: start}
+	case c == '\'':
+		p.tok = p.scanString(start)
+	case c == '-' || c == '+' || unicode.IsDigit(c):
+		p.pos++
+		for p.pos < len(p.input) && isNumberRune(p.input[p.pos]) {
+			p.pos++
+		}
+		p.tok = token{kind: tokNumber, text: string(p.input[start:p.pos]), pos: start}
+	case isIdentRune(c):
+		for p.pos < len(p.input) && isIdentRune(p.input[p.pos]) {
+			p.pos++
+		}
+		if _, ok := relativeBases[strings.ToLower(string(p.input[start:p.pos]))]; ok {
+			// 相对时间的偏移与起点一起读取，例如now-7d
+			for p.pos < len(p.input) && (isIdentRune(p.input[p.pos]) || p.input[p.pos] == '-' || p.input[p.pos] == '+') {
+				p.pos++
+			}
+		}
+		p.tok = token{kind: tokIdent, text: string(p.input[start:p.pos]), pos: start}
+	default:
+		p.pos++
+		p.tok = token{kind: tokEOF, pos: start}
+		p.err = &SyntaxError{Pos: start + 1, Msg: fmt.Sprintf("unexpected character %q", c)}
+	}
+}
+
+// scanString 读取单引号字符串，两个单引号表示一个单引号
+func (p *filterParser) scanString(start int) token {
+	var sb strings.Builder
+	p.pos++
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		p.pos++
+		if c != '\'' {
+			sb.WriteRune(c)
+			continue
+		}
+		if p.pos < len(p.input) && p.input[p.pos] == '\'' {
+			sb.WriteRune('\'')
+			p.pos++
+			continue
+		}
+		return token{kind: tokString, text: sb.String(), pos: start}
+	}
+	p.err = &SyntaxError{Pos: start + 1, Msg: "unterminated string"}
+	return token{kind: tokEOF, pos: start}
+}
+
+// isIdentRune 判断是否为字段名或关键字的字符，字段名可以使用点号访问嵌套字段
+func isIdentRune(c rune) bool {
+	return c == '_' || c == '.' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// isNumberRune 判断是否为数字的字符
+func isNumberRune(c rune) bool {
+	return unicode.IsDigit(c) || c == '.' || c == 'e' || c == 'E' || c == '-' || c == '+' || c == ':' || c == 'T' || c == 'Z'
+}
@@ -0,0 +1,243 @@
+package dto
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   string
+		expected *Expr
+	}{
+		{
+			name:     "single condition",
+			filter:   "status eq 'active'",
+			expected: Cond("status", Equal, "active"),
+		},
+		{
+			name:   "and binds tighter than or",
+			filter: "status eq 'active' or age gt 30 and city eq 'NY'",
+			expected: OrExpr(
+				Cond("status", Equal, "active"),
+				And(Cond("age", Greater, "30"), Cond("city", Equal, "NY")),
+			),
+		},
+		{
+			name:   "grouping and in list",
+			filter: "(status eq 'active' or age gt 30) and city in ('NY','SF')",
+			expected: And(
+				OrExpr(Cond("status", Equal, "active"), Cond("age", Greater, "30")),
+				Cond("city", In, []string{"NY", "SF"}),
+			),
+		},
+		{
+			name:   "relative times without quotes",
+			filter: "created_at gt now-7d and updated_at between startOfDay-1d+8h and NOW",
+			expected: And(
+				Cond("created_at", Greater, "now-7d"),
+				Cond("updated_at", Between, Range{Min: "startOfDay-1d+8h", Max: "NOW"}),
+			),
+		},
+		{
+			name:     "not and case insensitive keywords",
+			filter:   "NOT (deleted EQ true) AND name like 'o''brien'",
+			expected: And(Not(Cond("deleted", Equal, "true")), Cond("name", Like, "o'brien")),
+		},
+		{
+			name:   "extended operators",
+			filter: "price between 10 and 100 and tags all ('a','b') and status nin ('x') and deleted exists false",
+			expected: And(
+				Cond("price", Between, Range{Min: "10", Max: "100"}),
+				Cond("tags", All, []string{"a", "b"}),
+				Cond("status", NotIn, []string{"x"}),
+				Cond("deleted", Exists, false),
+			),
+		},
+		{
+			name:     "open range",
+			filter:   "createdAt between '2024-01-01' and null",
+			expected: Cond("createdAt", Between, Range{Min: "2024-01-01"}),
+		},
+		{
+			name:   "string operators",
+			filter: "name startswith 'Jo' or name endswith 'son' or name ilike 'BOB' or tags contains 'go'",
+			expected: OrExpr(
+				Cond("name", StartsWith, "Jo"),
+				Cond("name", EndsWith, "son"),
+				Cond("name", ILike, "BOB"),
+				Cond("tags", Contains, "go"),
+			),
+		},
+		{
+			name:     "nested field and negative number",
+			filter:   "address.zip ne -1",
+			expected: Cond("address.zip", NotEqual, "-1"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("ParseFilter() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ParseFilter() = %v, want %v", got, tt.expected)
+			}
+
+			// 输出的表达式可以重新解析为相同的语法树
+			reparsed, err := ParseFilter(got.String())
+			if err != nil {
+				t.Fatalf("ParseFilter(%q) error = %v", got.String(), err)
+			}
+			if !reflect.DeepEqual(reparsed, got) {
+				t.Errorf("ParseFilter(%q) = %v, want %v", got.String(), reparsed, got)
+			}
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   string
+		expected SyntaxError
+	}{
+		{
+			name:     "missing value",
+			filter:   "age gt",
+			expected: SyntaxError{Pos: 7, Msg: "expected value but found end of input"},
+		},
+		{
+			name:     "unknown operator",
+			filter:   "age approx 1",
+			expected: SyntaxError{Pos: 5, Msg: `unknown operator "approx"`},
+		},
+		{
+			name:     "between without and",
+			filter:   "age between 1 or 2",
+			expected: SyntaxError{Pos: 15, Msg: `expected and in between but found "or"`},
+		},
+		{
+			name:     "unclosed group",
+			filter:   "(age gt 1 or age lt 0",
+			expected: SyntaxError{Pos: 22, Msg: "expected ')' but found end of input"},
+		},
+		{
+			name:     "unterminated string",
+			filter:   "name eq 'bob",
+			expected: SyntaxError{Pos: 9, Msg: "unterminated string"},
+		},
+		{
+			name:     "unexpected character",
+			filter:   "name eq 'bob' & age gt 1",
+			expected: SyntaxError{Pos: 15, Msg: `unexpected character '&'`},
+		},
+		{
+			name:     "trailing tokens",
+			filter:   "name eq 'bob' age",
+			expected: SyntaxError{Pos: 15, Msg: `unexpected "age"`},
+		},
+		{
+			name:     "in without list",
+			filter:   "city in 'NY'",
+			expected: SyntaxError{Pos: 9, Msg: "expected '(' after in but found string 'NY'"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFilter(tt.filter)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("ParseFilter() error = %v, want *SyntaxError", err)
+			}
+			if !reflect.DeepEqual(*syntaxErr, tt.expected) {
+				t.Errorf("ParseFilter() error = %+v, want %+v", *syntaxErr, tt.expected)
+			}
+		})
+	}
+}
+
+func TestQueryRequestExpr(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected *Expr
+	}{
+		{
+			name:     "no conditions",
+			query:    "page=2",
+			expected: nil,
+		},
+		{
+			name:     "suffix conditions are anded",
+			query:    "age_gte=18&name=bob",
+			expected: And(Cond("age", GreaterOrEqual, "18"), Cond("name", Equal, "bob")),
+		},
+		{
+			name:  "or suffix conditions form one group",
+			query: "status=active&category_or=books&category_or=music",
+			expected: And(
+				Cond("status", Equal, "active"),
+				OrExpr(Cond("category", Equal, "books"), Cond("category", Equal, "music")),
+			),
+		},
+		{
+			name:  "suffix conditions and filter",
+			query: "status=active&filter=" + "age%20gt%2030%20or%20vip%20eq%20true",
+			expected: And(
+				Cond("status", Equal, "active"),
+				OrExpr(Cond("age", Greater, "30"), Cond("vip", Equal, "true")),
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qr, err := ParseQueryRequest(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQueryRequest() error = %v", err)
+			}
+			if got := qr.Expr(); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expr() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseQueryRequestFilterError(t *testing.T) {
+	qr, err := ParseQueryRequest("page=2&filter=age%20gt")
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("ParseQueryRequest() error = %v, want *SyntaxError", err)
+	}
+	if qr.Page != 2 || qr.Filter != nil {
+		t.Errorf("ParseQueryRequest() = %+v, want page 2 without filter", qr)
+	}
+
+	// NewQueryRequestFromURL忽略有语法错误的filter参数
+	if qr := NewQueryRequestFromURL("filter=age%20gt"); qr.Filter != nil || len(qr.Condition) != 0 {
+		t.Errorf("NewQueryRequestFromURL() = %+v, want no filter", qr)
+	}
+}
+
+func TestValidateFilter(t *testing.T) {
+	qr, err := ParseQueryRequest("filter=" + "age%20gt%2018%20and%20password%20eq%20'x'")
+	if err != nil {
+		t.Fatalf("ParseQueryRequest() error = %v", err)
+	}
+	var errs ValidationErrors
+	if !errors.As(qr.Validate(testSchema), &errs) || len(errs) != 1 {
+		t.Fatalf("Validate() = %v, want one error", errs)
+	}
+	if errs[0].Param != "filter" || errs[0].Reason != ReasonUnknownField {
+		t.Errorf("errors[0] = %+v", errs[0])
+	}
+	if got := qr.Filter.Children[0].Condition.Value; got != int64(18) {
+		t.Errorf("age value = %#v, want int64(18)", got)
+	}
+}
@@ -0,0 +1,137 @@
+package dto
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"unicode/utf8"
+)
+
+// QueryLimits 查询请求的安全限制，超出时Validate返回ReasonLimitExceeded错误，字段为0时不限制
+// like、ilike、startswith、endswith的值在mongo与sqlquery中总是按字面量转义（regexp.QuoteMeta或LIKE转义），
+// 不会形成正则表达式或通配符，因此只限制长度；(page-1)*page_size超出int范围时无论是否设置MaxOffset都返回错误
+type QueryLimits struct {
+	MaxPageSize      int // page_size的最大值
+	MaxOffset        int // 分页跳过的最大记录数，即(page-1)*page_size，游标分页不受限制
+	MaxConditions    int // 条件的最大数量，包括filter中的条件
+	MaxInValues      int // in、nin、all列表的最大长度
+	MaxPatternLength int // like、ilike、startswith、endswith值的最大字符数，值按字面量匹配
+	MaxSortKeys      int // 排序字段的最大数量
+	MaxFilterDepth   int // filter表达式的最大嵌套层数，单个条件为1层
+}
+
+// DefaultQueryLimits QuerySchema未设置Limits时使用的限制
+var DefaultQueryLimits = QueryLimits{
+	MaxPageSize:      100,
+	MaxOffset:        10000,
+	MaxConditions:    20,
+	MaxInValues:      100,
+	MaxPatternLength: 100,
+	MaxSortKeys:      5,
+	MaxFilterDepth:   8,
+}
+
+// limits 返回schema使用的限制
+func (s *QuerySchema) limits() QueryLimits {
+	if s.Limits == nil {
+		return DefaultQueryLimits
+	}
+	return *s.Limits
+}
+
+// patternOperators 值会转换为正则表达式或LIKE模式的操作符
+// 值在转换时按字面量转义，不会形成回溯过多的正则表达式，因此只限制长度
+var patternOperators = []Operator{Like, ILike, StartsWith, EndsWith}
+
+// validateLimits 检查查询请求是否超出限制，在转换条件的值之前调用
+func validateLimits(limits QueryLimits, qr *QueryRequest) ValidationErrors {
+	var errs ValidationErrors
+	limitErr := func(param, field string, op Operator, format string, args ...any) {
+		errs = append(errs, &FieldError{
+			Param:   param,
+			Field:   field,
+			Op:      op,
+			Reason:  ReasonLimitExceeded,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if limits.MaxPageSize > 0 && qr.PageSize > limits.MaxPageSize {
+		limitErr("page_size", "", "", "page_size %d exceeds the maximum of %d", qr.PageSize, limits.MaxPageSize)
+	}
+	if qr.PageSize > 0 && qr.Cursor == "" {
+		switch page := max(qr.Page, 1); {
+		case page-1 > math.MaxInt/qr.PageSize:
+			limitErr("page", "", "", "page %d with page_size %d is too large", qr.Page, qr.PageSize)
+		case limits.MaxOffset > 0 && qr.Offset() > limits.MaxOffset:
+			limitErr("page", "", "", "page %d with page_size %d skips %d records, the maximum is %d", qr.Page, qr.PageSize, qr.Offset(), limits.MaxOffset)
+		}
+	}
+	if limits.MaxSortKeys > 0 && len(qr.Order) > limits.MaxSortKeys {
+		limitErr("sort", "", "", "sorting by %d fields exceeds the maximum of %d", len(qr.Order), limits.MaxSortKeys)
+	}
+	if depth := qr.Filter.depth(); limits.MaxFilterDepth > 0 && depth > limits.MaxFilterDepth {
+		limitErr("filter", "", "", "filter is nested %d levels, the maximum is %d", depth, limits.MaxFilterDepth)
+	}
+
+	type paramCondition struct {
+		param string
+		c     *Condition
+	}
+	conditions := make([]paramCondition, 0, len(qr.Condition))
+	for i := range qr.Condition {
+		conditions = append(conditions, paramCondition{conditionParam(qr.Condition[i]), &qr.Condition[i]})
+	}
+	for _, c := range qr.Filter.Conditions() {
+		conditions = append(conditions, paramCondition{"filter", c})
+	}
+	if limits.MaxConditions > 0 && len(conditions) > limits.MaxConditions {
+		// 报告第一个超出数量的条件
+		first := conditions[limits.MaxConditions]
+		limitErr(first.param, first.c.Key, first.c.Op, "%d conditions exceed the maximum of %d", len(conditions), limits.MaxConditions)
+	}
+	for _, pc := range conditions {
+		c := pc.c
+		switch {
+		case c.Op == In || c.Op == NotIn || c.Op == All:
+			if n := listLength(c.Value); limits.MaxInValues > 0 && n > limits.MaxInValues {
+				limitErr(pc.param, c.Key, c.Op, "%s list has %d values, the maximum is %d", c.Op, n, limits.MaxInValues)
+			}
+		case slices.Contains(patternOperators, c.Op):
+			if n := utf8.RuneCountInString(fmt.Sprint(c.Value)); limits.MaxPatternLength > 0 && n > limits.MaxPatternLength {
+				limitErr(pc.param, c.Key, c.Op, "%s pattern has %d characters, the maximum is %d", c.Op, n, limits.MaxPatternLength)
+			}
+		}
+	}
+	return errs
+}
+
+// Offset 返回分页跳过的记录数，page小于1时按1处理，没有分页时为0
+// 应在Validate之后调用，Validate保证计算不会溢出
+func (qr *QueryRequest) Offset() int {
+	if qr.PageSize <= 0 {
+		return 0
+	}
+	return (max(qr.Page, 1) - 1) * qr.PageSize
+}
+
+// listLength 返回列表条件值的长度，不是列表时为1
+func listLength(value any) int {
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Slice {
+		return v.Len()
+	}
+	return 1
+}
+
+// depth 返回表达式的嵌套层数，nil为0
+func (e *Expr) depth() int {
+	if e == nil {
+		return 0
+	}
+	deepest := 0
+	for _, child := range e.Children {
+		deepest = max(deepest, child.depth())
+	}
+	return deepest + 1
+}
@@ -0,0 +1,177 @@
+package dto
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestValidateLimits(t *testing.T) {
+	limited := &QuerySchema{
+		Fields: map[string]FieldSchema{
+			"name": {Type: TypeString, Sortable: true},
+			"age":  {Type: TypeInt, Sortable: true},
+		},
+		Limits: &QueryLimits{
+			MaxPageSize:      50,
+			MaxOffset:        1000,
+			MaxConditions:    3,
+			MaxInValues:      2,
+			MaxPatternLength: 5,
+			MaxSortKeys:      1,
+			MaxFilterDepth:   2,
+		},
+	}
+	unlimited := &QuerySchema{Fields: limited.Fields, Limits: &QueryLimits{}}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantErrors []FieldError
+	}{
+		{
+			name:  "within limits",
+			query: "page_size=50&age_in=1,2&name_like=abcde&sort=age.desc&filter=" + "not (age eq 1)",
+		},
+		{
+			name:  "page size and sort keys",
+			query: "page_size=51&sort=age.desc,name.asc",
+			wantErrors: []FieldError{
+				{Param: "page_size", Reason: ReasonLimitExceeded},
+				{Param: "sort", Reason: ReasonLimitExceeded},
+			},
+		},
+		{
+			name:       "offset",
+			query:      "page=22&page_size=50",
+			wantErrors: []FieldError{{Param: "page", Reason: ReasonLimitExceeded}},
+		},
+		{
+			name:  "list length and pattern length",
+			query: "age_nin=1,2,3&name_startswith=abcdef&filter=" + "name ilike 'abcdé'",
+			wantErrors: []FieldError{
+				{Param: "age_nin", Field: "age", Op: NotIn, Reason: ReasonLimitExceeded},
+				{Param: "name_startswith", Field: "name", Op: StartsWith, Reason: ReasonLimitExceeded},
+			},
+		},
+		{
+			name:  "conditions include filter",
+			query: "age=1&name=a&filter=" + "age gt 1 and age lt 5",
+			wantErrors: []FieldError{
+				{Param: "filter", Field: "age", Op: Less, Reason: ReasonLimitExceeded},
+			},
+		},
+		{
+			name:  "filter depth",
+			query: "filter=" + "not (age eq 1 or name eq 'a')",
+			wantErrors: []FieldError{
+				{Param: "filter", Reason: ReasonLimitExceeded},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qr, err := ParseQueryRequest(strings.ReplaceAll(tt.query, " ", "+"))
+			if err != nil {
+				t.Fatalf("ParseQueryRequest() error = %v", err)
+			}
+			unlimitedQuery := *qr
+			if err := unlimitedQuery.Validate(unlimited); err != nil {
+				t.Errorf("Validate() without limits error = %v", err)
+			}
+
+			err = qr.Validate(limited)
+			if len(tt.wantErrors) == 0 {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			var validationErrs ValidationErrors
+			if !errors.As(err, &validationErrs) {
+				t.Fatalf("Validate() error = %v, want ValidationErrors", err)
+			}
+			got := make([]FieldError, 0, len(validationErrs))
+			for _, fieldErr := range validationErrs {
+				got = append(got, FieldError{Param: fieldErr.Param, Field: fieldErr.Field, Op: fieldErr.Op, Reason: fieldErr.Reason})
+			}
+			if !reflect.DeepEqual(got, tt.wantErrors) {
+				t.Errorf("Validate() errors = %+v, want %+v", got, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestValidateDefaultLimits(t *testing.T) {
+	schema := &QuerySchema{Fields: map[string]FieldSchema{"name": {Type: TypeString}}}
+	qr, err := ParseQueryRequest("page_size=1000000")
+	if err != nil {
+		t.Fatalf("ParseQueryRequest() error = %v", err)
+	}
+	var validationErrs ValidationErrors
+	if err = qr.Validate(schema); !errors.As(err, &validationErrs) || validationErrs[0].Reason != ReasonLimitExceeded {
+		t.Errorf("Validate() error = %v, want page_size limit_exceeded", err)
+	}
+}
+
+func TestValidateOffsetOverflow(t *testing.T) {
+	schema := &QuerySchema{Fields: map[string]FieldSchema{"name": {Type: TypeString}}, Limits: &QueryLimits{}}
+	qr, err := ParseQueryRequest("page=" + strconv.Itoa(math.MaxInt/2) + "&page_size=10")
+	if err != nil {
+		t.Fatalf("ParseQueryRequest() error = %v", err)
+	}
+	var validationErrs ValidationErrors
+	if err = qr.Validate(schema); !errors.As(err, &validationErrs) || validationErrs[0].Param != "page" {
+		t.Errorf("Validate() error = %v, want page limit_exceeded", err)
+	}
+
+	// 游标分页不使用page
+	qr.Cursor = "x"
+	if errs := validateLimits(QueryLimits{}, qr); len(errs) != 0 {
+		t.Errorf("validateLimits() with cursor = %v, want none", errs)
+	}
+}
+
+func TestParseQueryRequestInvalidParams(t *testing.T) {
+	qr, err := ParseQueryRequest("page=0&page_size=abc&sort=name.up,age.desc&name=bob&filter=" + "a+eq")
+
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("ParseQueryRequest() error = %v, want ValidationErrors", err)
+	}
+	var params []string
+	for _, fieldErr := range validationErrs {
+		params = append(params, fieldErr.Param)
+	}
+	if want := []string{"page", "page_size", "sort"}; !reflect.DeepEqual(params, want) {
+		t.Errorf("ParseQueryRequest() error params = %v, want %v", params, want)
+	}
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Errorf("ParseQueryRequest() error = %v, want *SyntaxError", err)
+	}
+
+	// 其他参数仍然解析，格式错误的参数使用默认值
+	if qr.Page != 1 || qr.PageSize != defaultPageSize {
+		t.Errorf("Page, PageSize = %d, %d, want defaults", qr.Page, qr.PageSize)
+	}
+	if want := []Order{{Key: "age", Direction: Descending}}; !reflect.DeepEqual(qr.Order, want) {
+		t.Errorf("Order = %v, want %v", qr.Order, want)
+	}
+	if want := []Condition{{Key: "name", Op: Equal, Value: "bob"}}; !reflect.DeepEqual(qr.Condition, want) {
+		t.Errorf("Condition = %v, want %v", qr.Condition, want)
+	}
+}
+
+func TestParseFilterNesting(t *testing.T) {
+	if _, err := ParseFilter(strings.Repeat("(", maxFilterNesting) + "a eq 1" + strings.Repeat(")", maxFilterNesting)); err != nil {
+		t.Errorf("ParseFilter() error = %v", err)
+	}
+	var syntaxErr *SyntaxError
+	if _, err := ParseFilter(strings.Repeat("not ", maxFilterNesting+1) + "a eq 1"); !errors.As(err, &syntaxErr) {
+		t.Errorf("ParseFilter() error = %v, want *SyntaxError", err)
+	}
+}
@@ -0,0 +1,432 @@
+package dto
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// defaultPageSize 未指定page_size时的每页数量
+const defaultPageSize = 10
+
+// reservedParams 不作为查询条件解析的参数
+// 名称与之相同的字段需使用key_eq形式查询，例如tz_eq=x，Encode同样输出该形式；
+// tz为时区参数，tz=x不再是tz字段的相等条件
+var reservedParams = []string{"page", "page_size", "sort", "filter", "cursor", "fields", "expand", "group_by", "agg", "tz"}
+
+// jsonQueryRequest JSON格式的查询请求，filter可以是表达式对象或filter参数语法的字符串
+type jsonQueryRequest struct {
+	Page      int             `json:"page"`
+	PageSize  int             `json:"page_size"`
+	Condition []Condition     `json:"condition"`
+	Filter    json.RawMessage `json:"filter"`
+	Order     []Order         `json:"order"`
+	Cursor    string          `json:"cursor"`
+	Fields    []string        `json:"fields"`
+	Expand    []string        `json:"expand"`
+	GroupBy   []string        `json:"group_by"`
+	Agg       []Aggregation   `json:"agg"`
+	TimeZone  string          `json:"tz"`
+}
+
+// ParseQueryRequestJSON 从JSON创建QueryRequest，默认值与ParseQueryRequest一致
+// 条件的值按URL参数的形式规范化：标量转换为字符串，in、nin、all为[]string，between为Range，exists、isnull为bool；
+// 数组只用于in、nin、all与between，元素中的逗号保持原样；{"min","max"}对象只用于between
+// page、page_size为负数或条件、排序、聚合、过滤表达式的结构无效时返回ValidationErrors，Param为JSON中的字段名；
+// filter的语法错误为*SyntaxError，两者都有时返回errors.Join的结果
+func ParseQueryRequestJSON(data []byte) (*QueryRequest, error) {
+	var in jsonQueryRequest
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&in); err != nil {
+		return nil, fmt.Errorf("query: invalid JSON: %w", err)
+	}
+
+	qr := &QueryRequest{
+		Page:      1,
+		PageSize:  defaultPageSize,
+		Condition: make([]Condition, 0, len(in.Condition)),
+		Order:     make([]Order, 0, len(in.Order)),
+		Cursor:    in.Cursor,
+		Fields:    parseList(strings.Join(in.Fields, ",")),
+		Expand:    parseList(strings.Join(in.Expand, ",")),
+		GroupBy:   parseList(strings.Join(in.GroupBy, ",")),
+		TimeZone:  in.TimeZone,
+	}
+	var errs ValidationErrors
+	for _, err := range []*FieldError{jsonPositiveInt("page", in.Page), jsonPositiveInt("page_size", in.PageSize)} {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if in.Page > 0 {
+		qr.Page = in.Page
+	}
+	if in.PageSize > 0 {
+		qr.PageSize = in.PageSize
+	}
+
+	for _, c := range in.Condition {
+		if err := normalizeJSONCondition(&c, "condition"); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		qr.Condition = append(qr.Condition, c)
+	}
+
+	for _, order := range in.Order {
+		switch {
+		case order.Key == "":
+			errs = append(errs, &FieldError{
+				Param:   "order",
+				Reason:  ReasonInvalidValue,
+				Message: "order key is empty",
+			})
+		case order.Direction != Ascending && order.Direction != Descending:
+			errs = append(errs, &FieldError{
+				Param:   "order",
+				Field:   order.Key,
+				Reason:  ReasonInvalidValue,
+				Message: fmt.Sprintf("invalid order direction %q for %q", order.Direction, order.Key),
+			})
+		default:
+			qr.Order = append(qr.Order, order)
+		}
+	}
+
+	for _, agg := range in.Agg {
+		if agg.Func == "" {
+			errs = append(errs, &FieldError{
+				Param:   "agg",
+				Field:   agg.Field,
+				Reason:  ReasonAggregateNotAllowed,
+				Message: "aggregation function is empty",
+			})
+			continue
+		}
+		qr.Agg = append(qr.Agg, agg)
+	}
+
+	filter, filterErrs, err := parseJSONFilter(in.Filter)
+	errs = append(errs, filterErrs...)
+	qr.Filter = filter
+
+	switch {
+	case len(errs) > 0 && err != nil:
+		return nil, errors.Join(errs, err)
+	case len(errs) > 0:
+		return nil, errs
+	case err != nil:
+		return nil, err
+	default:
+		return qr, nil
+	}
+}
+
+// jsonPositiveInt 校验JSON中的page或page_size，0表示未指定，负数返回与parsePositiveInt相同形式的错误
+func jsonPositiveInt(param string, n int) *FieldError {
+	if n >= 0 {
+		return nil
+	}
+	return &FieldError{
+		Param:   param,
+		Reason:  ReasonInvalidValue,
+		Message: fmt.Sprintf("%s must be a positive integer, got %d", param, n),
+	}
+}
+
+// parseJSONFilter 解析JSON中的filter，字符串按filter参数语法解析
+// 表达式对象规范化后重新解析，结果与相同内容的filter参数一致
+// 表达式对象的结构错误在errs中返回，err为JSON或filter语法的错误
+func parseJSONFilter(raw json.RawMessage) (expr *Expr, errs ValidationErrors, err error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	if raw[0] == '"' {
+		var s string
+		if err := decoder.Decode(&s); err != nil {
+			return nil, nil, fmt.Errorf("query: invalid filter: %w", err)
+		}
+		if strings.TrimSpace(s) == "" {
+			return nil, nil, nil
+		}
+		expr, err = ParseFilter(s)
+		return expr, nil, err
+	}
+
+	var node Expr
+	if err := decoder.Decode(&node); err != nil {
+		return nil, nil, fmt.Errorf("query: invalid filter: %w", err)
+	}
+	if errs = normalizeJSONExpr(&node); len(errs) > 0 {
+		return nil, errs, nil
+	}
+	expr, err = ParseFilter(node.String())
+	return expr, nil, err
+}
+
+// filterNodeError 返回过滤表达式结构错误
+func filterNodeError(format string, args ...any) *FieldError {
+	return &FieldError{
+		Param:   "filter",
+		Reason:  ReasonInvalidValue,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// normalizeJSONExpr 校验表达式结构并规范化其中条件的值，返回所有节点的错误
+func normalizeJSONExpr(e *Expr) ValidationErrors {
+	switch e.Kind {
+	case ExprCond:
+		if e.Condition == nil {
+			return ValidationErrors{filterNodeError("filter condition node has no condition")}
+		}
+		if err := normalizeJSONCondition(e.Condition, "filter"); err != nil {
+			return ValidationErrors{err}
+		}
+		return nil
+	case ExprNot:
+		if len(e.Children) != 1 {
+			return ValidationErrors{filterNodeError("filter not node needs exactly one child")}
+		}
+	case ExprAnd, ExprOr:
+		if len(e.Children) == 0 {
+			return ValidationErrors{filterNodeError("filter %s node has no children", e.Kind)}
+		}
+	default:
+		return ValidationErrors{filterNodeError("unknown filter node %q", e.Kind)}
+	}
+	var errs ValidationErrors
+	for _, child := range e.Children {
+		if child == nil {
+			errs = append(errs, filterNodeError("filter %s node has a null child", e.Kind))
+			continue
+		}
+		errs = append(errs, normalizeJSONExpr(child)...)
+	}
+	return errs
+}
+
+// normalizeJSONCondition 校验条件并将JSON的值转换为URL参数的形式，param为条件所在的JSON字段名
+func normalizeJSONCondition(c *Condition, param string) *FieldError {
+	if c.Key == "" {
+		return &FieldError{
+			Param:   param,
+			Op:      c.Op,
+			Reason:  ReasonInvalidValue,
+			Message: "condition key is empty",
+		}
+	}
+	if c.Op == "" {
+		c.Op = Equal
+	}
+	if !slices.Contains(suffixOperators, c.Op) {
+		return &FieldError{
+			Param:   param,
+			Field:   c.Key,
+			Op:      c.Op,
+			Reason:  ReasonOperatorNotAllowed,
+			Message: fmt.Sprintf("unknown operator %q for %q", c.Op, c.Key),
+		}
+	}
+	if c.Value == nil {
+		return nil
+	}
+	value, err := jsonConditionValue(c.Op, c.Value)
+	if err != nil {
+		return &FieldError{
+			Param:   param,
+			Field:   c.Key,
+			Op:      c.Op,
+			Reason:  ReasonInvalidValue,
+			Message: fmt.Sprintf("condition %q: %v", c.Key, err),
+		}
+	}
+	c.Value = value
+	return nil
+}
+
+// jsonConditionValue 将JSON值转换为与URL参数解析结果相同的形式
+// 数组直接转换为[]string或两个元素的Range，不经过逗号连接；标量文本按parseConditionValue解析
+func jsonConditionValue(op Operator, value any) (any, error) {
+	switch v := value.(type) {
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			text, err := jsonScalarText(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, text)
+		}
+		switch {
+		case op == In || op == NotIn || op == All:
+			return items, nil
+		case op == Between && len(items) == 2:
+			return jsonRange(items[0], items[1]), nil
+		}
+		return nil, fmt.Errorf("operator %s does not accept an array value", op)
+	case map[string]any:
+		if op != Between {
+			return nil, fmt.Errorf("operator %s does not accept an object value", op)
+		}
+		lower, err := jsonScalarText(v["min"])
+		if err != nil {
+			return nil, err
+		}
+		upper, err := jsonScalarText(v["max"])
+		if err != nil {
+			return nil, err
+		}
+		return jsonRange(lower, upper), nil
+	default:
+		text, err := jsonScalarText(value)
+		if err != nil {
+			return nil, err
+		}
+		return parseConditionValue(op, text), nil
+	}
+}
+
+// jsonRange 创建between的范围，与URL参数一致，空字符串表示该端不限制
+func jsonRange(lower, upper string) Range {
+	r := Range{}
+	if lower != "" {
+		r.Min = lower
+	}
+	if upper != "" {
+		r.Max = upper
+	}
+	return r
+}
+
+// jsonScalarText 将JSON标量转换为文本，null为空字符串
+func jsonScalarText(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", value)
+	}
+}
+
+// Encode 序列化为规范的URL查询参数，ParseQueryRequest解析结果与qr相同
+// 默认的page与page_size省略；条件按顺序输出为key_op=value，相等条件省略_eq；
+// in、nin、all的值以逗号连接，列表元素中的逗号无法表示
+func (qr *QueryRequest) Encode() string {
+	params := make([]string, 0, len(qr.Condition)+5)
+	if qr.Page > 1 {
+		params = append(params, "page="+strconv.Itoa(qr.Page))
+	}
+	if qr.PageSize > 0 && qr.PageSize != defaultPageSize {
+		params = append(params, "page_size="+strconv.Itoa(qr.PageSize))
+	}
+
+	for _, c := range qr.Condition {
+		param := queryEscape(conditionParamName(c))
+		if c.Value != nil {
+			param += "=" + queryEscape(conditionValueText(c.Value))
+		}
+		params = append(params, param)
+	}
+
+	if len(qr.Order) > 0 {
+		orders := make([]string, 0, len(qr.Order))
+		for _, order := range qr.Order {
+			orders = append(orders, order.Key+"."+string(order.Direction))
+		}
+		params = append(params, "sort="+queryEscape(strings.Join(orders, ",")))
+	}
+	if qr.Filter != nil {
+		params = append(params, "filter="+queryEscape(qr.Filter.String()))
+	}
+	if qr.Cursor != "" {
+		params = append(params, "cursor="+queryEscape(qr.Cursor))
+	}
+	if len(qr.Fields) > 0 {
+		params = append(params, "fields="+queryEscape(strings.Join(qr.Fields, ",")))
+	}
+	if len(qr.Expand) > 0 {
+		params = append(params, "expand="+queryEscape(strings.Join(qr.Expand, ",")))
+	}
+	if len(qr.GroupBy) > 0 {
+		params = append(params, "group_by="+queryEscape(strings.Join(qr.GroupBy, ",")))
+	}
+	if len(qr.Agg) > 0 {
+		aggs := make([]string, 0, len(qr.Agg))
+		for _, agg := range qr.Agg {
+			aggs = append(aggs, agg.String())
+		}
+		params = append(params, "agg="+queryEscape(strings.Join(aggs, ",")))
+	}
+	if qr.TimeZone != "" {
+		params = append(params, "tz="+queryEscape(qr.TimeZone))
+	}
+	return strings.Join(params, "&")
+}
+
+// conditionParamName 返回条件的参数名
+// 相等条件在字段名会被解析为其他操作符或与保留参数同名时使用_eq后缀
+func conditionParamName(c Condition) string {
+	if c.Op == Equal {
+		if parsed := parseCondition(c.Key); parsed.Key == c.Key && !slices.Contains(reservedParams, c.Key) {
+			return c.Key
+		}
+	}
+	return c.Key + "_" + string(c.Op)
+}
+
+// conditionValueText 返回条件的值在URL参数中的文本
+func conditionValueText(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case Range:
+		var lower, upper string
+		if v.Min != nil {
+			lower = conditionValueText(v.Min)
+		}
+		if v.Max != nil {
+			upper = conditionValueText(v.Max)
+		}
+		return lower + "," + upper
+	case []string:
+		return strings.Join(v, ",")
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, conditionValueText(item))
+		}
+		return strings.Join(items, ",")
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case primitive.ObjectID:
+		return v.Hex()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// queryEscape 转义URL参数，逗号保持原样便于阅读
+func queryEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "%2C", ",")
+}
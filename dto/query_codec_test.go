@@ -0,0 +1,427 @@
+package dto
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name     string
+		qr       *QueryRequest
+		expected string
+	}{
+		{
+			name:     "defaults are omitted",
+			qr:       &QueryRequest{Page: 1, PageSize: 10},
+			expected: "",
+		},
+		{
+			name: "conditions, sort, filter and cursor",
+			qr: &QueryRequest{
+				Page:     2,
+				PageSize: 20,
+				Condition: []Condition{
+					{Key: "name", Op: Equal, Value: "a&b c"},
+					{Key: "age", Op: In, Value: []string{"18", "20"}},
+					{Key: "price", Op: Between, Value: Range{Max: "9.5"}},
+					{Key: "deleted", Op: Exists, Value: false},
+					{Key: "city", Op: Or, Value: "NY"},
+				},
+				Order:    []Order{{Key: "age", Direction: Descending}, {Key: "owner.name", Direction: Ascending}},
+				Filter:   Cond("status", Equal, "it's"),
+				Cursor:   "abc_-",
+				Fields:   []string{"name", "address.city"},
+				Expand:   []string{"owner.team"},
+				GroupBy:  []string{"city"},
+				Agg:      []Aggregation{{Func: AggCount}, {Func: AggSum, Field: "amount"}},
+				TimeZone: "Asia/Shanghai",
+			},
+			expected: "page=2&page_size=20&name=a%26b+c&age_in=18,20&price_between=,9.5&deleted_exists=false&city_or=NY" +
+				"&sort=age.desc,owner.name.asc&filter=status+eq+%27it%27%27s%27&cursor=abc_-" +
+				"&fields=name,address.city&expand=owner.team&group_by=city&agg=count,sum%28amount%29&tz=Asia%2FShanghai",
+		},
+		{
+			name: "equal on ambiguous keys keeps the suffix",
+			qr: &QueryRequest{Condition: []Condition{
+				{Key: "size_in", Op: Equal, Value: "x"},
+				{Key: "page", Op: Equal, Value: "1"},
+				{Key: "flag", Op: Equal},
+			}},
+			expected: "size_in_eq=x&page_eq=1&flag",
+		},
+		{
+			name: "tz field and time zone",
+			qr: &QueryRequest{
+				Condition: []Condition{{Key: "tz", Op: Equal, Value: "utc"}},
+				TimeZone:  "Asia/Shanghai",
+			},
+			expected: "tz_eq=utc&tz=Asia%2FShanghai",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.qr.Encode(); got != tt.expected {
+				t.Errorf("Encode() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseTimeZoneField(t *testing.T) {
+	qr, err := ParseQueryRequest("tz_eq=utc&tz=Asia%2FShanghai")
+	if err != nil {
+		t.Fatalf("ParseQueryRequest() error = %v", err)
+	}
+	if want := []Condition{{Key: "tz", Op: Equal, Value: "utc"}}; !reflect.DeepEqual(qr.Condition, want) {
+		t.Errorf("Condition = %+v, want %+v", qr.Condition, want)
+	}
+	if qr.TimeZone != "Asia/Shanghai" {
+		t.Errorf("TimeZone = %q, want Asia/Shanghai", qr.TimeZone)
+	}
+}
+
+func TestParseQueryRequestJSON(t *testing.T) {
+	data := `{
+		"page": 2,
+		"condition": [
+			{"key": "age", "op": "gte", "value": 18},
+			{"key": "status", "op": "in", "value": ["active", "new"]},
+			{"key": "price", "op": "between", "value": {"min": 1.5}},
+			{"key": "deleted", "op": "exists", "value": false},
+			{"key": "name", "value": "bob"},
+			{"key": "city", "op": "in", "value": ["Paris, TX", "Rome"]},
+			{"key": "age", "op": "between", "value": [18, null]}
+		],
+		"filter": {"kind": "or", "children": [
+			{"kind": "cond", "condition": {"key": "a", "op": "eq", "value": 1}},
+			{"kind": "or", "children": [{"kind": "cond", "condition": {"key": "b", "op": "nin", "value": [1, 2]}}]}
+		]},
+		"order": [{"key": "age", "direction": "desc"}]
+	}`
+	expected := &QueryRequest{
+		Page:     2,
+		PageSize: 10,
+		Condition: []Condition{
+			{Key: "age", Op: GreaterOrEqual, Value: "18"},
+			{Key: "status", Op: In, Value: []string{"active", "new"}},
+			{Key: "price", Op: Between, Value: Range{Min: "1.5"}},
+			{Key: "deleted", Op: Exists, Value: false},
+			{Key: "name", Op: Equal, Value: "bob"},
+			{Key: "city", Op: In, Value: []string{"Paris, TX", "Rome"}},
+			{Key: "age", Op: Between, Value: Range{Min: "18"}},
+		},
+		Filter: OrExpr(Cond("a", Equal, "1"), Cond("b", NotIn, []string{"1", "2"})),
+		Order:  []Order{{Key: "age", Direction: Descending}},
+	}
+
+	qr, err := ParseQueryRequestJSON([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseQueryRequestJSON() error = %v", err)
+	}
+	if !reflect.DeepEqual(qr, expected) {
+		t.Errorf("ParseQueryRequestJSON() = %+v, want %+v", qr, expected)
+	}
+
+	fromString, err := ParseQueryRequestJSON([]byte(`{"filter": "a eq 1 or b nin (1, 2)"}`))
+	if err != nil {
+		t.Fatalf("ParseQueryRequestJSON() error = %v", err)
+	}
+	if !reflect.DeepEqual(fromString.Filter, expected.Filter) {
+		t.Errorf("ParseQueryRequestJSON() filter = %v, want %v", fromString.Filter, expected.Filter)
+	}
+}
+
+func TestParseQueryRequestJSONErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "invalid JSON", data: `{"page":`},
+		{name: "unknown operator", data: `{"condition": [{"key": "a", "op": "approx", "value": 1}]}`},
+		{name: "empty key", data: `{"condition": [{"op": "eq", "value": 1}]}`},
+		{name: "unsupported value", data: `{"condition": [{"key": "a", "value": [[1]]}]}`},
+		{name: "object for in", data: `{"condition": [{"key": "a", "op": "in", "value": {"min": 1}}]}`},
+		{name: "array for eq", data: `{"condition": [{"key": "a", "value": [1, 2]}]}`},
+		{name: "negative page size", data: `{"page_size": -1}`},
+		{name: "invalid direction", data: `{"order": [{"key": "a", "direction": "up"}]}`},
+		{name: "not with two children", data: `{"filter": {"kind": "not", "children": [{"kind": "cond", "condition": {"key": "a", "value": 1}}, {"kind": "cond", "condition": {"key": "b", "value": 1}}]}}`},
+		{name: "unknown node", data: `{"filter": {"kind": "xor"}}`},
+		{name: "filter syntax", data: `{"filter": "a eq"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseQueryRequestJSON([]byte(tt.data)); err == nil {
+				t.Errorf("ParseQueryRequestJSON() error = nil, want error")
+			}
+		})
+	}
+
+	var syntaxErr *SyntaxError
+	if _, err := ParseQueryRequestJSON([]byte(`{"filter": "a eq"}`)); !errors.As(err, &syntaxErr) {
+		t.Errorf("ParseQueryRequestJSON() error = %v, want *SyntaxError", err)
+	}
+}
+
+func TestParseQueryRequestJSONFieldErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []FieldError
+	}{
+		{
+			name: "unknown operator",
+			data: `{"condition": [{"key": "a", "op": "approx", "value": 1}]}`,
+			want: []FieldError{{Param: "condition", Field: "a", Op: "approx", Reason: ReasonOperatorNotAllowed}},
+		},
+		{
+			name: "negative page values",
+			data: `{"page": -1, "page_size": -10}`,
+			want: []FieldError{{Param: "page", Reason: ReasonInvalidValue}, {Param: "page_size", Reason: ReasonInvalidValue}},
+		},
+		{
+			name: "unsupported value",
+			data: `{"condition": [{"key": "a", "value": [[1]]}]}`,
+			want: []FieldError{{Param: "condition", Field: "a", Op: Equal, Reason: ReasonInvalidValue}},
+		},
+		{
+			name: "all errors are returned",
+			data: `{"order": [{"key": "a", "direction": "up"}, {"direction": "asc"}], "agg": [{"field": "b"}]}`,
+			want: []FieldError{
+				{Param: "order", Field: "a", Reason: ReasonInvalidValue},
+				{Param: "order", Reason: ReasonInvalidValue},
+				{Param: "agg", Field: "b", Reason: ReasonAggregateNotAllowed},
+			},
+		},
+		{
+			name: "filter node and condition",
+			data: `{"filter": {"kind": "and", "children": [{"kind": "not"}, {"kind": "cond", "condition": {"key": "a", "op": "approx"}}]}}`,
+			want: []FieldError{
+				{Param: "filter", Reason: ReasonInvalidValue},
+				{Param: "filter", Field: "a", Op: "approx", Reason: ReasonOperatorNotAllowed},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseQueryRequestJSON([]byte(tt.data))
+			var errs ValidationErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("ParseQueryRequestJSON() error = %v, want ValidationErrors", err)
+			}
+			got := make([]FieldError, 0, len(errs))
+			for _, fieldErr := range errs {
+				got = append(got, FieldError{Param: fieldErr.Param, Field: fieldErr.Field, Op: fieldErr.Op, Reason: fieldErr.Reason})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseQueryRequestJSON() errors = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// randomQueryRequest 随机生成的规范形式的查询请求，用于往返属性测试
+type randomQueryRequest struct {
+	qr *QueryRequest
+}
+
+// Generate 实现quick.Generator
+func (randomQueryRequest) Generate(r *rand.Rand, _ int) reflect.Value {
+	qr := &QueryRequest{
+		Page:      1 + r.Intn(5),
+		PageSize:  []int{10, 1 + r.Intn(50)}[r.Intn(2)],
+		Condition: make([]Condition, 0),
+		Order:     make([]Order, 0),
+	}
+	for range r.Intn(5) {
+		op := suffixOperators[r.Intn(len(suffixOperators))]
+		qr.Condition = append(qr.Condition, Condition{Key: randomKey(r), Op: op, Value: randomValue(r, op, true)})
+	}
+	for range r.Intn(3) {
+		direction := []OrderDirection{Ascending, Descending}[r.Intn(2)]
+		qr.Order = append(qr.Order, Order{Key: randomKey(r), Direction: direction})
+	}
+	if r.Intn(2) == 0 {
+		// 通过解析得到规范形式的表达式
+		filter, err := ParseFilter(randomExpr(r, 3).String())
+		if err != nil {
+			panic(err)
+		}
+		qr.Filter = filter
+	}
+	if r.Intn(2) == 0 {
+		qr.Cursor = randomText(r, "abcXYZ019-_", 1+r.Intn(20))
+	}
+	for range r.Intn(3) {
+		qr.Fields = append(qr.Fields, randomKey(r))
+	}
+	for range r.Intn(3) {
+		qr.Expand = append(qr.Expand, randomKey(r))
+	}
+	for range r.Intn(3) {
+		qr.GroupBy = append(qr.GroupBy, randomKey(r))
+	}
+	for range r.Intn(3) {
+		agg := Aggregation{Func: []AggFunc{AggCount, AggSum, AggAvg, AggMin, AggMax}[r.Intn(5)]}
+		if agg.Func != AggCount {
+			agg.Field = randomKey(r)
+		}
+		qr.Agg = append(qr.Agg, agg)
+	}
+	if r.Intn(2) == 0 {
+		qr.TimeZone = []string{"UTC", "Asia/Shanghai", "America/New_York"}[r.Intn(3)]
+	}
+	return reflect.ValueOf(randomQueryRequest{qr: qr})
+}
+
+// randomKey 随机字段名，包含下划线、点以及与操作符后缀相同的结尾
+func randomKey(r *rand.Rand) string {
+	key := randomText(r, "abcxyz", 1+r.Intn(4)) + randomText(r, "abc_.", r.Intn(4))
+	if r.Intn(4) == 0 {
+		key += "_" + string(suffixOperators[r.Intn(len(suffixOperators))])
+	}
+	if r.Intn(8) == 0 {
+		key = reservedParams[r.Intn(len(reservedParams))]
+	}
+	return key
+}
+
+// randomExpr 随机过滤表达式，字段名不与关键字冲突
+func randomExpr(r *rand.Rand, depth int) *Expr {
+	if depth == 0 || r.Intn(3) == 0 {
+		op := filterOperatorList[r.Intn(len(filterOperatorList))]
+		key := []string{"name", "age", "owner.name", "is_null"}[r.Intn(4)]
+		return Cond(key, op, randomValue(r, op, false))
+	}
+	switch r.Intn(3) {
+	case 0:
+		return Not(randomExpr(r, depth-1))
+	case 1:
+		return And(randomExpr(r, depth-1), randomExpr(r, depth-1))
+	default:
+		return OrExpr(randomExpr(r, depth-1), randomExpr(r, depth-1))
+	}
+}
+
+// filterOperatorList filter参数支持的操作符
+var filterOperatorList = func() []Operator {
+	ops := make([]Operator, 0, len(filterOperators))
+	for _, op := range suffixOperators {
+		if _, ok := filterOperators[string(op)]; ok {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}()
+
+// randomValue 按操作符随机生成规范形式的值
+func randomValue(r *rand.Rand, op Operator, allowNil bool) any {
+	const chars = "aZ09 &=%+',.é中?#/_-"
+	const listChars = "aZ09 &=%+',.é中?#/_-"
+	switch op {
+	case In, NotIn, All:
+		values := make([]string, 1+r.Intn(3))
+		for i := range values {
+			values[i] = randomText(r, listChars, r.Intn(6))
+		}
+		return values
+	case Between:
+		bound := func() any {
+			if r.Intn(3) == 0 {
+				return nil
+			}
+			return randomText(r, listChars, 1+r.Intn(6))
+		}
+		return Range{Min: bound(), Max: bound()}
+	case Exists, IsNull:
+		return r.Intn(2) == 0
+	default:
+		if allowNil && r.Intn(10) == 0 {
+			return nil
+		}
+		return randomText(r, chars, r.Intn(8))
+	}
+}
+
+// randomText 从chars中随机选取n个字符
+func randomText(r *rand.Rand, chars string, n int) string {
+	runes := []rune(chars)
+	var sb strings.Builder
+	for range n {
+		sb.WriteRune(runes[r.Intn(len(runes))])
+	}
+	return sb.String()
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	roundTrip := func(x randomQueryRequest) bool {
+		if !urlEncodable(x.qr) {
+			return true
+		}
+		encoded := x.qr.Encode()
+		parsed, err := ParseQueryRequest(encoded)
+		if err != nil {
+			t.Logf("ParseQueryRequest(%q) error = %v", encoded, err)
+			return false
+		}
+		if !reflect.DeepEqual(parsed, x.qr) {
+			t.Logf("ParseQueryRequest(%q) = %+v, want %+v", encoded, parsed, x.qr)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
+
+// urlEncodable 判断条件的列表元素与between端点是否都不含逗号，URL参数中无法表示这些逗号
+func urlEncodable(qr *QueryRequest) bool {
+	hasComma := func(value any) bool {
+		s, _ := value.(string)
+		return strings.Contains(s, ",")
+	}
+	for _, c := range qr.Condition {
+		switch v := c.Value.(type) {
+		case []string:
+			for _, item := range v {
+				if hasComma(item) {
+					return false
+				}
+			}
+		case Range:
+			if hasComma(v.Min) || hasComma(v.Max) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	roundTrip := func(x randomQueryRequest) bool {
+		data, err := json.Marshal(x.qr)
+		if err != nil {
+			t.Logf("json.Marshal() error = %v", err)
+			return false
+		}
+		parsed, err := ParseQueryRequestJSON(data)
+		if err != nil {
+			t.Logf("ParseQueryRequestJSON(%s) error = %v", data, err)
+			return false
+		}
+		if !reflect.DeepEqual(parsed, x.qr) {
+			t.Logf("ParseQueryRequestJSON(%s) = %+v, want %+v", data, parsed, x.qr)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
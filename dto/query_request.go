@@ -1,7 +1,10 @@
 package dto
 
 import (
+	"errors"
+	"fmt"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -19,8 +22,24 @@ const (
 	In             Operator = "in"  // IN操作符
 	Or             Operator = "or"  // OR操作符
 	Like           Operator = "like"
+	NotIn          Operator = "nin"        // NOT IN操作符，值为[]string
+	Between        Operator = "between"    // 范围，值为Range，包含边界
+	Exists         Operator = "exists"     // 字段是否存在，值为bool
+	IsNull         Operator = "isnull"     // 字段是否为null，值为bool
+	StartsWith     Operator = "startswith" // 字符串前缀
+	EndsWith       Operator = "endswith"   // 字符串后缀
+	ILike          Operator = "ilike"      // 不区分大小写的like
+	Contains       Operator = "contains"   // 数组字段包含该值
+	All            Operator = "all"        // 数组字段包含所有值，值为[]string
 )
 
+// Range between操作符的取值范围，Min或Max为nil时该端不限制
+// 解析时边界为字符串，Validate后转换为字段类型
+type Range struct {
+	Min any `json:"min"` // 下限
+	Max any `json:"max"` // 上限
+}
+
 // OrderDirection 排序方向
 type OrderDirection string
 
@@ -44,55 +63,132 @@ type Order struct {
 
 // QueryRequest 查询请求
 type QueryRequest struct {
-	Page      int         `json:"page"`      // 页码
-	PageSize  int         `json:"page_size"` // 每页数量
-	Condition []Condition `json:"condition"` // 查询条件
-	Order     []Order     `json:"order"`     // 排序条件
+	Page      int           `json:"page"`               // 页码
+	PageSize  int           `json:"page_size"`          // 每页数量
+	Condition []Condition   `json:"condition"`          // 查询条件
+	Filter    *Expr         `json:"filter,omitempty"`   // filter参数的过滤表达式
+	Order     []Order       `json:"order"`              // 排序条件
+	Cursor    string        `json:"cursor,omitempty"`   // 游标，不为空时从上一页最后一条记录之后开始，忽略Page
+	Fields    []string      `json:"fields,omitempty"`   // 返回的字段，为空时返回全部字段
+	Expand    []string      `json:"expand,omitempty"`   // 展开的关联，owner.team形式表示嵌套展开
+	GroupBy   []string      `json:"group_by,omitempty"` // 分组字段，与Agg任一不为空时为分组聚合请求
+	Agg       []Aggregation `json:"agg,omitempty"`      // 聚合，为空时为count
+	TimeZone  string        `json:"tz,omitempty"`       // 时区，例如Asia/Shanghai，用于解析没有时区的时间与startOfDay，为空时为UTC；名为tz的字段使用tz_eq查询
 }
 
 // NewQueryRequestFromURL 从URL查询参数创建QueryRequest
+// 格式错误的page、page_size、sort与filter参数被忽略，需要返回错误时使用ParseQueryRequest
 func NewQueryRequestFromURL(rawQuery string) *QueryRequest {
+	qr, _ := ParseQueryRequest(rawQuery)
+	return qr
+}
+
+// ParseQueryRequest 从URL查询参数创建QueryRequest
+// page、page_size不是正整数或sort的格式错误时返回ValidationErrors，filter参数有语法错误时返回*SyntaxError，
+// 两者都有时返回errors.Join的结果；返回错误时QueryRequest仍包含其他参数，格式错误的参数使用默认值
+func ParseQueryRequest(rawQuery string) (*QueryRequest, error) {
 	// 解析查询字符串但保留顺序
 	query, _ := url.ParseQuery(rawQuery)
 
 	qr := &QueryRequest{
 		Page:      1,
-		PageSize:  10,
+		PageSize:  defaultPageSize,
 		Condition: make([]Condition, 0),
 		Order:     make([]Order, 0),
 	}
 
+	var errs ValidationErrors
+
 	// 解析页码
-	parsePage(query, qr)
+	if err := parsePage(query, qr); err != nil {
+		errs = append(errs, err)
+	}
 
 	// 解析每页数量
-	parsePageSize(query, qr)
+	if err := parsePageSize(query, qr); err != nil {
+		errs = append(errs, err)
+	}
 
 	// 解析查询条件
 	parseConditions(rawQuery, qr)
 
 	// 解析排序条件
-	parseSort(query, qr)
+	errs = append(errs, parseSort(query, qr)...)
 
-	return qr
+	// 解析游标
+	qr.Cursor = query.Get("cursor")
+
+	// 解析返回字段与展开的关联
+	qr.Fields = parseList(query.Get("fields"))
+	qr.Expand = parseList(query.Get("expand"))
+
+	// 解析分组与聚合
+	qr.GroupBy = parseList(query.Get("group_by"))
+	qr.Agg = parseAggregations(query.Get("agg"))
+
+	// 解析时区
+	qr.TimeZone = query.Get("tz")
+
+	// 解析过滤表达式
+	err := parseFilter(query, qr)
+
+	switch {
+	case len(errs) > 0 && err != nil:
+		return qr, errors.Join(errs, err)
+	case len(errs) > 0:
+		return qr, errs
+	default:
+		return qr, err
+	}
 }
 
-// parsePage 解析页码
-func parsePage(query url.Values, qr *QueryRequest) {
-	if pageStr := query.Get("page"); pageStr != "" {
-		if page, err := strconv.Atoi(pageStr); err == nil && page > 0 {
-			qr.Page = page
-		}
+// parsePage 解析页码，不是正整数时返回错误并保留默认值
+func parsePage(query url.Values, qr *QueryRequest) *FieldError {
+	page, err := parsePositiveInt(query, "page")
+	if page > 0 {
+		qr.Page = page
 	}
+	return err
 }
 
-// parsePageSize 解析每页数量
-func parsePageSize(query url.Values, qr *QueryRequest) {
-	if pageSizeStr := query.Get("page_size"); pageSizeStr != "" {
-		if pageSize, err := strconv.Atoi(pageSizeStr); err == nil && pageSize > 0 {
-			qr.PageSize = pageSize
+// parsePageSize 解析每页数量，不是正整数时返回错误并保留默认值
+func parsePageSize(query url.Values, qr *QueryRequest) *FieldError {
+	pageSize, err := parsePositiveInt(query, "page_size")
+	if pageSize > 0 {
+		qr.PageSize = pageSize
+	}
+	return err
+}
+
+// parsePositiveInt 解析正整数参数，参数为空时返回0
+func parsePositiveInt(query url.Values, param string) (int, *FieldError) {
+	s := query.Get(param)
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, &FieldError{
+			Param:   param,
+			Reason:  ReasonInvalidValue,
+			Message: fmt.Sprintf("%s must be a positive integer, got %q", param, s),
 		}
 	}
+	return n, nil
+}
+
+// parseFilter 解析filter参数
+func parseFilter(query url.Values, qr *QueryRequest) error {
+	filterStr := query.Get("filter")
+	if strings.TrimSpace(filterStr) == "" {
+		return nil
+	}
+	filter, err := ParseFilter(filterStr)
+	if err != nil {
+		return err
+	}
+	qr.Filter = filter
+	return nil
 }
 
 // parseConditions 解析查询条件
@@ -108,10 +204,13 @@ func parseConditions(rawQuery string, qr *QueryRequest) {
 
 		// 分割键和值
 		kv := strings.SplitN(pair, "=", 2)
-		key := kv[0]
+		key, err := url.QueryUnescape(kv[0])
+		if err != nil {
+			key = kv[0]
+		}
 
 		// 跳过特殊参数
-		if key == "page" || key == "page_size" || key == "sort" {
+		if slices.Contains(reservedParams, key) {
 			continue
 		}
 
@@ -124,39 +223,76 @@ func parseConditions(rawQuery string, qr *QueryRequest) {
 					// 解码失败则使用原值
 					value = kv[1]
 				}
-				condition.Value = value
-
-				// 对于IN操作符，需要特殊处理值为数组的情况
-				if condition.Op == In {
-					// 处理逗号分隔的值作为数组
-					if str, ok := condition.Value.(string); ok && strings.Contains(str, ",") {
-						condition.Value = strings.Split(str, ",")
-					} else {
-						// 单个值也转换为数组
-						condition.Value = []string{condition.Value.(string)}
-					}
-				}
+				condition.Value = parseConditionValue(condition.Op, value)
 			}
 			qr.Condition = append(qr.Condition, *condition)
 		}
 	}
 }
 
+// parseConditionValue 按操作符解析条件的值
+func parseConditionValue(op Operator, value string) any {
+	switch op {
+	case In, NotIn, All:
+		// 处理逗号分隔的值作为数组，单个值也转换为数组
+		return strings.Split(value, ",")
+	case Between:
+		// 格式: min,max，任一端为空表示不限制；格式错误时保留原值，由Validate报告
+		bounds := strings.Split(value, ",")
+		if len(bounds) != 2 {
+			return value
+		}
+		r := Range{}
+		if bounds[0] != "" {
+			r.Min = bounds[0]
+		}
+		if bounds[1] != "" {
+			r.Max = bounds[1]
+		}
+		return r
+	case Exists, IsNull:
+		// 布尔值，格式错误时保留原值，由Validate报告
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+// parseList 解析逗号分隔的列表，忽略空项，没有项时返回nil
+func parseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // parseSort 解析排序条件
-func parseSort(query url.Values, qr *QueryRequest) {
+func parseSort(query url.Values, qr *QueryRequest) ValidationErrors {
 	// 解析排序条件
 	// 支持格式: sort=key1.asc,key2.desc
+	var errs ValidationErrors
 	if sortStr := query.Get("sort"); sortStr != "" {
-		qr.Order = parseOrder(sortStr)
+		qr.Order, errs = parseOrder(sortStr)
 	}
+	return errs
+}
+
+// suffixOperators 条件参数支持的操作符后缀，较长的后缀在前，避免被较短的后缀截断
+var suffixOperators = []Operator{
+	StartsWith, EndsWith, Between, Contains, Exists, IsNull, ILike, NotIn, All,
+	GreaterOrEqual, LessOrEqual, Equal, NotEqual, Greater, Less, In, Or, Like,
 }
 
 // parseCondition 解析条件字段
 func parseCondition(key string) *Condition {
 	// 查找操作符
-	operators := []Operator{GreaterOrEqual, LessOrEqual, Equal, NotEqual, Greater, Less, In, Or, Like}
-
-	for _, op := range operators {
+	for _, op := range suffixOperators {
 		suffix := "_" + string(op)
 		if len(key) > len(suffix) && key[len(key)-len(suffix):] == suffix {
 			fieldName := key[:len(key)-len(suffix)]
@@ -174,31 +310,59 @@ func parseCondition(key string) *Condition {
 	}
 }
 
-// parseOrder 解析排序字段
-func parseOrder(sortStr string) []Order {
+// parseOrder 解析排序字段，格式错误的项不加入结果，每项返回一条错误
+func parseOrder(sortStr string) ([]Order, ValidationErrors) {
 	orders := make([]Order, 0)
+	var errs ValidationErrors
 
 	// 支持格式: key1.asc,key2.desc
 	pairs := strings.Split(sortStr, ",")
 	for _, pair := range pairs {
-		parts := strings.Split(pair, ".")
-		if len(parts) == 2 {
-			order := Order{
-				Key: parts[0],
-			}
+		if pair == "" {
+			continue
+		}
 
-			switch parts[1] {
-			case "asc":
-				order.Direction = Ascending
-			case "desc":
-				order.Direction = Descending
-			default:
-				continue // 无效的排序方向
-			}
+		// 方向在最后一个点之后，字段名可以是owner.name形式的路径
+		key, direction, ok := cutLast(pair, ".")
+		if !ok || key == "" {
+			errs = append(errs, &FieldError{
+				Param:   "sort",
+				Field:   pair,
+				Reason:  ReasonInvalidValue,
+				Message: fmt.Sprintf("sort %q must be in the form key.asc or key.desc", pair),
+			})
+			continue
+		}
 
-			orders = append(orders, order)
+		order := Order{
+			Key: key,
 		}
+
+		switch direction {
+		case "asc":
+			order.Direction = Ascending
+		case "desc":
+			order.Direction = Descending
+		default:
+			errs = append(errs, &FieldError{
+				Param:   "sort",
+				Field:   key,
+				Reason:  ReasonInvalidValue,
+				Message: fmt.Sprintf("invalid sort direction %q for %q", direction, key),
+			})
+			continue
+		}
+
+		orders = append(orders, order)
 	}
 
-	return orders
+	return orders, errs
+}
+
+// cutLast 在最后一个sep处切分s
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
 }
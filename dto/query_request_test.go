@@ -165,6 +165,94 @@ func TestNewQueryRequestFromURL(t *testing.T) {
 				Order:    []Order{},
 			},
 		},
+		{
+			name:  "not in condition",
+			query: "status_nin=deleted,banned",
+			expected: &QueryRequest{
+				Page:     1,
+				PageSize: 10,
+				Condition: []Condition{
+					{Key: "status", Value: []string{"deleted", "banned"}, Op: NotIn},
+				},
+				Order: []Order{},
+			},
+		},
+		{
+			name:  "between condition",
+			query: "price_between=10,100",
+			expected: &QueryRequest{
+				Page:     1,
+				PageSize: 10,
+				Condition: []Condition{
+					{Key: "price", Value: Range{Min: "10", Max: "100"}, Op: Between},
+				},
+				Order: []Order{},
+			},
+		},
+		{
+			name:  "between condition with open bound",
+			query: "price_between=,100",
+			expected: &QueryRequest{
+				Page:     1,
+				PageSize: 10,
+				Condition: []Condition{
+					{Key: "price", Value: Range{Max: "100"}, Op: Between},
+				},
+				Order: []Order{},
+			},
+		},
+		{
+			name:  "between condition with invalid range",
+			query: "price_between=10",
+			expected: &QueryRequest{
+				Page:     1,
+				PageSize: 10,
+				Condition: []Condition{
+					{Key: "price", Value: "10", Op: Between},
+				},
+				Order: []Order{},
+			},
+		},
+		{
+			name:  "exists and isnull conditions",
+			query: "deletedAt_exists=false&parent_isnull=true",
+			expected: &QueryRequest{
+				Page:     1,
+				PageSize: 10,
+				Condition: []Condition{
+					{Key: "deletedAt", Value: false, Op: Exists},
+					{Key: "parent", Value: true, Op: IsNull},
+				},
+				Order: []Order{},
+			},
+		},
+		{
+			name:  "string match conditions",
+			query: "name_startswith=Jo&name_endswith=son&email_ilike=GMAIL",
+			expected: &QueryRequest{
+				Page:     1,
+				PageSize: 10,
+				Condition: []Condition{
+					{Key: "name", Value: "Jo", Op: StartsWith},
+					{Key: "name", Value: "son", Op: EndsWith},
+					{Key: "email", Value: "GMAIL", Op: ILike},
+				},
+				Order: []Order{},
+			},
+		},
+		{
+			name:  "array conditions",
+			query: "tags_contains=go&tags_all=go,rust",
+			expected: &QueryRequest{
+				Page:     1,
+				PageSize: 10,
+				Condition: []Condition{
+					{Key: "tags", Value: "go", Op: Contains},
+					{Key: "tags", Value: []string{"go", "rust"}, Op: All},
+				},
+				Order: []Order{},
+			},
+		},
 		{
 			name:  "single ascending order",
 			query: "sort=name.asc",
@@ -313,6 +401,86 @@ func TestParseCondition(t *testing.T) {
 				Op:  Or,
 			},
 		},
+		{
+			name: "parse not in condition",
+			key:  "status_nin",
+			expected: &Condition{
+				Key: "status",
+				Op:  NotIn,
+			},
+		},
+		{
+			name: "parse between condition",
+			key:  "price_between",
+			expected: &Condition{
+				Key: "price",
+				Op:  Between,
+			},
+		},
+		{
+			name: "parse exists condition",
+			key:  "deletedAt_exists",
+			expected: &Condition{
+				Key: "deletedAt",
+				Op:  Exists,
+			},
+		},
+		{
+			name: "parse isnull condition",
+			key:  "parent_isnull",
+			expected: &Condition{
+				Key: "parent",
+				Op:  IsNull,
+			},
+		},
+		{
+			name: "parse startswith condition",
+			key:  "name_startswith",
+			expected: &Condition{
+				Key: "name",
+				Op:  StartsWith,
+			},
+		},
+		{
+			name: "parse endswith condition",
+			key:  "name_endswith",
+			expected: &Condition{
+				Key: "name",
+				Op:  EndsWith,
+			},
+		},
+		{
+			name: "parse ilike condition",
+			key:  "name_ilike",
+			expected: &Condition{
+				Key: "name",
+				Op:  ILike,
+			},
+		},
+		{
+			name: "parse contains condition",
+			key:  "tags_contains",
+			expected: &Condition{
+				Key: "tags",
+				Op:  Contains,
+			},
+		},
+		{
+			name: "parse all condition",
+			key:  "tags_all",
+			expected: &Condition{
+				Key: "tags",
+				Op:  All,
+			},
+		},
+		{
+			name: "parse field name containing an operator",
+			key:  "login_ip_in",
+			expected: &Condition{
+				Key: "login_ip",
+				Op:  In,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -330,9 +498,10 @@ func TestParseCondition(t *testing.T) {
 
 func TestParseOrder(t *testing.T) {
 	tests := []struct {
-		name     string
-		sortStr  string
-		expected []Order
+		name          string
+		sortStr       string
+		expected      []Order
+		invalidFields []string
 	}{
 		{
 			name:     "empty sort string",
@@ -363,26 +532,38 @@ func TestParseOrder(t *testing.T) {
 			},
 		},
 		{
-			name:     "invalid order direction",
-			sortStr:  "name.invalid",
-			expected: []Order{},
+			name:          "invalid order direction",
+			sortStr:       "name.invalid",
+			expected:      []Order{},
+			invalidFields: []string{"name"},
 		},
 		{
 			name:    "mixed valid and invalid orders",
-			sortStr: "name.asc,invalid.direction,age.desc",
+			sortStr: "name.asc,invalid.direction,age,age.desc",
 			expected: []Order{
 				{Key: "name", Direction: Ascending},
 				{Key: "age", Direction: Descending},
 			},
+			invalidFields: []string{"invalid", "age"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := parseOrder(tt.sortStr)
+			got, errs := parseOrder(tt.sortStr)
 			if !reflect.DeepEqual(got, tt.expected) {
 				t.Errorf("parseOrder() = %v, want %v", got, tt.expected)
 			}
+			var invalidFields []string
+			for _, err := range errs {
+				if err.Param != "sort" || err.Reason != ReasonInvalidValue {
+					t.Errorf("parseOrder() error = %+v, want sort invalid_value", err)
+				}
+				invalidFields = append(invalidFields, err.Field)
+			}
+			if !reflect.DeepEqual(invalidFields, tt.invalidFields) {
+				t.Errorf("parseOrder() invalid fields = %v, want %v", invalidFields, tt.invalidFields)
+			}
 		})
 	}
 }
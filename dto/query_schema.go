@@ -0,0 +1,376 @@
+package dto
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FieldType 字段类型
+type FieldType string
+
+const (
+	TypeString   FieldType = "string"   // 字符串
+	TypeInt      FieldType = "int"      // 整数，转换为int64
+	TypeFloat    FieldType = "float"    // 浮点数，转换为float64
+	TypeBool     FieldType = "bool"     // 布尔值
+	TypeTime     FieldType = "time"     // 时间，格式见ParseTime，按FieldSchema.TimeFormat转换为time.Time或Unix时间戳
+	TypeObjectID FieldType = "objectid" // MongoDB ObjectID，转换为primitive.ObjectID
+	TypeEnum     FieldType = "enum"     // 枚举字符串，取值必须在Enum中
+)
+
+// defaultOperators 未指定Operators时各类型允许的操作符
+var defaultOperators = map[FieldType][]Operator{
+	TypeString:   {Equal, NotEqual, In, NotIn, Or, Like, ILike, StartsWith, EndsWith, Exists, IsNull},
+	TypeInt:      {Equal, NotEqual, Greater, Less, GreaterOrEqual, LessOrEqual, In, NotIn, Or, Between, Exists, IsNull},
+	TypeFloat:    {Equal, NotEqual, Greater, Less, GreaterOrEqual, LessOrEqual, In, NotIn, Or, Between, Exists, IsNull},
+	TypeBool:     {Equal, NotEqual, Or, Exists, IsNull},
+	TypeTime:     {Equal, NotEqual, Greater, Less, GreaterOrEqual, LessOrEqual, Or, Between, Exists, IsNull},
+	TypeObjectID: {Equal, NotEqual, In, NotIn, Or, Exists, IsNull},
+	TypeEnum:     {Equal, NotEqual, In, NotIn, Or, Exists, IsNull},
+}
+
+// defaultArrayOperators 未指定Operators时数组字段允许的操作符
+var defaultArrayOperators = []Operator{Contains, All, Exists, IsNull}
+
+// FieldSchema 字段定义
+type FieldSchema struct {
+	Type       FieldType  // 字段类型
+	Operators  []Operator // 允许的操作符，为空时使用该类型的默认操作符
+	Enum       []string   // TypeEnum的可选值
+	Sortable   bool       // 是否允许排序
+	Groupable  bool       // 是否允许分组
+	Array      bool       // 是否为数组字段，Type为元素类型
+	Path       string     // 存储中的字段路径，例如owner._id，为空时与字段名相同
+	TimeFormat TimeFormat // TypeTime在存储中的表示，条件的值转换为该表示
+}
+
+// allowed 判断是否允许操作符
+func (f FieldSchema) allowed(op Operator) bool {
+	operators := f.Operators
+	if len(operators) == 0 {
+		operators = defaultOperators[f.Type]
+		if f.Array {
+			operators = defaultArrayOperators
+		}
+	}
+	return slices.Contains(operators, op)
+}
+
+// QuerySchema 查询定义，声明允许查询的字段及其类型、操作符与排序
+type QuerySchema struct {
+	Fields    map[string]FieldSchema // 字段名到字段定义，同时是fields参数允许返回的字段
+	Relations map[string]*Relation   // 关联名到关联定义，expand参数允许展开的关联
+	Limits    *QueryLimits           // 查询请求的安全限制，为nil时使用DefaultQueryLimits
+}
+
+// Path 返回字段在存储中的路径，未声明Path时返回字段名
+func (s *QuerySchema) Path(key string) string {
+	if field, ok := s.Fields[key]; ok && field.Path != "" {
+		return field.Path
+	}
+	return key
+}
+
+// fieldPath 返回fields参数中字段的存储路径，字段本身或其上级字段在Fields中声明时ok为true
+// 例如声明了address时address.city映射为address的路径加.city
+func (s *QuerySchema) fieldPath(key string) (path string, ok bool) {
+	for parent, rest := key, ""; parent != ""; {
+		if _, declared := s.Fields[parent]; declared {
+			return s.Path(parent) + rest, true
+		}
+		i := strings.LastIndex(parent, ".")
+		if i < 0 {
+			break
+		}
+		parent, rest = parent[:i], parent[i:]+rest
+	}
+	return "", false
+}
+
+// Projection 返回需要从存储读取的字段路径：fields中各字段的路径、排序字段的路径，以及expand第一层关联的LocalField
+// 排序字段用于生成游标
+// 没有fields参数时返回nil，表示读取全部字段
+func (qr *QueryRequest) Projection(schema *QuerySchema) []string {
+	if len(qr.Fields) == 0 {
+		return nil
+	}
+	paths := make([]string, 0, len(qr.Fields)+len(qr.Order)+len(qr.Expand))
+	for _, field := range qr.Fields {
+		if path, ok := schema.fieldPath(field); ok && !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+	for _, order := range qr.Order {
+		if path := schema.Path(order.Key); !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+	for _, expand := range qr.Expand {
+		name, _, _ := strings.Cut(expand, ".")
+		if relation := schema.Relations[name]; relation != nil && !slices.Contains(paths, relation.LocalField) {
+			paths = append(paths, relation.LocalField)
+		}
+	}
+	return paths
+}
+
+// 校验错误原因
+const (
+	ReasonUnknownField        = "unknown_field"         // 字段不允许查询
+	ReasonOperatorNotAllowed  = "operator_not_allowed"  // 字段不允许该操作符
+	ReasonInvalidValue        = "invalid_value"         // 值无法转换为字段类型
+	ReasonSortNotAllowed      = "sort_not_allowed"      // 字段不允许排序
+	ReasonUnknownRelation     = "unknown_relation"      // 关联不允许展开
+	ReasonExpandTooDeep       = "expand_too_deep"       // 展开的层数超过MaxExpandDepth
+	ReasonGroupNotAllowed     = "group_not_allowed"     // 字段不允许分组
+	ReasonAggregateNotAllowed = "aggregate_not_allowed" // 聚合函数未知或不适用于该字段
+	ReasonLimitExceeded       = "limit_exceeded"        // 超出QueryLimits的限制
+)
+
+// FieldError 单个查询参数的校验错误
+type FieldError struct {
+	Param   string   `json:"param"`        // 查询参数名，例如age_gt
+	Field   string   `json:"field"`        // 字段名
+	Op      Operator `json:"op,omitempty"` // 操作符
+	Reason  string   `json:"reason"`       // 错误原因
+	Message string   `json:"message"`      // 错误描述
+}
+
+// Error 实现error接口
+func (e *FieldError) Error() string {
+	return e.Param + ": " + e.Message
+}
+
+// ValidationErrors 查询请求的校验错误，每个出错的参数一条
+type ValidationErrors []*FieldError
+
+// Error 实现error接口
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fieldErr := range e {
+		messages = append(messages, fieldErr.Error())
+	}
+	return "invalid query: " + strings.Join(messages, "; ")
+}
+
+// Response 转换为错误响应，Data为各参数的错误
+func (e ValidationErrors) Response() Response[ValidationErrors] {
+	return Response[ValidationErrors]{
+		Err:  true,
+		Code: http.StatusBadRequest,
+		Msg:  e.Error(),
+		Data: e,
+	}
+}
+
+// Validate 按schema校验查询条件、过滤表达式、排序、游标、返回字段、展开的关联与分组聚合，并将条件的值转换为字段类型
+// 超出schema.Limits的请求返回ReasonLimitExceeded错误
+// IN条件的值转换为[]any，时间按tz参数的时区解析并转换为FieldSchema.TimeFormat；返回的错误为ValidationErrors，没有错误时返回nil
+func (qr *QueryRequest) Validate(schema *QuerySchema) error {
+	errs := validateLimits(schema.limits(), qr)
+	loc, err := qr.Location()
+	if err != nil {
+		errs = append(errs, &FieldError{
+			Param:   "tz",
+			Reason:  ReasonInvalidValue,
+			Message: err.Error(),
+		})
+		loc = time.UTC
+	}
+	// 同一请求中的相对时间以同一时刻计算
+	vc := valueConverter{now: timeNow(), loc: loc}
+	for i := range qr.Condition {
+		if err := validateCondition(schema, &qr.Condition[i], conditionParam(qr.Condition[i]), vc); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, c := range qr.Filter.Conditions() {
+		if err := validateCondition(schema, c, "filter", vc); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if qr.IsAggregate() {
+		// 分组聚合请求的排序与游标由validateAggregate校验
+		errs = append(errs, validateAggregate(schema, qr)...)
+	} else {
+		for _, order := range qr.Order {
+			field, ok := schema.Fields[order.Key]
+			if !ok || !field.Sortable {
+				errs = append(errs, &FieldError{
+					Param:   "sort",
+					Field:   order.Key,
+					Reason:  ReasonSortNotAllowed,
+					Message: fmt.Sprintf("sorting by %q is not allowed", order.Key),
+				})
+			}
+		}
+		if _, err := qr.DecodeCursor(schema); err != nil {
+			errs = append(errs, &FieldError{
+				Param:   "cursor",
+				Reason:  ReasonInvalidValue,
+				Message: err.Error(),
+			})
+		}
+	}
+	for _, field := range qr.Fields {
+		if _, ok := schema.fieldPath(field); !ok {
+			errs = append(errs, &FieldError{
+				Param:   "fields",
+				Field:   field,
+				Reason:  ReasonUnknownField,
+				Message: fmt.Sprintf("field %q is not allowed", field),
+			})
+		}
+	}
+	for _, path := range qr.Expand {
+		if err := validateExpand(schema, path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateCondition 校验单个条件并转换值
+// param 为条件所在的查询参数名
+func validateCondition(schema *QuerySchema, c *Condition, param string, vc valueConverter) *FieldError {
+	fieldErr := func(reason, format string, args ...any) *FieldError {
+		return &FieldError{
+			Param:   param,
+			Field:   c.Key,
+			Op:      c.Op,
+			Reason:  reason,
+			Message: fmt.Sprintf(format, args...),
+		}
+	}
+
+	field, ok := schema.Fields[c.Key]
+	if !ok {
+		return fieldErr(ReasonUnknownField, "field %q is not allowed", c.Key)
+	}
+	if !field.allowed(c.Op) {
+		return fieldErr(ReasonOperatorNotAllowed, "operator %q is not allowed for field %q", c.Op, c.Key)
+	}
+
+	value, err := vc.convertConditionValue(field, c.Op, c.Value)
+	if err != nil {
+		return fieldErr(ReasonInvalidValue, "%v", err)
+	}
+	c.Value = value
+	return nil
+}
+
+// conditionParam 返回条件对应的查询参数名
+func conditionParam(c Condition) string {
+	if c.Op == Equal {
+		return c.Key
+	}
+	return c.Key + "_" + string(c.Op)
+}
+
+// convertConditionValue 按操作符的值形式转换条件值，数组与范围中的每个元素分别转换
+func (vc valueConverter) convertConditionValue(field FieldSchema, op Operator, value any) (any, error) {
+	switch op {
+	case Exists, IsNull:
+		return vc.convertValue(FieldSchema{Type: TypeBool}, value)
+	case Between:
+		r, ok := value.(Range)
+		if !ok {
+			return nil, fmt.Errorf("%q is not a range, expected min,max", fmt.Sprint(value))
+		}
+		if r.Min == nil && r.Max == nil {
+			return nil, fmt.Errorf("range needs at least one bound")
+		}
+		var err error
+		if r.Min != nil {
+			if r.Min, err = vc.convertValue(field, r.Min); err != nil {
+				return nil, err
+			}
+		}
+		if r.Max != nil {
+			if r.Max, err = vc.convertValue(field, r.Max); err != nil {
+				return nil, err
+			}
+		}
+		return r, nil
+	}
+
+	switch v := value.(type) {
+	case []string:
+		values := make([]any, 0, len(v))
+		for _, item := range v {
+			converted, err := vc.convertValue(field, item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, converted)
+		}
+		return values, nil
+	default:
+		return vc.convertValue(field, value)
+	}
+}
+
+// valueConverter 将条件的值转换为字段类型，now与loc用于解析时间
+type valueConverter struct {
+	now time.Time
+	loc *time.Location
+}
+
+// convertValue 将字符串转换为字段类型，已转换过的值保持不变
+func (vc valueConverter) convertValue(field FieldSchema, value any) (any, error) {
+	if t, ok := value.(time.Time); ok && field.Type == TypeTime {
+		return field.TimeFormat.storageValue(t), nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+	switch field.Type {
+	case TypeInt:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", s)
+		}
+		return n, nil
+	case TypeFloat:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", s)
+		}
+		return f, nil
+	case TypeBool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", s)
+		}
+		return b, nil
+	case TypeTime:
+		t, err := ParseTime(s, vc.now, vc.loc)
+		if err != nil {
+			return nil, err
+		}
+		return field.TimeFormat.storageValue(t), nil
+	case TypeObjectID:
+		id, err := primitive.ObjectIDFromHex(s)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an ObjectID", s)
+		}
+		return id, nil
+	case TypeEnum:
+		if !slices.Contains(field.Enum, s) {
+			return nil, fmt.Errorf("%q is not one of %s", s, strings.Join(field.Enum, ", "))
+		}
+		return s, nil
+	default:
+		return s, nil
+	}
+}
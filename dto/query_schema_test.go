@@ -0,0 +1,154 @@
+package dto
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testSchema 测试使用的查询定义
+var testSchema = &QuerySchema{
+	Fields: map[string]FieldSchema{
+		"name":      {Type: TypeString, Sortable: true},
+		"age":       {Type: TypeInt, Sortable: true},
+		"price":     {Type: TypeFloat},
+		"active":    {Type: TypeBool},
+		"createdAt": {Type: TypeTime, Sortable: true},
+		"ownerId":   {Type: TypeObjectID},
+		"status":    {Type: TypeEnum, Enum: []string{"active", "inactive"}},
+		"city":      {Type: TypeString, Operators: []Operator{Equal}},
+		"tags":      {Type: TypeString, Array: true},
+		"scores":    {Type: TypeInt, Array: true},
+	},
+}
+
+func TestValidate(t *testing.T) {
+	ownerId := primitive.NewObjectID()
+
+	tests := []struct {
+		name       string
+		query      string
+		expected   []Condition
+		wantErrors []FieldError
+	}{
+		{
+			name:  "convert values to field types",
+			query: "age_gt=18&price_lte=9.5&active=true&ownerId=" + ownerId.Hex() + "&status_in=active,inactive&name_like=bob",
+			expected: []Condition{
+				{Key: "age", Value: int64(18), Op: Greater},
+				{Key: "price", Value: 9.5, Op: LessOrEqual},
+				{Key: "active", Value: true, Op: Equal},
+				{Key: "ownerId", Value: ownerId, Op: Equal},
+				{Key: "status", Value: []any{"active", "inactive"}, Op: In},
+				{Key: "name", Value: "bob", Op: Like},
+			},
+		},
+		{
+			name:  "convert time values",
+			query: "createdAt_gte=2024-01-02&createdAt_lt=1704240000",
+			expected: []Condition{
+				{Key: "createdAt", Value: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Op: GreaterOrEqual},
+				{Key: "createdAt", Value: time.Unix(1704240000, 0), Op: Less},
+			},
+		},
+		{
+			name:  "convert extended operator values",
+			query: "age_between=18,65&price_between=,9.5&age_nin=1,2&tags_contains=go&scores_all=1,2&active_exists=true&status_isnull=false",
+			expected: []Condition{
+				{Key: "age", Value: Range{Min: int64(18), Max: int64(65)}, Op: Between},
+				{Key: "price", Value: Range{Max: 9.5}, Op: Between},
+				{Key: "age", Value: []any{int64(1), int64(2)}, Op: NotIn},
+				{Key: "tags", Value: "go", Op: Contains},
+				{Key: "scores", Value: []any{int64(1), int64(2)}, Op: All},
+				{Key: "active", Value: true, Op: Exists},
+				{Key: "status", Value: false, Op: IsNull},
+			},
+		},
+		{
+			name:  "invalid extended operator values",
+			query: "age_between=18&price_between=,&age_between=a,2&active_exists=maybe&tags_eq=go&name_contains=x",
+			wantErrors: []FieldError{
+				{Param: "age_between", Field: "age", Op: Between, Reason: ReasonInvalidValue},
+				{Param: "price_between", Field: "price", Op: Between, Reason: ReasonInvalidValue},
+				{Param: "age_between", Field: "age", Op: Between, Reason: ReasonInvalidValue},
+				{Param: "active_exists", Field: "active", Op: Exists, Reason: ReasonInvalidValue},
+				{Param: "tags", Field: "tags", Op: Equal, Reason: ReasonOperatorNotAllowed},
+				{Param: "name_contains", Field: "name", Op: Contains, Reason: ReasonOperatorNotAllowed},
+			},
+		},
+		{
+			name:  "one error per offending parameter",
+			query: "age_gt=abc&password=x&city_ne=NY&status=deleted&active_gt=true&sort=price.asc",
+			wantErrors: []FieldError{
+				{Param: "age_gt", Field: "age", Op: Greater, Reason: ReasonInvalidValue},
+				{Param: "password", Field: "password", Op: Equal, Reason: ReasonUnknownField},
+				{Param: "city_ne", Field: "city", Op: NotEqual, Reason: ReasonOperatorNotAllowed},
+				{Param: "status", Field: "status", Op: Equal, Reason: ReasonInvalidValue},
+				{Param: "active_gt", Field: "active", Op: Greater, Reason: ReasonOperatorNotAllowed},
+				{Param: "sort", Field: "price", Reason: ReasonSortNotAllowed},
+			},
+		},
+		{
+			name:  "invalid element in list",
+			query: "age_in=1,x",
+			wantErrors: []FieldError{
+				{Param: "age_in", Field: "age", Op: In, Reason: ReasonInvalidValue},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qr := NewQueryRequestFromURL(tt.query)
+			err := qr.Validate(testSchema)
+
+			if tt.wantErrors == nil {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				if !reflect.DeepEqual(qr.Condition, tt.expected) {
+					t.Errorf("Condition = %#v, want %#v", qr.Condition, tt.expected)
+				}
+				return
+			}
+
+			var errs ValidationErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("Validate() error = %v, want ValidationErrors", err)
+			}
+			if len(errs) != len(tt.wantErrors) {
+				t.Fatalf("got %d errors, want %d: %v", len(errs), len(tt.wantErrors), errs)
+			}
+			for i, want := range tt.wantErrors {
+				got := *errs[i]
+				got.Message = ""
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("errors[%d] = %+v, want %+v", i, got, want)
+				}
+				if errs[i].Message == "" {
+					t.Errorf("errors[%d] has no message", i)
+				}
+			}
+		})
+	}
+}
+
+func TestValidationErrorsResponse(t *testing.T) {
+	errs := ValidationErrors{
+		{Param: "age_gt", Field: "age", Op: Greater, Reason: ReasonInvalidValue, Message: `"abc" is not an integer`},
+	}
+	resp := errs.Response()
+	if !resp.Err || resp.Code != http.StatusBadRequest {
+		t.Errorf("Response() = %+v, want error with code 400", resp)
+	}
+	if resp.Msg != `invalid query: age_gt: "abc" is not an integer` {
+		t.Errorf("Msg = %q", resp.Msg)
+	}
+	if !reflect.DeepEqual(resp.Data, errs) {
+		t.Errorf("Data = %v, want %v", resp.Data, errs)
+	}
+}
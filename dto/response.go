@@ -11,3 +11,21 @@ type ListResponse[T any] struct {
 	List  []T   `json:"list"`
 	Total int64 `json:"total"`
 }
+
+// CursorListResponse 游标分页的列表响应
+type CursorListResponse[T any] struct {
+	List       []T    `json:"list"`
+	NextCursor string `json:"nextCursor,omitempty"` // 下一页的游标，没有下一页时为空
+	HasMore    bool   `json:"hasMore"`              // 是否还有下一页
+}
+
+// AggregateBucket 分组聚合的一组结果
+type AggregateBucket struct {
+	Key    map[string]any `json:"key"`    // 分组字段名到分组的值，没有group_by时为空
+	Values map[string]any `json:"values"` // 聚合名称到聚合值，名称为count、sum(amount)的形式
+}
+
+// AggregateResponse 分组聚合的响应
+type AggregateResponse struct {
+	Buckets []AggregateBucket `json:"buckets"`
+}
@@ -0,0 +1,160 @@
+package dto
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeFormat 时间字段在存储中的表示
+type TimeFormat string
+
+const (
+	TimeNative      TimeFormat = ""        // time.Time，MongoDB中为Date
+	TimeUnixSeconds TimeFormat = "unix"    // Unix秒，int64
+	TimeUnixMillis  TimeFormat = "unix_ms" // Unix毫秒，int64，例如model.MongoBaseModel的CreatedAt与UpdatedAt
+)
+
+// storageValue 将时间转换为存储中的表示
+func (f TimeFormat) storageValue(t time.Time) any {
+	switch f {
+	case TimeUnixSeconds:
+		return t.Unix()
+	case TimeUnixMillis:
+		return t.UnixMilli()
+	default:
+		return t
+	}
+}
+
+// timeNow 返回当前时间，测试中替换
+var timeNow = time.Now
+
+// unixMillisThreshold 纯数字的时间不小于该值时按Unix毫秒处理，小于时按Unix秒处理
+// 1e11秒约为公元5138年，1e11毫秒约为1973年
+const unixMillisThreshold = 1e11
+
+// minUnixDigits 按Unix时间解析的纯数字的最少位数，2026等较短的数字不是有效时间
+const minUnixDigits = 5
+
+// timeLayouts 支持的时间格式，没有时区的格式按tz参数的时区解析
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// relativeBases 相对时间的起点
+var relativeBases = map[string]func(now time.Time) time.Time{
+	"now": func(now time.Time) time.Time { return now },
+	"startofday": func(now time.Time) time.Time {
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	},
+	"startofmonth": func(now time.Time) time.Time {
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	},
+}
+
+// ParseTime 解析查询条件中的时间
+//
+//	now-7d、now+2h、startOfDay、startOfMonth-1d  相对时间，单位为w、d、h、m、s，起点不区分大小写
+//	2026-01-01、2026-01-01 08:00:00              没有时区的日期与时间，按loc解析
+//	2026-01-01T08:00:00+08:00                    RFC3339，使用其中的时区
+//	1767225600、1767225600000                    Unix秒或毫秒，按数值大小区分，至少5位数字
+//
+// 相对时间以now在loc中的时间计算，startOfDay与startOfMonth为loc中当天或当月的零点；loc为nil时为UTC
+// URL查询中未编码的+会解码为空格，例如now+1d传为now 1d，无法解析时将空格作为+再解析一次
+func ParseTime(s string, now time.Time, loc *time.Location) (time.Time, error) {
+	t, err := parseTime(s, now, loc)
+	if err != nil && strings.Contains(s, " ") {
+		if plus, plusErr := parseTime(strings.ReplaceAll(s, " ", "+"), now, loc); plusErr == nil {
+			return plus, nil
+		}
+	}
+	return t, err
+}
+
+// parseTime 按ParseTime支持的格式解析时间
+func parseTime(s string, now time.Time, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if len(strings.TrimLeft(s, "+-")) < minUnixDigits {
+			return time.Time{}, fmt.Errorf("%q is not a valid time, Unix timestamps need at least %d digits", s, minUnixDigits)
+		}
+		if n >= unixMillisThreshold || n <= -unixMillisThreshold {
+			return time.UnixMilli(n), nil
+		}
+		return time.Unix(n, 0), nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	if t, ok, err := parseRelativeTime(s, now.In(loc)); ok {
+		return t, err
+	}
+	return time.Time{}, fmt.Errorf("%q is not a valid time", s)
+}
+
+// parseRelativeTime 解析相对时间，格式为起点后接若干±N单位，ok为false表示不是相对时间
+func parseRelativeTime(s string, now time.Time) (t time.Time, ok bool, err error) {
+	i := strings.IndexAny(s, "+-")
+	if i < 0 {
+		i = len(s)
+	}
+	base, found := relativeBases[strings.ToLower(s[:i])]
+	if !found {
+		return time.Time{}, false, nil
+	}
+	t = base(now)
+
+	for rest := s[i:]; rest != ""; {
+		sign := 1
+		if rest[0] == '-' {
+			sign = -1
+		}
+		rest = rest[1:]
+		end := strings.IndexAny(rest, "+-")
+		if end < 0 {
+			end = len(rest)
+		}
+		offset := rest[:end]
+		rest = rest[end:]
+
+		if len(offset) < 2 {
+			return time.Time{}, true, fmt.Errorf("%q is not a valid relative time", s)
+		}
+		n, err := strconv.Atoi(offset[:len(offset)-1])
+		if err != nil || n < 0 {
+			return time.Time{}, true, fmt.Errorf("%q is not a valid relative time", s)
+		}
+		n *= sign
+		switch offset[len(offset)-1] {
+		case 'w':
+			t = t.AddDate(0, 0, 7*n)
+		case 'd':
+			t = t.AddDate(0, 0, n)
+		case 'h':
+			t = t.Add(time.Duration(n) * time.Hour)
+		case 'm':
+			t = t.Add(time.Duration(n) * time.Minute)
+		case 's':
+			t = t.Add(time.Duration(n) * time.Second)
+		default:
+			return time.Time{}, true, fmt.Errorf("%q has an unknown time unit, expected w, d, h, m or s", s)
+		}
+	}
+	return t, true, nil
+}
+
+// Location 返回tz参数的时区，为空时为UTC
+func (qr *QueryRequest) Location() (*time.Location, error) {
+	if qr.TimeZone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(qr.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", qr.TimeZone)
+	}
+	return loc, nil
+}
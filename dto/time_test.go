@@ -0,0 +1,132 @@
+package dto
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	// 2026-03-15 02:30 UTC，上海为当天10:30
+	now := time.Date(2026, 3, 15, 2, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    string
+		loc      *time.Location
+		expected time.Time
+		wantErr  bool
+	}{
+		{name: "now", value: "now", expected: now},
+		{name: "days ago", value: "now-7d", expected: now.AddDate(0, 0, -7)},
+		{name: "combined offsets", value: "now+1h-30m", expected: now.Add(30 * time.Minute)},
+		{name: "weeks and seconds", value: "NOW-1w+10s", expected: now.AddDate(0, 0, -7).Add(10 * time.Second)},
+		{name: "start of day in UTC", value: "startOfDay", expected: time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{name: "start of day in time zone", value: "startOfDay", loc: shanghai, expected: time.Date(2026, 3, 15, 0, 0, 0, 0, shanghai)},
+		{name: "start of month with offset", value: "startofmonth-1d", expected: time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC)},
+		{name: "date in UTC", value: "2026-01-01", expected: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "date in time zone", value: "2026-01-01", loc: shanghai, expected: time.Date(2026, 1, 1, 0, 0, 0, 0, shanghai)},
+		{name: "datetime in time zone", value: "2026-01-01 08:00:00", loc: shanghai, expected: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "RFC3339 keeps its offset", value: "2026-01-01T08:00:00+08:00", loc: time.UTC, expected: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "plus decoded as space", value: "now 1h-30m", expected: now.Add(30 * time.Minute)},
+		{name: "offset plus decoded as space", value: "2026-01-01T08:00:00 08:00", expected: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "epoch seconds", value: "1767225600", expected: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "epoch milliseconds", value: "1767225600123", expected: time.Date(2026, 1, 1, 0, 0, 0, 123e6, time.UTC)},
+		{name: "year is not epoch seconds", value: "2026", wantErr: true},
+		{name: "unknown unit", value: "now-7y", wantErr: true},
+		{name: "missing amount", value: "now-d", wantErr: true},
+		{name: "unknown base", value: "yesterday", wantErr: true},
+		{name: "trailing sign", value: "now-", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTime(tt.value, now, tt.loc)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseTime(%q) = %v, want error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTime(%q) error = %v", tt.value, err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("ParseTime(%q) = %v, want %v", tt.value, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidateTimeFormat(t *testing.T) {
+	now := time.Date(2026, 3, 15, 2, 30, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	schema := &QuerySchema{
+		Fields: map[string]FieldSchema{
+			"createdAt": {Type: TypeTime, TimeFormat: TimeUnixMillis},
+			"updatedAt": {Type: TypeTime, TimeFormat: TimeUnixSeconds},
+			"deletedAt": {Type: TypeTime},
+		},
+	}
+	qr, err := ParseQueryRequest("createdAt_gte=now-7d&updatedAt_between=2026-01-01,startOfDay&deletedAt_lt=2026-01-01&tz=Asia/Shanghai")
+	if err != nil {
+		t.Fatalf("ParseQueryRequest() error = %v", err)
+	}
+	if err = qr.Validate(schema); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	startOfDay := time.Date(2026, 3, 14, 16, 0, 0, 0, time.UTC) // 上海3月15日零点
+	newYear := time.Date(2025, 12, 31, 16, 0, 0, 0, time.UTC)   // 上海1月1日零点
+	expected := []Condition{
+		{Key: "createdAt", Op: GreaterOrEqual, Value: now.AddDate(0, 0, -7).UnixMilli()},
+		{Key: "updatedAt", Op: Between, Value: Range{Min: newYear.Unix(), Max: startOfDay.Unix()}},
+	}
+	if !reflect.DeepEqual(qr.Condition[:2], expected) {
+		t.Errorf("Condition = %+v, want %+v", qr.Condition[:2], expected)
+	}
+	if got, ok := qr.Condition[2].Value.(time.Time); !ok || !got.Equal(newYear) {
+		t.Errorf("deletedAt value = %v, want %v", qr.Condition[2].Value, newYear)
+	}
+
+	// 已转换为time.Time的值也转换为存储表示
+	qr = &QueryRequest{Condition: []Condition{{Key: "createdAt", Op: Less, Value: now}}}
+	if err = qr.Validate(schema); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if qr.Condition[0].Value != now.UnixMilli() {
+		t.Errorf("createdAt value = %v, want %d", qr.Condition[0].Value, now.UnixMilli())
+	}
+
+	// 未编码的+在URL中解码为空格，编码为%2B时保持不变
+	for _, rawQuery := range []string{
+		"createdAt_gte=now+1d&deletedAt_lt=2026-01-01T08:00:00+08:00",
+		"createdAt_gte=now%2B1d&deletedAt_lt=2026-01-01T08:00:00%2B08:00",
+	} {
+		qr, _ = ParseQueryRequest(rawQuery)
+		if err = qr.Validate(schema); err != nil {
+			t.Fatalf("Validate(%q) error = %v", rawQuery, err)
+		}
+		if qr.Condition[0].Value != now.AddDate(0, 0, 1).UnixMilli() {
+			t.Errorf("%q createdAt value = %v, want %d", rawQuery, qr.Condition[0].Value, now.AddDate(0, 0, 1).UnixMilli())
+		}
+		if got, ok := qr.Condition[1].Value.(time.Time); !ok || !got.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("%q deletedAt value = %v, want 2026-01-01T00:00:00Z", rawQuery, qr.Condition[1].Value)
+		}
+	}
+
+	qr, _ = ParseQueryRequest("createdAt_gte=now-7y&tz=Mars/Olympus")
+	var validationErrs ValidationErrors
+	if err = qr.Validate(schema); !errors.As(err, &validationErrs) || len(validationErrs) != 2 {
+		t.Fatalf("Validate() error = %v, want tz and createdAt errors", err)
+	}
+	if validationErrs[0].Param != "tz" || validationErrs[1].Param != "createdAt_gte" {
+		t.Errorf("Validate() errors = %v, want tz and createdAt_gte", validationErrs)
+	}
+}
@@ -2,6 +2,7 @@ package handle
 
 import "github.com/space-ark-x/infra-common/log"
 
+// Default 记录错误日志，err为nil时忽略
 func Default(err error) {
 	if err != nil {
 		log.Error(map[string]any{
@@ -10,6 +11,8 @@ func Default(err error) {
 	}
 }
 
+// Fatal 记录致命错误日志并终止程序，err为nil时忽略
+// 与log.Fatal相同，终止前执行已注册的致命错误钩子并刷新所有日志
 func Fatal(err error) {
 	if err != nil {
 		log.Fatal(map[string]any{
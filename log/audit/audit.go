@@ -0,0 +1,328 @@
+// Package audit 提供防篡改的审计日志
+//
+// 审计日志写入log目录下独立的只追加文件(log/log_<日期>_audit.log)，
+// 编码与ZapLogger的JSON格式一致。每条记录包含上一行的SHA-256哈希(prev_hash)，
+// 形成哈希链；文件按日期轮转，轮转或关闭时写入以HMAC-SHA256签名的检查点，
+// 使用Verify或infra-audit verify命令可以发现记录被修改、删除或文件被截断。
+//
+// 每次写入检查点时，最后一个检查点的位置以签名的文件头(Head)写入<文件>.head，
+// 文件被截断到更早的检查点时，VerifyFile会发现文件头记录的检查点已不存在。
+// 文件头可以与审计文件一起被替换为旧版本，需要更强的保证时，
+// 使用OnCheckpoint将文件头保存到其他系统，并用Report.CheckHead校验。
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/space-ark-x/infra-common/config"
+	"github.com/space-ark-x/infra-common/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// ModuleName 审计日志的模块名
+	ModuleName = "audit"
+
+	// TypeEntry 审计记录
+	TypeEntry = "entry"
+	// TypeCheckpoint 签名检查点
+	TypeCheckpoint = "checkpoint"
+)
+
+// genesisHash 每个文件第一行的prev_hash
+var genesisHash = strings.Repeat("0", sha256.Size*2)
+
+// ErrNoKey 未配置签名密钥
+var ErrNoKey = errors.New("audit: signing key not configured")
+
+// Logger 审计日志记录器
+type Logger struct {
+	mu       sync.Mutex
+	key      []byte
+	encoder  zapcore.Encoder
+	file     *os.File
+	path     string
+	seq      uint64
+	prevHash string
+	lastType string
+	now      func() time.Time
+
+	onCheckpoint func(Head)
+}
+
+// Head 文件头，记录审计文件最后一个检查点的位置
+type Head struct {
+	File      string `json:"file"`      // 文件名
+	Seq       uint64 `json:"seq"`       // 检查点的序号
+	Hash      string `json:"hash"`      // 检查点所在行的哈希
+	Signature string `json:"signature"` // HMAC-SHA256签名
+}
+
+// HeadPath 返回审计文件对应的文件头路径
+func HeadPath(path string) string {
+	return path + ".head"
+}
+
+// ReadHead 读取文件头
+func ReadHead(path string) (Head, error) {
+	var head Head
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return head, err
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return head, fmt.Errorf("audit: %s: invalid head: %w", path, err)
+	}
+	return head, nil
+}
+
+// New 创建审计日志记录器，key用于签名检查点
+func New(key []byte) (*Logger, error) {
+	if len(key) == 0 {
+		return nil, ErrNoKey
+	}
+	// 审计记录保留logger字段，与业务日志一起查看时可以区分
+	encoderConfig := log.NewEncoderConfig()
+	encoderConfig.NameKey = "logger"
+	return &Logger{
+		key:     key,
+		encoder: zapcore.NewJSONEncoder(encoderConfig),
+		now:     time.Now,
+	}, nil
+}
+
+// NewFromConfig 使用配置项AUDIT_SIGNING_KEY创建审计日志记录器，环境变量优先于配置文件
+// 环境变量未设置且配置文件无法加载时返回错误，不会使用其他来源的密钥
+func NewFromConfig() (*Logger, error) {
+	key, err := config.Lookup("AUDIT_SIGNING_KEY", "")
+	if err != nil {
+		return nil, fmt.Errorf("audit: read signing key: %w", err)
+	}
+	return New([]byte(key))
+}
+
+// OnCheckpoint 设置写入检查点后调用的函数，用于将文件头保存到其他系统
+// fn在持有记录器锁时调用，不应阻塞
+func (l *Logger) OnCheckpoint(fn func(Head)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onCheckpoint = fn
+}
+
+// Log 记录一条审计日志
+// actor 操作者，action 操作，resource 被操作的资源，fields 其他信息
+func (l *Logger) Log(actor, action, resource string, fields map[string]any) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if err := l.rotate(now); err != nil {
+		return err
+	}
+
+	zapFields := []zap.Field{
+		zap.String("actor", actor),
+		zap.String("action", action),
+		zap.String("resource", resource),
+	}
+	if len(fields) > 0 {
+		zapFields = append(zapFields, zap.Any("fields", fields))
+	}
+	return l.write(now, TypeEntry, zapFields...)
+}
+
+// Checkpoint 立即写入签名检查点
+func (l *Logger) Checkpoint() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	return l.checkpoint(l.now())
+}
+
+// Close 写入签名检查点并关闭文件
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.close(l.now())
+}
+
+// rotate 打开当天的审计文件，日期变化时为旧文件写入检查点后关闭
+func (l *Logger) rotate(now time.Time) error {
+	path := log.FileName(ModuleName, now)
+	if l.file != nil && l.path == path {
+		return nil
+	}
+	if err := l.close(now); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("audit: create log directory: %w", err)
+	}
+	seq, prevHash, lastType, err := lastState(path)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("audit: open %s: %w", path, err)
+	}
+	l.file, l.path, l.seq, l.prevHash, l.lastType = file, path, seq, prevHash, lastType
+	return nil
+}
+
+// close 写入检查点并关闭当前文件
+func (l *Logger) close(now time.Time) error {
+	if l.file == nil {
+		return nil
+	}
+	err := l.checkpoint(now)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file = nil
+	return err
+}
+
+// checkpoint 写入签名检查点并更新文件头，最后一行已是检查点时跳过
+func (l *Logger) checkpoint(now time.Time) error {
+	if l.seq == 0 || l.lastType == TypeCheckpoint {
+		return nil
+	}
+	signature := sign(l.key, filepath.Base(l.path), l.seq+1, l.prevHash)
+	if err := l.write(now, TypeCheckpoint, zap.String("signature", signature)); err != nil {
+		return err
+	}
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+
+	head := Head{File: filepath.Base(l.path), Seq: l.seq, Hash: l.prevHash}
+	head.Signature = signHead(l.key, head)
+	if err := writeHead(HeadPath(l.path), head); err != nil {
+		return err
+	}
+	if l.onCheckpoint != nil {
+		l.onCheckpoint(head)
+	}
+	return nil
+}
+
+// writeHead 写入临时文件后重命名，避免留下不完整的文件头
+func writeHead(path string, head Head) error {
+	data, err := json.Marshal(head)
+	if err != nil {
+		return fmt.Errorf("audit: encode head: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("audit: write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("audit: write %s: %w", path, err)
+	}
+	return nil
+}
+
+// write 编码并追加一行记录，更新哈希链
+func (l *Logger) write(now time.Time, recordType string, fields ...zap.Field) error {
+	l.seq++
+	fields = append([]zap.Field{
+		zap.String("audit_type", recordType),
+		zap.Uint64("seq", l.seq),
+		zap.String("prev_hash", l.prevHash),
+	}, fields...)
+
+	buf, err := l.encoder.EncodeEntry(zapcore.Entry{
+		Level:      zapcore.InfoLevel,
+		Time:       now,
+		LoggerName: ModuleName,
+		Message:    recordType,
+	}, fields)
+	if err != nil {
+		l.seq--
+		return fmt.Errorf("audit: encode: %w", err)
+	}
+	defer buf.Free()
+
+	if _, err := l.file.Write(buf.Bytes()); err != nil {
+		l.seq--
+		return fmt.Errorf("audit: write %s: %w", l.path, err)
+	}
+	l.prevHash = hashLine(buf.Bytes())
+	l.lastType = recordType
+	return nil
+}
+
+// record 审计文件中一行记录的校验相关字段
+type record struct {
+	Type      string `json:"audit_type"`
+	Seq       uint64 `json:"seq"`
+	PrevHash  string `json:"prev_hash"`
+	Signature string `json:"signature"`
+}
+
+// lastState 读取已有文件最后一行的序号、哈希与类型，文件不存在时从创世哈希开始
+func lastState(path string) (uint64, string, string, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, genesisHash, "", nil
+	}
+	if err != nil {
+		return 0, "", "", fmt.Errorf("audit: open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var last []byte
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		last = append(last[:0], scanner.Bytes()...)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, "", "", fmt.Errorf("audit: read %s: %w", path, err)
+	}
+	if last == nil {
+		return 0, genesisHash, "", nil
+	}
+
+	var r record
+	if err := json.Unmarshal(last, &r); err != nil {
+		return 0, "", "", fmt.Errorf("audit: %s: last line is not a valid record: %w", path, err)
+	}
+	return r.Seq, hashLine(last), r.Type, nil
+}
+
+// hashLine 计算一行记录(不含换行符)的SHA-256哈希
+func hashLine(line []byte) string {
+	sum := sha256.Sum256([]byte(strings.TrimSuffix(string(line), "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// sign 计算检查点签名，签名内容包含文件名、序号与上一行哈希
+func sign(key []byte, file string, seq uint64, prevHash string) string {
+	mac := hmac.New(sha256.New, key)
+	_, _ = fmt.Fprintf(mac, "%s|%d|%s", file, seq, prevHash)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signHead 计算文件头签名，以head:前缀与检查点签名区分
+func signHead(key []byte, head Head) string {
+	return sign(key, "head:"+head.File, head.Seq, head.Hash)
+}
@@ -0,0 +1,176 @@
+package audit
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/space-ark-x/infra-common/config"
+	"github.com/space-ark-x/infra-common/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testKey = []byte("secret")
+
+// newTestLogger 在临时目录中创建使用固定时钟的审计日志记录器
+func newTestLogger(t *testing.T, now *time.Time) *Logger {
+	t.Chdir(t.TempDir())
+	l, err := New(testKey)
+	require.NoError(t, err)
+	l.now = func() time.Time { return *now }
+	return l
+}
+
+func TestLogAndVerify(t *testing.T) {
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	l := newTestLogger(t, &now)
+
+	require.NoError(t, l.Log("alice", "update", "users/1", map[string]any{"field": "email"}))
+	require.NoError(t, l.Log("bob", "delete", "users/2", nil))
+	require.NoError(t, l.Close())
+
+	path := log.FileName(ModuleName, now)
+	report, err := VerifyFile(path, testKey)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Entries)
+	assert.Equal(t, 1, report.Checkpoints)
+
+	// 重新打开后继续哈希链
+	require.NoError(t, l.Log("carol", "create", "users/3", nil))
+	require.NoError(t, l.Close())
+	report, err = VerifyFile(path, testKey)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Entries)
+	assert.Equal(t, 2, report.Checkpoints)
+
+	_, err = VerifyFile(path, []byte("wrong"))
+	assert.ErrorIs(t, err, ErrBadSignature)
+}
+
+func TestRotation(t *testing.T) {
+	now := time.Date(2026, 1, 2, 23, 59, 0, 0, time.UTC)
+	l := newTestLogger(t, &now)
+
+	require.NoError(t, l.Log("alice", "login", "session", nil))
+	first := log.FileName(ModuleName, now)
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, l.Log("alice", "logout", "session", nil))
+	require.NoError(t, l.Close())
+
+	for _, path := range []string{first, log.FileName(ModuleName, now)} {
+		report, err := VerifyFile(path, testKey)
+		require.NoError(t, err, path)
+		assert.Equal(t, 1, report.Entries)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	l := newTestLogger(t, &now)
+	for _, actor := range []string{"alice", "bob", "carol"} {
+		require.NoError(t, l.Log(actor, "read", "report", nil))
+	}
+	require.NoError(t, l.Close())
+	data, err := os.ReadFile(log.FileName(ModuleName, now))
+	require.NoError(t, err)
+	lines := bytes.SplitAfter(data, []byte("\n"))
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{
+			name: "modified record",
+			data: bytes.Replace(data, []byte("bob"), []byte("eve"), 1),
+			want: ErrModified,
+		},
+		{
+			name: "deleted record",
+			data: bytes.Join([][]byte{lines[0], lines[2], lines[3]}, nil),
+			want: ErrModified,
+		},
+		{
+			name: "truncated checkpoint",
+			data: bytes.Join(lines[:3], nil),
+			want: ErrTruncated,
+		},
+		{
+			name: "partial line",
+			data: data[:len(data)-10],
+			want: ErrTruncated,
+		},
+	}
+
+	name := "log_2026-01-02_audit.log"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Verify(bytes.NewReader(tt.data), name, testKey)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+
+	_, err = Verify(bytes.NewReader(data), name, testKey)
+	assert.NoError(t, err)
+}
+
+func TestVerifyDetectsTruncationToEarlierCheckpoint(t *testing.T) {
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	l := newTestLogger(t, &now)
+	var heads []Head
+	l.OnCheckpoint(func(head Head) { heads = append(heads, head) })
+
+	require.NoError(t, l.Log("alice", "update", "users/1", nil))
+	require.NoError(t, l.Checkpoint())
+	require.NoError(t, l.Log("bob", "delete", "users/2", nil))
+	require.NoError(t, l.Close())
+
+	path := log.FileName(ModuleName, now)
+	report, err := VerifyFile(path, testKey)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), report.Seq)
+	require.Len(t, heads, 2)
+	assert.Equal(t, uint64(4), heads[1].Seq)
+
+	// 截断到第一个检查点后，文件本身仍以有效的检查点结束
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	require.NoError(t, os.WriteFile(path, bytes.Join(lines[:2], nil), 0600))
+	report, err = Verify(bytes.NewReader(bytes.Join(lines[:2], nil)), "log_2026-01-02_audit.log", testKey)
+	require.NoError(t, err)
+
+	_, err = VerifyFile(path, testKey)
+	assert.ErrorIs(t, err, ErrTruncated)
+	// 文件头被替换为旧版本时，保存在其他系统的文件头仍能发现截断
+	assert.ErrorIs(t, report.CheckHead(heads[1], testKey), ErrTruncated)
+	assert.NoError(t, report.CheckHead(heads[0], testKey))
+
+	forged := heads[0]
+	forged.Seq = 1
+	assert.ErrorIs(t, report.CheckHead(forged, testKey), ErrBadSignature)
+
+	require.NoError(t, os.Remove(HeadPath(path)))
+	_, err = VerifyFile(path, testKey)
+	assert.ErrorIs(t, err, ErrTruncated)
+}
+
+func TestNewFromConfig(t *testing.T) {
+	t.Setenv("Env", "")
+	t.Setenv("AUDIT_SIGNING_KEY", "from-env")
+	l, err := NewFromConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("from-env"), l.key)
+
+	// 环境变量未设置时需要配置文件，无法加载时返回错误
+	t.Setenv("AUDIT_SIGNING_KEY", "")
+	_, err = NewFromConfig()
+	assert.ErrorIs(t, err, config.ErrNoEnv)
+
+	t.Setenv("Env", "missing")
+	_, err = NewFromConfig()
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
@@ -0,0 +1,159 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+var (
+	// ErrModified 记录被修改、插入或删除
+	ErrModified = errors.New("audit: record modified")
+	// ErrTruncated 文件被截断或未以检查点结束
+	ErrTruncated = errors.New("audit: file truncated")
+	// ErrBadSignature 检查点签名无效
+	ErrBadSignature = errors.New("audit: invalid checkpoint signature")
+)
+
+// Report 校验结果
+type Report struct {
+	File        string `json:"file"`        // 文件路径
+	Entries     int    `json:"entries"`     // 审计记录数
+	Checkpoints int    `json:"checkpoints"` // 检查点数
+	Seq         uint64 `json:"seq"`         // 最后一个检查点的序号
+
+	// checkpoints 各检查点序号对应的行哈希
+	checkpoints map[uint64]string
+}
+
+// VerifyError 校验失败的位置与原因
+type VerifyError struct {
+	File string // 文件路径
+	Line int    // 行号，从1开始
+	Err  error  // ErrModified、ErrTruncated或ErrBadSignature
+	Msg  string // 详细说明
+}
+
+// Error 实现error接口
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("%s:%d: %v: %s", e.File, e.Line, e.Err, e.Msg)
+}
+
+// Unwrap 返回错误类型，便于errors.Is判断
+func (e *VerifyError) Unwrap() error {
+	return e.Err
+}
+
+// VerifyFile 校验审计文件的哈希链、检查点签名与文件头
+// 文件必须以有效的检查点结束，并包含文件头记录的检查点，否则视为被截断
+func VerifyFile(path string, key []byte) (*Report, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	report, err := Verify(file, filepath.Base(path), key)
+	if err != nil {
+		return report, err
+	}
+	head, err := ReadHead(HeadPath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return report, &VerifyError{File: report.File, Line: int(report.Seq), Err: ErrTruncated, Msg: "head file is missing"}
+	}
+	if err != nil {
+		return report, err
+	}
+	return report, report.CheckHead(head, key)
+}
+
+// CheckHead 校验文件头并检查文件是否包含文件头记录的检查点
+// 文件被截断到更早的检查点时返回ErrTruncated
+func (r *Report) CheckHead(head Head, key []byte) error {
+	fail := func(kind error, format string, args ...any) error {
+		return &VerifyError{File: r.File, Line: int(r.Seq), Err: kind, Msg: fmt.Sprintf(format, args...)}
+	}
+
+	if !hmac.Equal([]byte(signHead(key, head)), []byte(head.Signature)) {
+		return fail(ErrBadSignature, "head at sequence %d", head.Seq)
+	}
+	if head.File != r.File {
+		return fail(ErrModified, "head belongs to %s", head.File)
+	}
+	hash, ok := r.checkpoints[head.Seq]
+	switch {
+	case !ok && head.Seq > r.Seq:
+		return fail(ErrTruncated, "file ends at sequence %d, head is at sequence %d", r.Seq, head.Seq)
+	case !ok || hash != head.Hash:
+		return fail(ErrModified, "checkpoint at sequence %d does not match head", head.Seq)
+	}
+	return nil
+}
+
+// Verify 校验审计记录流，name为签名时使用的文件名
+// 不读取文件头，截断到更早检查点的文件需要再用Report.CheckHead校验
+func Verify(r io.Reader, name string, key []byte) (*Report, error) {
+	report := &Report{File: name, checkpoints: make(map[uint64]string)}
+	fail := func(line int, kind error, format string, args ...any) (*Report, error) {
+		return report, &VerifyError{File: name, Line: line, Err: kind, Msg: fmt.Sprintf(format, args...)}
+	}
+
+	reader := bufio.NewReader(r)
+	prevHash := genesisHash
+	lastType := ""
+	line := 0
+	for {
+		data, err := reader.ReadBytes('\n')
+		if len(data) == 0 && errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return report, err
+		}
+		line++
+		if !bytes.HasSuffix(data, []byte("\n")) {
+			return fail(line, ErrTruncated, "incomplete last line")
+		}
+
+		var rec record
+		if jsonErr := json.Unmarshal(data, &rec); jsonErr != nil {
+			return fail(line, ErrModified, "invalid record: %v", jsonErr)
+		}
+		if rec.Seq != uint64(line) {
+			return fail(line, ErrModified, "sequence %d, want %d", rec.Seq, line)
+		}
+		if rec.PrevHash != prevHash {
+			return fail(line, ErrModified, "hash chain broken, previous line was changed")
+		}
+
+		switch rec.Type {
+		case TypeEntry:
+			report.Entries++
+		case TypeCheckpoint:
+			want := sign(key, name, rec.Seq, rec.PrevHash)
+			if !hmac.Equal([]byte(want), []byte(rec.Signature)) {
+				return fail(line, ErrBadSignature, "checkpoint at sequence %d", rec.Seq)
+			}
+			report.Checkpoints++
+			report.Seq = rec.Seq
+		default:
+			return fail(line, ErrModified, "unknown record type %q", rec.Type)
+		}
+		prevHash = hashLine(data)
+		lastType = rec.Type
+		if rec.Type == TypeCheckpoint {
+			report.checkpoints[rec.Seq] = prevHash
+		}
+	}
+
+	if lastType != TypeCheckpoint {
+		return fail(line, ErrTruncated, "file does not end with a signed checkpoint")
+	}
+	return report, nil
+}
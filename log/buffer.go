@@ -0,0 +1,391 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RequestBufferConfig 请求日志缓冲配置，零值字段使用默认值
+type RequestBufferConfig struct {
+	MaxEntries  int // 单个请求缓冲的最大日志数，超出后丢弃最早的日志并计数，默认1000
+	MaxRequests int // 同时缓冲的最大请求数，超出后新请求的日志直接写入，默认10000
+}
+
+// withDefaults 填充默认值
+func (c RequestBufferConfig) withDefaults() RequestBufferConfig {
+	if c.MaxEntries <= 0 {
+		c.MaxEntries = 1000
+	}
+	if c.MaxRequests <= 0 {
+		c.MaxRequests = 10000
+	}
+	return c
+}
+
+// bufferedEntry 缓冲中的一条日志
+type bufferedEntry struct {
+	core   zapcore.Core
+	ent    zapcore.Entry
+	fields []zapcore.Field
+}
+
+// RequestBuffer 单个请求的日志缓冲
+// 缓冲期间该traceId的Debug与Info日志暂不写入，请求失败时通过Flush写入，成功时通过Discard丢弃；
+// 同一traceId的Error及以上级别日志会先写入已缓冲的日志，之后的日志不再缓冲
+type RequestBuffer struct {
+	key      string
+	traceId  string
+	mu       sync.Mutex
+	entries  []bufferedEntry
+	dropped  int64
+	flushed  bool
+	released bool
+	refs     int
+}
+
+// requestBuffers 所有请求共享的缓冲状态
+type requestBuffers struct {
+	mu      sync.RWMutex
+	config  RequestBufferConfig
+	buffers map[string]*RequestBuffer
+	active  atomic.Int64
+}
+
+// defaultBuffers 包级别的请求缓冲
+var defaultBuffers = &requestBuffers{
+	config:  RequestBufferConfig{}.withDefaults(),
+	buffers: make(map[string]*RequestBuffer),
+}
+
+// SetRequestBuffer 设置请求日志缓冲的容量，只影响之后开始缓冲的请求
+func SetRequestBuffer(config RequestBufferConfig) {
+	loadSettings()
+	defaultBuffers.setConfig(config)
+}
+
+// getRequestBufferFromEnv 从配置读取请求缓冲容量
+// LOG_BUFFER_MAX_ENTRIES 单个请求的最大日志数，LOG_BUFFER_MAX_REQUESTS 最大请求数
+func getRequestBufferFromEnv() RequestBufferConfig {
+	config := RequestBufferConfig{}
+	if n, err := strconv.Atoi(configValue("LOG_BUFFER_MAX_ENTRIES", "0")); err == nil {
+		config.MaxEntries = n
+	}
+	if n, err := strconv.Atoi(configValue("LOG_BUFFER_MAX_REQUESTS", "0")); err == nil {
+		config.MaxRequests = n
+	}
+	return config
+}
+
+// setConfig 替换缓冲配置
+func (b *requestBuffers) setConfig(config RequestBufferConfig) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.config = config.withDefaults()
+}
+
+// StartBuffer 开始缓冲traceId的Debug与Info日志，日志通过traceId或trace_id字段关联请求
+// 只有带有该字段的日志会被缓冲，请求中应使用FromContext或WithTraceId返回的日志记录器；
+// traceId为空或缓冲的请求数已达上限时返回nil，nil缓冲的方法均可安全调用；
+// 同一traceId重复开始时共享缓冲，全部Discard后才丢弃；traceId可能由客户端传入时应使用StartRequestBuffer
+func StartBuffer(traceId string) *RequestBuffer {
+	if traceId == "" {
+		return nil
+	}
+	loadSettings()
+	return defaultBuffers.start(traceId, traceId)
+}
+
+// requestBufferContextKey 上下文中请求缓冲的key
+type requestBufferContextKey struct{}
+
+// requestBufferSeq 生成请求缓冲key的唯一后缀
+var requestBufferSeq atomic.Uint64
+
+// StartRequestBuffer 为单个请求开始缓冲Debug与Info日志，返回携带该缓冲的上下文
+// 缓冲以上下文中的追踪ID加唯一后缀为key，追踪ID相同的并发请求各自缓冲，互不Flush或Discard对方的日志；
+// 只有通过FromContext或Start使用返回的上下文记录的日志会被缓冲；缓冲的请求数已达上限时返回原上下文与nil
+func StartRequestBuffer(ctx context.Context) (context.Context, *RequestBuffer) {
+	loadSettings()
+	traceId := TraceIdFromContext(ctx)
+	key := traceId + "#" + strconv.FormatUint(requestBufferSeq.Add(1), 10)
+	buf := defaultBuffers.start(key, traceId)
+	if buf == nil {
+		return ctx, nil
+	}
+	return context.WithValue(ctx, requestBufferContextKey{}, buf), buf
+}
+
+// requestBufferFromContext 返回上下文中的请求缓冲
+func requestBufferFromContext(ctx context.Context) *RequestBuffer {
+	if ctx == nil {
+		return nil
+	}
+	buf, _ := ctx.Value(requestBufferContextKey{}).(*RequestBuffer)
+	return buf
+}
+
+// withRequestBuffer 返回日志写入上下文中请求缓冲的日志记录器，没有缓冲或不是zap日志记录器时返回logger本身
+func withRequestBuffer(ctx context.Context, logger Logger) Logger {
+	buf := requestBufferFromContext(ctx)
+	if buf == nil {
+		return logger
+	}
+	if z, ok := logger.(*ZapLogger); ok {
+		return wrapZapLogger(z.logger.With(requestBufferField(buf)))
+	}
+	return logger
+}
+
+// requestBufferField 将缓冲绑定到日志记录器的字段，编码时被跳过
+func requestBufferField(buf *RequestBuffer) zapcore.Field {
+	return zapcore.Field{Type: zapcore.SkipType, Interface: buf}
+}
+
+// start 注册或复用key的缓冲
+func (b *requestBuffers) start(key, traceId string) *RequestBuffer {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if buf, ok := b.buffers[key]; ok {
+		buf.mu.Lock()
+		buf.refs++
+		buf.mu.Unlock()
+		return buf
+	}
+	if len(b.buffers) >= b.config.MaxRequests {
+		return nil
+	}
+	buf := &RequestBuffer{key: key, traceId: traceId, refs: 1}
+	b.buffers[key] = buf
+	b.active.Add(1)
+	return buf
+}
+
+// lookup 返回traceId对应的缓冲
+func (b *requestBuffers) lookup(traceId string) *RequestBuffer {
+	if b.active.Load() == 0 {
+		return nil
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.buffers[traceId]
+}
+
+// maxEntries 返回单个请求的最大日志数
+func (b *requestBuffers) maxEntries() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.config.MaxEntries
+}
+
+// release 减少引用计数，返回是否已不再被使用
+func (b *requestBuffers) release(buf *RequestBuffer) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	buf.mu.Lock()
+	buf.refs--
+	last := buf.refs <= 0
+	if last {
+		buf.released = true
+	}
+	buf.mu.Unlock()
+	if last && b.buffers[buf.key] == buf {
+		delete(b.buffers, buf.key)
+		b.active.Add(-1)
+	}
+	return last
+}
+
+// Flush 写入已缓冲的日志并结束缓冲，用于请求失败时
+func (r *RequestBuffer) Flush() {
+	if r == nil {
+		return
+	}
+	r.flush()
+	defaultBuffers.release(r)
+}
+
+// Discard 丢弃已缓冲的日志并结束缓冲，用于请求成功时
+// 已因Error日志或Flush写入过的缓冲不会丢失日志
+func (r *RequestBuffer) Discard() {
+	if r == nil {
+		return
+	}
+	if defaultBuffers.release(r) {
+		r.mu.Lock()
+		r.entries = nil
+		r.dropped = 0
+		r.mu.Unlock()
+	}
+}
+
+// Len 返回当前缓冲的日志数
+func (r *RequestBuffer) Len() int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+// add 缓冲一条日志，已写入过或已结束的缓冲返回false表示应直接写入
+func (r *RequestBuffer) add(entry bufferedEntry, maxEntries int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.flushed || r.released {
+		return false
+	}
+	if len(r.entries) >= maxEntries {
+		// 保留最近的日志，失败前的上下文更有价值
+		copy(r.entries, r.entries[1:])
+		r.entries = r.entries[:len(r.entries)-1]
+		r.dropped++
+	}
+	r.entries = append(r.entries, entry)
+	return true
+}
+
+// flush 按记录顺序写入已缓冲的日志，之后的日志不再缓冲
+func (r *RequestBuffer) flush() {
+	r.mu.Lock()
+	entries, dropped := r.entries, r.dropped
+	r.entries, r.dropped, r.flushed = nil, 0, true
+	r.mu.Unlock()
+
+	if dropped > 0 && len(entries) > 0 {
+		first := entries[0]
+		ent := first.ent
+		ent.Level = zapcore.WarnLevel
+		ent.Message = "dropped " + formatCount(dropped) + " buffered entries"
+		ent.Stack = ""
+		_ = first.core.Write(ent, []zapcore.Field{
+			zap.String("traceId", r.traceId),
+			zap.Int64("dropped", dropped),
+		})
+	}
+	for _, entry := range entries {
+		_ = entry.core.Write(entry.ent, entry.fields)
+	}
+}
+
+// bufferCore 按traceId或绑定的请求缓冲缓冲请求日志的核心
+type bufferCore struct {
+	zapcore.Core
+	buffers *requestBuffers
+	traceId string
+	buf     *RequestBuffer
+}
+
+// newBufferCore 使用包级别的请求缓冲包装核心
+func newBufferCore(core zapcore.Core) zapcore.Core {
+	return &bufferCore{Core: core, buffers: defaultBuffers}
+}
+
+// With 返回附加了上下文字段的核心，上下文中的traceId同样用于关联请求，绑定的请求缓冲优先于traceId
+func (c *bufferCore) With(fields []zapcore.Field) zapcore.Core {
+	traceId := c.traceId
+	if id, ok := traceIdField(fields); ok {
+		traceId = id
+	}
+	buf := c.buf
+	for _, field := range fields {
+		if bound, ok := field.Interface.(*RequestBuffer); ok && field.Type == zapcore.SkipType {
+			buf = bound
+		}
+	}
+	return &bufferCore{Core: c.Core.With(fields), buffers: c.buffers, traceId: traceId, buf: buf}
+}
+
+// Check 级别启用时将自身加入检查结果
+func (c *bufferCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write 缓冲请求的Debug与Info日志，Error及以上级别先写入已缓冲的日志
+func (c *bufferCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf := c.buf
+	if buf == nil {
+		if c.buffers.active.Load() == 0 {
+			return c.Core.Write(ent, fields)
+		}
+		traceId := c.traceId
+		if id, ok := traceIdField(fields); ok {
+			traceId = id
+		}
+		if buf = c.buffers.lookup(traceId); buf == nil {
+			return c.Core.Write(ent, fields)
+		}
+	}
+
+	switch {
+	case ent.Level <= zapcore.InfoLevel:
+		entry := bufferedEntry{core: c.Core, ent: ent, fields: copyFields(fields)}
+		if buf.add(entry, c.buffers.maxEntries()) {
+			return nil
+		}
+	case ent.Level >= zapcore.ErrorLevel:
+		buf.flush()
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// traceIdField 查找traceId或trace_id字符串字段
+func traceIdField(fields []zapcore.Field) (string, bool) {
+	for _, field := range fields {
+		if field.Type != zapcore.StringType {
+			continue
+		}
+		for _, key := range traceIdKeys {
+			if field.Key == key && field.String != "" {
+				return field.String, true
+			}
+		}
+	}
+	return "", false
+}
+
+// copyFields 复制字段引用的map、切片等值，缓冲期间调用方修改这些值不影响之后写入的日志
+func copyFields(fields []zapcore.Field) []zapcore.Field {
+	copied := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		copied[i] = copyField(field)
+	}
+	return copied
+}
+
+// copyField 将引用类型的字段值编码为独立的副本，无法编码时保留原字段
+func copyField(field zapcore.Field) zapcore.Field {
+	switch field.Type {
+	case zapcore.ArrayMarshalerType, zapcore.ObjectMarshalerType:
+		enc := zapcore.NewMapObjectEncoder()
+		field.AddTo(enc)
+		if value, ok := enc.Fields[field.Key]; ok {
+			return zap.Any(field.Key, value)
+		}
+	case zapcore.ReflectType:
+		data, err := json.Marshal(field.Interface)
+		if err != nil {
+			return field
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var value any
+		if dec.Decode(&value) == nil {
+			return zap.Any(field.Key, value)
+		}
+	}
+	return field
+}
@@ -0,0 +1,167 @@
+package log_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/space-ark-x/infra-common/log"
+	"github.com/space-ark-x/infra-common/log/logtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestBuffer(t *testing.T) {
+	tests := []struct {
+		name      string
+		run       func(buf *log.RequestBuffer)
+		wantSteps []int64
+	}{
+		{
+			name: "discard on success",
+			run: func(buf *log.RequestBuffer) {
+				log.Debug(map[string]any{"traceId": "t-1", "step": 1})
+				log.Info(map[string]any{"traceId": "t-1", "step": 2})
+				buf.Discard()
+			},
+		},
+		{
+			name: "flush on failure",
+			run: func(buf *log.RequestBuffer) {
+				log.Debug(map[string]any{"traceId": "t-1", "step": 1})
+				log.Info(map[string]any{"traceId": "t-1", "step": 2})
+				buf.Flush()
+			},
+			wantSteps: []int64{1, 2},
+		},
+		{
+			name: "error log flushes and stops buffering",
+			run: func(buf *log.RequestBuffer) {
+				log.Debug(map[string]any{"traceId": "t-1", "step": 1})
+				log.Error(map[string]any{"traceId": "t-1", "step": 2})
+				log.Info(map[string]any{"traceId": "t-1", "step": 3})
+				buf.Discard()
+			},
+			wantSteps: []int64{1, 2, 3},
+		},
+		{
+			name: "warn is written directly",
+			run: func(buf *log.RequestBuffer) {
+				log.Debug(map[string]any{"traceId": "t-1", "step": 1})
+				log.Warn(map[string]any{"traceId": "t-1", "step": 2})
+				buf.Discard()
+			},
+			wantSteps: []int64{2},
+		},
+		{
+			name: "other requests are not buffered",
+			run: func(buf *log.RequestBuffer) {
+				log.Info(map[string]any{"traceId": "t-2", "step": 1})
+				log.Info(map[string]any{"step": 2})
+				buf.Discard()
+			},
+			wantSteps: []int64{1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := logtest.New(t)
+			buf := log.StartBuffer("t-1")
+			assert.NotNil(t, buf)
+
+			tt.run(buf)
+
+			var steps []int64
+			for _, entry := range rec.Entries() {
+				steps = append(steps, entry.Fields["step"].(int64))
+			}
+			assert.Equal(t, tt.wantSteps, steps)
+		})
+	}
+}
+
+func TestRequestBufferLimits(t *testing.T) {
+	rec := logtest.New(t)
+	log.SetRequestBuffer(log.RequestBufferConfig{MaxEntries: 3, MaxRequests: 1})
+	t.Cleanup(func() { log.SetRequestBuffer(log.RequestBufferConfig{}) })
+
+	buf := log.StartBuffer("t-1")
+	assert.Nil(t, log.StartBuffer("t-2"), "request limit reached")
+	for i := 1; i <= 5; i++ {
+		log.Debug(map[string]any{"traceId": "t-1", "step": i})
+	}
+	assert.Equal(t, 3, buf.Len())
+	buf.Flush()
+
+	entries := rec.Entries()
+	assert.Len(t, entries, 4)
+	assert.Equal(t, "dropped 2 buffered entries", entries[0].Message)
+	assert.Len(t, entries.Field("step", 3), 1)
+	assert.Len(t, entries.Field("step", 1), 0)
+
+	var nilBuf *log.RequestBuffer
+	nilBuf.Flush()
+	nilBuf.Discard()
+	assert.Nil(t, log.StartBuffer(""))
+}
+
+func TestRequestBufferFromContext(t *testing.T) {
+	rec := logtest.New(t)
+	ctx := log.ContextWithTraceId(context.Background(), "t-1")
+	buf := log.StartBuffer("t-1")
+
+	log.FromContext(ctx).Info(map[string]any{"step": 1})
+	log.FromContext(context.Background()).Info(map[string]any{"step": 2})
+	assert.Equal(t, 1, buf.Len(), "带有追踪ID的日志被缓冲")
+
+	tags := []string{"a"}
+	attrs := map[string]any{"k": "v"}
+	log.FromContext(ctx).Debug(map[string]any{"step": 3, "tags": tags, "attrs": attrs})
+	tags[0] = "changed"
+	attrs["k"] = "changed"
+	buf.Flush()
+
+	entries := rec.Entries()
+	assert.Len(t, entries, 3)
+	assert.Equal(t, int64(2), entries[0].Fields["step"])
+	assert.Equal(t, "t-1", entries[1].Fields["traceId"])
+	assert.Equal(t, []any{"a"}, entries[2].Fields["tags"], "缓冲后修改切片不影响日志")
+	assert.Equal(t, map[string]any{"k": "v"}, entries[2].Fields["attrs"], "缓冲后修改map不影响日志")
+}
+
+func TestStartRequestBuffer(t *testing.T) {
+	rec := logtest.New(t)
+	ctx := log.ContextWithTraceId(context.Background(), "t-1")
+	ctx1, buf1 := log.StartRequestBuffer(ctx)
+	ctx2, buf2 := log.StartRequestBuffer(ctx)
+	assert.NotSame(t, buf1, buf2, "追踪ID相同的请求各自缓冲")
+
+	log.FromContext(ctx1).Info(map[string]any{"step": 1})
+	log.FromContext(ctx2).Info(map[string]any{"step": 2})
+	log.Start(ctx2, "op").Finish(nil)
+	log.Info(map[string]any{"traceId": "t-1", "step": 3})
+	assert.Equal(t, 1, buf1.Len())
+	assert.Equal(t, 2, buf2.Len())
+
+	buf2.Discard()
+	buf1.Flush()
+	log.FromContext(ctx2).Info(map[string]any{"step": 4})
+
+	entries := rec.Entries()
+	assert.Len(t, entries, 3)
+	assert.Equal(t, int64(3), entries[0].Fields["step"], "只带traceId字段的日志不进入请求缓冲")
+	assert.Equal(t, int64(1), entries[1].Fields["step"])
+	assert.Equal(t, "t-1", entries[1].Fields["traceId"])
+	assert.Equal(t, int64(4), entries[2].Fields["step"], "缓冲结束后直接写入")
+	assert.NotContains(t, entries[1].Fields, "", "绑定缓冲的字段不输出")
+}
+
+func TestWithTraceId(t *testing.T) {
+	rec := logtest.New(t)
+	logger := log.WithTraceId(log.NewSlogLogger(log.NewSlogHandler(log.GetLogger())), "t-1")
+	buf := log.StartBuffer("t-1")
+
+	logger.Info(map[string]any{"step": 1})
+	assert.Equal(t, 1, buf.Len())
+	buf.Flush()
+	assert.Len(t, rec.Entries().Field("traceId", "t-1"), 1)
+	assert.Same(t, log.GetLogger(), log.WithTraceId(log.GetLogger(), ""))
+}
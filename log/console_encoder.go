@@ -0,0 +1,213 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// FormatJSON 以JSON格式输出日志
+	FormatJSON = "json"
+	// FormatConsole 以便于阅读的彩色文本格式输出日志，仅用于开发环境
+	FormatConsole = "console"
+)
+
+// traceIdKeys 控制台输出时单独展示的追踪ID字段名
+var traceIdKeys = []string{"traceId", "trace_id"}
+
+// levelColors 各日志级别对应的ANSI颜色
+var levelColors = map[zapcore.Level]string{
+	zapcore.DebugLevel:  "\x1b[35m",
+	zapcore.InfoLevel:   "\x1b[34m",
+	zapcore.WarnLevel:   "\x1b[33m",
+	zapcore.ErrorLevel:  "\x1b[31m",
+	zapcore.DPanicLevel: "\x1b[31m",
+	zapcore.PanicLevel:  "\x1b[31m",
+	zapcore.FatalLevel:  "\x1b[31m",
+}
+
+// consoleTimeLayout 控制台输出的时间格式
+const consoleTimeLayout = "2006-01-02 15:04:05.000"
+
+const (
+	colorReset = "\x1b[0m"
+	colorGray  = "\x1b[90m"
+	colorCyan  = "\x1b[36m"
+)
+
+var consoleBufferPool = buffer.NewPool()
+
+// consoleEncoder 开发环境使用的控制台编码器
+// 输出格式: 级别 时间 [模块] [追踪ID] key=value ...
+type consoleEncoder struct {
+	*zapcore.MapObjectEncoder
+	color bool
+}
+
+// newConsoleEncoder 创建控制台编码器
+// color 控制是否输出ANSI颜色
+func newConsoleEncoder(color bool) zapcore.Encoder {
+	return &consoleEncoder{
+		MapObjectEncoder: zapcore.NewMapObjectEncoder(),
+		color:            color,
+	}
+}
+
+// isTerminal 判断文件是否为终端设备
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Clone 复制编码器及其上下文字段
+func (c *consoleEncoder) Clone() zapcore.Encoder {
+	clone := &consoleEncoder{
+		MapObjectEncoder: zapcore.NewMapObjectEncoder(),
+		color:            c.color,
+	}
+	for k, v := range c.Fields {
+		clone.Fields[k] = v
+	}
+	return clone
+}
+
+// EncodeEntry 将日志条目编码为一行控制台文本
+func (c *consoleEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	enc := zapcore.NewMapObjectEncoder()
+	for k, v := range c.Fields {
+		enc.Fields[k] = v
+	}
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+
+	record := ConsoleRecord{
+		Level:   ent.Level,
+		Time:    ent.Time,
+		Module:  ent.LoggerName,
+		Message: ent.Message,
+		Fields:  enc.Fields,
+		Stack:   ent.Stack,
+	}
+	if ent.Caller.Defined {
+		record.Caller = ent.Caller.TrimmedPath()
+	}
+
+	line := consoleBufferPool.Get()
+	line.AppendString(record.Text(c.color))
+	line.AppendByte('\n')
+	return line, nil
+}
+
+// ConsoleRecord 以控制台格式展示的一条日志，infra-log查看日志文件时使用相同的格式
+type ConsoleRecord struct {
+	Level   Level
+	Time    time.Time // 为零值时以空格占位
+	Module  string
+	Message string
+	Fields  map[string]any // 追踪ID字段展示在模块之后，其余字段按key排序
+	Caller  string
+	Stack   string
+}
+
+// Text 返回控制台格式的文本，不含结尾换行
+// 格式: 级别 时间 [模块] [追踪ID] 消息 key=value ... 调用位置
+// color 控制是否输出ANSI颜色
+func (r ConsoleRecord) Text(color bool) string {
+	var sb strings.Builder
+	paint := func(code, s string) {
+		if !color {
+			sb.WriteString(s)
+			return
+		}
+		sb.WriteString(code)
+		sb.WriteString(s)
+		sb.WriteString(colorReset)
+	}
+
+	// 级别
+	paint(levelColors[r.Level], fmt.Sprintf("%-5s", r.Level.CapitalString()))
+
+	// 时间
+	sb.WriteByte(' ')
+	if r.Time.IsZero() {
+		sb.WriteString(strings.Repeat(" ", len(consoleTimeLayout)))
+	} else {
+		paint(colorGray, r.Time.Format(consoleTimeLayout))
+	}
+
+	// 模块
+	sb.WriteByte(' ')
+	paint(colorCyan, fmt.Sprintf("%-12s", "["+r.Module+"]"))
+
+	// 追踪ID
+	traceKey := ""
+	for _, key := range traceIdKeys {
+		if traceId, ok := r.Fields[key]; ok {
+			traceKey = key
+			sb.WriteByte(' ')
+			paint(colorGray, fmt.Sprintf("[%v]", traceId))
+			break
+		}
+	}
+
+	if r.Message != "" {
+		sb.WriteByte(' ')
+		sb.WriteString(r.Message)
+	}
+
+	// 其余字段按key排序输出
+	for _, k := range sortedKeys(r.Fields) {
+		if k == traceKey {
+			continue
+		}
+		sb.WriteByte(' ')
+		paint(colorGray, k+"=")
+		sb.WriteString(formatConsoleValue(r.Fields[k]))
+	}
+
+	if r.Caller != "" {
+		sb.WriteByte(' ')
+		paint(colorGray, r.Caller)
+	}
+	if r.Stack != "" {
+		sb.WriteByte('\n')
+		sb.WriteString(r.Stack)
+	}
+	return sb.String()
+}
+
+// formatConsoleValue 将字段值格式化为控制台文本
+func formatConsoleValue(v any) string {
+	switch value := v.(type) {
+	case string:
+		if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+			return strconv.Quote(value)
+		}
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case nil:
+		return "null"
+	case fmt.Stringer:
+		return formatConsoleValue(value.String())
+	case map[string]any, []any:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprintf("%v", value)
+		}
+		return string(data)
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
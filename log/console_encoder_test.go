@@ -0,0 +1,80 @@
+package log
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestConsoleEncoder(t *testing.T) {
+	enc := newConsoleEncoder(false)
+	enc.AddString("service", "api")
+	clone := enc.Clone()
+	enc.AddString("leaked", "no")
+
+	ent := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       time.Date(2026, 1, 2, 10, 4, 5, 6e6, time.Local),
+		LoggerName: "order",
+		Caller:     zapcore.NewEntryCaller(0, "/src/order/handler.go", 42, true),
+	}
+	buf, err := clone.EncodeEntry(ent, []zapcore.Field{
+		zap.String("traceId", "t-1"),
+		zap.Int("count", 3),
+		zap.String("note", "two words"),
+		zap.Any("user", map[string]any{"id": 7}),
+	})
+	require.NoError(t, err)
+	defer buf.Free()
+
+	assert.Equal(t,
+		`WARN  2026-01-02 10:04:05.006 [order]      [t-1] count=3 note="two words" service=api user={"id":7} order/handler.go:42`+"\n",
+		buf.String())
+}
+
+func TestConsoleEncoderColor(t *testing.T) {
+	buf, err := newConsoleEncoder(true).EncodeEntry(zapcore.Entry{
+		Level:      zapcore.ErrorLevel,
+		Time:       time.Now(),
+		LoggerName: "default",
+		Message:    "failed",
+		Stack:      "goroutine 1",
+	}, nil)
+	require.NoError(t, err)
+	defer buf.Free()
+
+	line := buf.String()
+	assert.True(t, strings.HasPrefix(line, levelColors[zapcore.ErrorLevel]+"ERROR"+colorReset))
+	assert.Contains(t, line, colorCyan+"[default]")
+	assert.True(t, strings.HasSuffix(line, " failed\ngoroutine 1\n"))
+}
+
+func TestConsoleRecordText(t *testing.T) {
+	record := ConsoleRecord{
+		Level:  InfoLevel,
+		Module: "default",
+		Fields: map[string]any{"trace_id": "t-2", "latency": 1.5e6, "empty": "", "missing": nil},
+	}
+	assert.Equal(t, `INFO  `+strings.Repeat(" ", 23)+` [default]    [t-2] empty="" latency=1500000 missing=null`, record.Text(false))
+}
+
+func TestFileOmitsLoggerName(t *testing.T) {
+	t.Chdir(t.TempDir())
+	logger := NewZapLoggerWithConfig("order", false)
+	logger.Info(map[string]any{"orderId": "o-1"})
+	require.NoError(t, Sync())
+
+	data, err := os.ReadFile(FileName("order", time.Now()))
+	require.NoError(t, err)
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(data, &line))
+	assert.Equal(t, "o-1", line["orderId"])
+	assert.NotContains(t, line, "logger", "文件名已包含模块名")
+}
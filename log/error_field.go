@@ -0,0 +1,174 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// maxErrorDepth 展开错误原因链的最大层数
+const maxErrorDepth = 32
+
+// stackError 携带调用栈的错误
+type stackError struct {
+	err error
+	pcs []uintptr
+}
+
+// WithStack 为错误附加当前调用栈，err为nil时返回nil
+// 已携带调用栈的错误原样返回
+func WithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errorStack(err) != "" {
+		return err
+	}
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2, pcs)
+	return &stackError{err: err, pcs: pcs[:n]}
+}
+
+// Error 返回原始错误信息
+func (s *stackError) Error() string {
+	return s.err.Error()
+}
+
+// Unwrap 返回原始错误
+func (s *stackError) Unwrap() error {
+	return s.err
+}
+
+// Stack 返回格式化后的调用栈
+func (s *stackError) Stack() string {
+	var sb strings.Builder
+	frames := runtime.CallersFrames(s.pcs)
+	for {
+		frame, more := frames.Next()
+		sb.WriteString(frame.Function)
+		sb.WriteString("\n\t")
+		sb.WriteString(frame.File)
+		sb.WriteByte(':')
+		sb.WriteString(strconv.Itoa(frame.Line))
+		if !more {
+			break
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// errorStack 获取错误自身携带的调用栈，不检查原因链
+// 支持Stack() string、Stack() []byte以及github.com/pkg/errors风格的StackTrace()
+func errorStack(err error) string {
+	switch e := err.(type) {
+	case interface{ Stack() string }:
+		return e.Stack()
+	case interface{ Stack() []byte }:
+		return string(e.Stack())
+	}
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return ""
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%+v", method.Call(nil)[0].Interface()), "\n")
+}
+
+// hasErrorStack 判断字段中是否有错误携带了调用栈
+func hasErrorStack(in map[string]any) bool {
+	for _, v := range in {
+		err, ok := v.(error)
+		if !ok || isNilError(err) {
+			continue
+		}
+		for _, cause := range errorChain(err) {
+			if errorStack(cause) != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// errorChain 按深度优先顺序展开errors.Unwrap与errors.Join形成的原因链
+// 第一个元素为err本身
+func errorChain(err error) []error {
+	chain := make([]error, 0, 4)
+	var walk func(e error, depth int)
+	walk = func(e error, depth int) {
+		if isNilError(e) || depth > maxErrorDepth || len(chain) > maxErrorDepth {
+			return
+		}
+		chain = append(chain, e)
+		switch u := e.(type) {
+		case interface{ Unwrap() []error }:
+			for _, inner := range u.Unwrap() {
+				walk(inner, depth+1)
+			}
+		default:
+			walk(errors.Unwrap(e), depth+1)
+		}
+	}
+	walk(err, 0)
+	return chain
+}
+
+// isNilError 判断错误是否为nil或nil指针
+// nil指针类型的错误不为nil，调用其方法可能panic，不展开
+func isNilError(err error) bool {
+	if err == nil {
+		return true
+	}
+	v := reflect.ValueOf(err)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
+
+// errorField 将错误转换为结构化字段
+// 输出message、type、stack以及causes，重复的调用栈只输出一次
+func errorField(key string, err error) zap.Field {
+	return zap.Object(key, errorObject{err: err})
+}
+
+// errorObject 错误的结构化编码
+type errorObject struct {
+	err error
+}
+
+// MarshalLogObject 实现zapcore.ObjectMarshaler
+func (e errorObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	chain := errorChain(e.err)
+	seen := make(map[string]bool)
+
+	encodeErrorInfo(enc, chain[0], seen)
+	if len(chain) == 1 {
+		return nil
+	}
+	return enc.AddArray("causes", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		for _, cause := range chain[1:] {
+			err := arr.AppendObject(zapcore.ObjectMarshalerFunc(func(obj zapcore.ObjectEncoder) error {
+				encodeErrorInfo(obj, cause, seen)
+				return nil
+			}))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+// encodeErrorInfo 写入单个错误的信息，已输出过的调用栈会被跳过
+func encodeErrorInfo(enc zapcore.ObjectEncoder, err error, seen map[string]bool) {
+	enc.AddString("message", err.Error())
+	enc.AddString("type", fmt.Sprintf("%T", err))
+	if stack := errorStack(err); stack != "" && !seen[stack] {
+		seen[stack] = true
+		enc.AddString("stack", stack)
+	}
+}
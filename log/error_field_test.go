@@ -0,0 +1,66 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorFields(t *testing.T) {
+	logger, logs := observedLogger(t)
+
+	base := WithStack(errors.New("connection refused"))
+	err := fmt.Errorf("load user: %w", errors.Join(base, fmt.Errorf("retry: %w", base)))
+	logger.Error(map[string]any{"error": err})
+
+	obj := logs.All()[0].ContextMap()["error"].(map[string]any)
+	assert.Equal(t, "load user: connection refused\nretry: connection refused", obj["message"])
+	assert.Equal(t, "*fmt.wrapError", obj["type"])
+
+	causes := obj["causes"].([]any)
+	assert.Len(t, causes, 6)
+	stacks := 0
+	for _, cause := range causes {
+		if _, ok := cause.(map[string]any)["stack"]; ok {
+			stacks++
+		}
+	}
+	assert.Equal(t, 1, stacks, "重复的调用栈只记录一次")
+}
+
+func TestErrorStackCapture(t *testing.T) {
+	logger, logs := observedLogger(t)
+	EnableErrorStack(true)
+	defer EnableErrorStack(false)
+
+	logger.Error(map[string]any{"error": errors.New("plain")})
+	logger.Error(map[string]any{"error": WithStack(errors.New("carried"))})
+
+	entries := logs.All()
+	assert.Contains(t, entries[0].ContextMap(), "stacktrace")
+	assert.NotContains(t, entries[1].ContextMap(), "stacktrace")
+}
+
+type nilPointerErr struct{ msg string }
+
+func (e *nilPointerErr) Error() string { return e.msg }
+
+func TestNilPointerError(t *testing.T) {
+	logger, logs := observedLogger(t)
+	EnableErrorStack(true)
+	defer EnableErrorStack(false)
+
+	var e *nilPointerErr
+	var err error = e
+	assert.NotPanics(t, func() {
+		logger.Error(map[string]any{"err": err, "wrapped": fmt.Errorf("load: %w", err)})
+	})
+
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, "<nil>", fields["err"])
+	wrapped := fields["wrapped"].(map[string]any)
+	assert.Equal(t, "load: <nil>", wrapped["message"])
+	assert.NotContains(t, wrapped, "causes", "nil指针的原因不展开")
+}
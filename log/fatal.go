@@ -0,0 +1,207 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// FatalHook 致命错误日志写入后、程序退出前执行的钩子
+// ctx 在超时后取消，钩子应尽快返回
+type FatalHook func(ctx context.Context)
+
+// ExitBehavior 致命错误处理完成后的退出行为
+// fields 为致命错误日志的字段
+type ExitBehavior func(fields map[string]any)
+
+var (
+	// fatalMu 保护致命错误相关的配置
+	fatalMu sync.Mutex
+	// fatalHooks 已注册的致命错误钩子
+	fatalHooks []*registeredFatalHook
+	// fatalHookTimeout 所有钩子执行的总超时时间
+	fatalHookTimeout = 5 * time.Second
+	// exitBehavior 当前的退出行为，默认以退出码1退出
+	exitBehavior = ExitWithCode(1)
+	// fatalRunning 防止钩子中再次记录致命错误导致重复执行
+	fatalRunning atomic.Bool
+	// fatalFlushCtx 致命错误处理期间刷新使用的上下文，超时后不再发送或重试
+	fatalFlushCtx atomic.Pointer[context.Context]
+
+	// registryMu 保护已创建的日志记录器
+	registryMu sync.Mutex
+	// registry 每个模块最近创建的日志记录器，致命错误时统一刷新
+	// 同一模块重新创建时替换旧的记录器，不再引用的日志文件由GC关闭
+	registry = make(map[string]*zap.Logger)
+)
+
+// ExitWithCode 以指定退出码结束进程
+func ExitWithCode(code int) ExitBehavior {
+	return func(map[string]any) {
+		os.Exit(code)
+	}
+}
+
+// ExitPanic 以panic代替退出，便于上层recover
+func ExitPanic() ExitBehavior {
+	return func(fields map[string]any) {
+		panic(fmt.Sprintf("fatal error: %v", fields))
+	}
+}
+
+// ExitNoop 不退出进程，仅用于测试
+func ExitNoop() ExitBehavior {
+	return func(map[string]any) {}
+}
+
+// registeredFatalHook 已注册的致命错误钩子，以指针区分同一函数的多次注册
+type registeredFatalHook struct {
+	hook FatalHook
+}
+
+// RegisterFatalHook 注册致命错误钩子，按注册顺序执行，返回取消注册的函数
+func RegisterFatalHook(hook FatalHook) (unregister func()) {
+	registered := &registeredFatalHook{hook: hook}
+
+	fatalMu.Lock()
+	defer fatalMu.Unlock()
+	fatalHooks = append(fatalHooks, registered)
+
+	return func() {
+		fatalMu.Lock()
+		defer fatalMu.Unlock()
+		next := make([]*registeredFatalHook, 0, len(fatalHooks))
+		for _, h := range fatalHooks {
+			if h != registered {
+				next = append(next, h)
+			}
+		}
+		fatalHooks = next
+	}
+}
+
+// SetFatalHookTimeout 设置致命错误钩子的总超时时间
+func SetFatalHookTimeout(timeout time.Duration) {
+	fatalMu.Lock()
+	defer fatalMu.Unlock()
+	fatalHookTimeout = timeout
+}
+
+// SetExitBehavior 设置致命错误的退出行为，返回之前的退出行为
+func SetExitBehavior(behavior ExitBehavior) ExitBehavior {
+	fatalMu.Lock()
+	defer fatalMu.Unlock()
+	prev := exitBehavior
+	exitBehavior = behavior
+	return prev
+}
+
+// Sync 刷新所有已创建日志记录器的缓冲以及已注册的钩子
+func Sync() error {
+	registryMu.Lock()
+	loggers := make([]*zap.Logger, 0, len(registry))
+	for _, logger := range registry {
+		loggers = append(loggers, logger)
+	}
+	registryMu.Unlock()
+
+	var firstErr error
+	for _, logger := range loggers {
+		if err := logger.Sync(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if err := syncHooks(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
+// register 记录模块新创建的日志记录器，替换该模块之前的记录器
+func register(moduleName string, logger *zap.Logger) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[moduleName] = logger
+}
+
+// fatalWriteHook 替代zap默认的os.Exit行为
+type fatalWriteHook struct{}
+
+// OnWrite 在致命错误日志写入后执行致命错误处理
+func (fatalWriteHook) OnWrite(_ *zapcore.CheckedEntry, fields []zapcore.Field) {
+	handleFatal(NewEntry(zapcore.Entry{}, fields).Fields)
+}
+
+// handleFatal 依次执行钩子、刷新缓冲并按退出行为处理
+// 钩子与刷新共用超时时间，接收方不可用时不会推迟退出
+func handleFatal(fields map[string]any) {
+	fatalMu.Lock()
+	hooks := make([]FatalHook, 0, len(fatalHooks))
+	for _, h := range fatalHooks {
+		hooks = append(hooks, h.hook)
+	}
+	timeout := fatalHookTimeout
+	behavior := exitBehavior
+	fatalMu.Unlock()
+
+	if fatalRunning.CompareAndSwap(false, true) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		runFatalHooks(ctx, hooks)
+		fatalFlushCtx.Store(&ctx)
+		_ = Sync()
+		fatalFlushCtx.Store(nil)
+		cancel()
+		fatalRunning.Store(false)
+	}
+	behavior(fields)
+}
+
+// fatalFlushing 返回是否正在致命错误处理中刷新
+func fatalFlushing() bool {
+	return fatalFlushCtx.Load() != nil
+}
+
+// flushContext 返回刷新时发送请求使用的上下文，致命错误处理期间带有超时
+func flushContext() context.Context {
+	if ctx := fatalFlushCtx.Load(); ctx != nil {
+		return *ctx
+	}
+	return context.Background()
+}
+
+// runFatalHooks 在ctx超时前执行所有钩子，超时后不再等待
+func runFatalHooks(ctx context.Context, hooks []FatalHook) {
+	if len(hooks) == 0 {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, hook := range hooks {
+			if ctx.Err() != nil {
+				return
+			}
+			runFatalHook(ctx, hook)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// runFatalHook 执行单个钩子，钩子panic不影响后续处理
+func runFatalHook(ctx context.Context, hook FatalHook) {
+	defer func() {
+		_ = recover()
+	}()
+	hook(ctx)
+}
@@ -0,0 +1,108 @@
+package log
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// observedLogger 创建写入内存的日志记录器，致命错误经过fatalWriteHook处理
+func observedLogger(t *testing.T) (*ZapLogger, *observer.ObservedLogs) {
+	t.Helper()
+	core, logs := observer.New(zapcore.DebugLevel)
+	return &ZapLogger{logger: zap.New(core, zap.WithFatalHook(fatalWriteHook{}))}, logs
+}
+
+// resetFatal 测试结束后恢复致命错误钩子、超时时间与退出行为
+func resetFatal(t *testing.T) {
+	t.Helper()
+	fatalMu.Lock()
+	hooks, timeout, behavior := fatalHooks, fatalHookTimeout, exitBehavior
+	fatalMu.Unlock()
+	t.Cleanup(func() {
+		fatalMu.Lock()
+		fatalHooks, fatalHookTimeout, exitBehavior = hooks, timeout, behavior
+		fatalMu.Unlock()
+	})
+}
+
+func TestFatalHooks(t *testing.T) {
+	resetFatal(t)
+	logger, logs := observedLogger(t)
+
+	var calls []string
+	var exitFields map[string]any
+	RegisterFatalHook(func(context.Context) { calls = append(calls, "first") })
+	RegisterFatalHook(func(context.Context) { panic("hook failed") })
+	RegisterFatalHook(func(context.Context) { calls = append(calls, "last") })
+	SetExitBehavior(func(fields map[string]any) { exitFields = fields })
+
+	assert.True(t, logger.Fatal(map[string]any{"reason": "shutdown"}))
+
+	assert.Equal(t, []string{"first", "last"}, calls, "钩子panic后继续执行后续钩子")
+	assert.Equal(t, "shutdown", exitFields["reason"])
+	assert.Equal(t, 1, logs.FilterLevelExact(zapcore.FatalLevel).Len())
+}
+
+func TestUnregisterFatalHook(t *testing.T) {
+	resetFatal(t)
+	logger, _ := observedLogger(t)
+	SetExitBehavior(ExitNoop())
+
+	var calls []string
+	hook := func(context.Context) { calls = append(calls, "hook") }
+	unregister := RegisterFatalHook(hook)
+	RegisterFatalHook(hook)
+	unregister()
+	unregister()
+
+	logger.Fatal(map[string]any{"reason": "unregister"})
+	assert.Equal(t, []string{"hook"}, calls, "只取消对应的一次注册")
+}
+
+func TestFatalHookTimeout(t *testing.T) {
+	resetFatal(t)
+	logger, _ := observedLogger(t)
+
+	hookErr := make(chan error, 1)
+	RegisterFatalHook(func(ctx context.Context) {
+		<-ctx.Done()
+		hookErr <- ctx.Err()
+	})
+	SetFatalHookTimeout(20 * time.Millisecond)
+	SetExitBehavior(ExitNoop())
+
+	start := time.Now()
+	logger.Fatal(map[string]any{"reason": "timeout"})
+	assert.Less(t, time.Since(start), time.Second)
+	assert.ErrorIs(t, <-hookErr, context.DeadlineExceeded)
+}
+
+func TestExitPanic(t *testing.T) {
+	resetFatal(t)
+	logger, _ := observedLogger(t)
+	SetExitBehavior(ExitPanic())
+
+	assert.Panics(t, func() {
+		logger.Fatal(map[string]any{"reason": "panic"})
+	})
+}
+
+func TestRegistryPerModule(t *testing.T) {
+	core, _ := observer.New(zapcore.DebugLevel)
+	for range 100 {
+		NewZapLoggerWithCore("registry-a", core)
+	}
+	latest := NewZapLoggerWithCore("registry-a", core).(*ZapLogger)
+	NewZapLoggerWithCore("registry-b", core)
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	assert.Same(t, latest.logger, registry["registry-a"], "同一模块只保留最近创建的记录器")
+	assert.Contains(t, registry, "registry-b")
+}
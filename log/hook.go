@@ -0,0 +1,163 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Hook 日志钩子，Fire在记录日志的goroutine中同步调用，不应阻塞
+type Hook interface {
+	Fire(entry Entry)
+}
+
+// HookFunc 函数形式的钩子
+type HookFunc func(entry Entry)
+
+// Fire 实现Hook接口
+func (f HookFunc) Fire(entry Entry) {
+	f(entry)
+}
+
+// HookMatcher 钩子的触发条件
+type HookMatcher struct {
+	Levels []Level        // 触发的级别，为空时为Error及以上级别
+	Fields map[string]any // 需要全部相等的字段，为空时不检查
+}
+
+// matchLevel 判断级别是否触发
+func (m HookMatcher) matchLevel(level Level) bool {
+	if len(m.Levels) == 0 {
+		return level >= ErrorLevel
+	}
+	for _, l := range m.Levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// matchFields 判断字段是否全部相等
+func (m HookMatcher) matchFields(fields map[string]any) bool {
+	for k, want := range m.Fields {
+		got, ok := fields[k]
+		if !ok || !FieldEqual(got, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// registeredHook 已注册的钩子
+type registeredHook struct {
+	matcher HookMatcher
+	hook    Hook
+}
+
+var (
+	// hooksMu 保护钩子注册
+	hooksMu sync.Mutex
+	// hooks 已注册钩子的快照，写日志时无锁读取
+	hooks atomic.Pointer[[]*registeredHook]
+)
+
+// RegisterHook 注册日志钩子，返回取消注册的函数
+// 对已创建的日志记录器同样生效；实现了Sync() error的钩子会在Sync与致命错误时被刷新
+func RegisterHook(matcher HookMatcher, hook Hook) (unregister func()) {
+	registered := &registeredHook{matcher: matcher, hook: hook}
+
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	current := loadHooks()
+	next := append(append(make([]*registeredHook, 0, len(current)+1), current...), registered)
+	hooks.Store(&next)
+
+	return func() {
+		hooksMu.Lock()
+		defer hooksMu.Unlock()
+		current := loadHooks()
+		next := make([]*registeredHook, 0, len(current))
+		for _, h := range current {
+			if h != registered {
+				next = append(next, h)
+			}
+		}
+		hooks.Store(&next)
+	}
+}
+
+// loadHooks 返回当前已注册的钩子
+func loadHooks() []*registeredHook {
+	if current := hooks.Load(); current != nil {
+		return *current
+	}
+	return nil
+}
+
+// syncHooks 刷新实现了Sync的钩子
+func syncHooks() error {
+	var firstErr error
+	for _, h := range loadHooks() {
+		if syncer, ok := h.hook.(interface{ Sync() error }); ok {
+			if err := syncer.Sync(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// hookCore 写入前触发钩子的核心，钩子在采样之前触发，能看到所有日志
+type hookCore struct {
+	zapcore.Core
+	context []zapcore.Field
+}
+
+// newHookCore 包装核心以触发已注册的钩子
+func newHookCore(core zapcore.Core) zapcore.Core {
+	return &hookCore{Core: core}
+}
+
+// With 返回附加了上下文字段的核心
+func (c *hookCore) With(fields []zapcore.Field) zapcore.Core {
+	return &hookCore{
+		Core:    c.Core.With(fields),
+		context: append(append([]zapcore.Field(nil), c.context...), fields...),
+	}
+}
+
+// Check 级别启用时将自身加入检查结果
+func (c *hookCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write 触发匹配的钩子后写入被包装的核心
+func (c *hookCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var entry *Entry
+	for _, h := range loadHooks() {
+		if !h.matcher.matchLevel(ent.Level) {
+			continue
+		}
+		if entry == nil {
+			e := NewEntry(ent, append(append([]zapcore.Field(nil), c.context...), fields...))
+			entry = &e
+		}
+		if h.matcher.matchFields(entry.Fields) {
+			fireHook(h.hook, *entry)
+		}
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// fireHook 调用钩子，钩子panic不影响日志写入
+func fireHook(hook Hook, entry Entry) {
+	defer func() {
+		_ = recover()
+	}()
+	hook.Fire(entry)
+}
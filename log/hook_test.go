@@ -0,0 +1,187 @@
+package log_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/space-ark-x/infra-common/log"
+	"github.com/space-ark-x/infra-common/log/logtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterHook(t *testing.T) {
+	logtest.New(t)
+	var fired []log.Entry
+	unregister := log.RegisterHook(log.HookMatcher{
+		Levels: []log.Level{log.WarnLevel},
+		Fields: map[string]any{"code": 42},
+	}, log.HookFunc(func(entry log.Entry) {
+		fired = append(fired, entry)
+	}))
+
+	log.Warn(map[string]any{"code": 42})
+	log.Warn(map[string]any{"code": 7})
+	log.Error(map[string]any{"code": 42})
+	require.Len(t, fired, 1)
+	assert.Equal(t, log.WarnLevel, fired[0].Level)
+
+	unregister()
+	log.Warn(map[string]any{"code": 42})
+	assert.Len(t, fired, 1)
+}
+
+// webhookServer 记录收到的请求，前failures次返回500
+type webhookServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	payloads []log.WebhookPayload
+	requests atomic.Int32
+}
+
+func newWebhookServer(t *testing.T, failures int32) *webhookServer {
+	s := &webhookServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.requests.Add(1) <= failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var payload log.WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.payloads = append(s.payloads, payload)
+		s.mu.Unlock()
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *webhookServer) received() []log.WebhookPayload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]log.WebhookPayload(nil), s.payloads...)
+}
+
+func TestWebhookBatchAndDedupe(t *testing.T) {
+	logtest.New(t)
+	server := newWebhookServer(t, 0)
+	hook := log.NewWebhookHook(log.WebhookConfig{URL: server.URL, FlushInterval: time.Hour})
+	defer hook.Close()
+	defer log.RegisterHook(log.HookMatcher{}, hook)()
+
+	for i := 0; i < 50; i++ {
+		log.Error(map[string]any{"error": errors.New("db down")})
+	}
+	log.Error(map[string]any{"error": errors.New("cache down")})
+	log.Info(map[string]any{"ignored": true})
+	require.NoError(t, log.Sync())
+
+	payloads := server.received()
+	require.Len(t, payloads, 1)
+	alerts := payloads[0].Alerts
+	require.Len(t, alerts, 2)
+	assert.Equal(t, 50, alerts[0].Count)
+	assert.Equal(t, "error", alerts[0].Level)
+	assert.Equal(t, 1, alerts[1].Count)
+}
+
+func TestWebhookDedupeByCallSite(t *testing.T) {
+	logtest.New(t)
+	server := newWebhookServer(t, 0)
+	hook := log.NewWebhookHook(log.WebhookConfig{URL: server.URL, FlushInterval: time.Hour})
+	defer hook.Close()
+	defer log.RegisterHook(log.HookMatcher{}, hook)()
+
+	// 相同错误在不同位置记录时为不同的告警
+	log.Error(map[string]any{"error": errors.New("db down")})
+	log.Error(map[string]any{"error": errors.New("db down")})
+	require.NoError(t, log.Sync())
+
+	payloads := server.received()
+	require.Len(t, payloads, 1)
+	require.Len(t, payloads[0].Alerts, 2)
+	assert.NotEqual(t, payloads[0].Alerts[0].Caller, payloads[0].Alerts[1].Caller)
+}
+
+func TestWebhookRetry(t *testing.T) {
+	logtest.New(t)
+	server := newWebhookServer(t, 2)
+	hook := log.NewWebhookHook(log.WebhookConfig{URL: server.URL, FlushInterval: time.Hour, RetryBackoff: time.Millisecond})
+	defer hook.Close()
+	defer log.RegisterHook(log.HookMatcher{}, hook)()
+
+	log.Error(map[string]any{"error": errors.New("boom")})
+	require.NoError(t, hook.Sync())
+	assert.Equal(t, int32(3), server.requests.Load())
+	assert.Len(t, server.received(), 1)
+}
+
+func TestWebhookFatalDeadline(t *testing.T) {
+	logtest.New(t)
+	server := newWebhookServer(t, 1000)
+	hook := log.NewWebhookHook(log.WebhookConfig{URL: server.URL, FlushInterval: time.Hour, MaxRetries: 10, RetryBackoff: 200 * time.Millisecond})
+	defer hook.Close()
+	defer log.RegisterHook(log.HookMatcher{}, hook)()
+	log.SetFatalHookTimeout(50 * time.Millisecond)
+	defer log.SetFatalHookTimeout(5 * time.Second)
+
+	// 接收方不可用时，刷新与重试不超过致命错误钩子的超时时间
+	start := time.Now()
+	log.Fatal(map[string]any{"reason": "shutdown"})
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(1), server.requests.Load())
+}
+
+func TestWebhookThrottle(t *testing.T) {
+	server := newWebhookServer(t, 0)
+	hook := log.NewWebhookHook(log.WebhookConfig{
+		URL:           server.URL,
+		BatchSize:     1,
+		FlushInterval: 10 * time.Millisecond,
+		MinInterval:   time.Hour,
+	})
+	defer hook.Close()
+
+	for i := 0; i < 5; i++ {
+		hook.Fire(log.Entry{Level: log.ErrorLevel, Message: string(rune('a' + i))})
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// 最小间隔内只允许发送一次
+	payloads := server.received()
+	require.Len(t, payloads, 1)
+	assert.Len(t, payloads[0].Alerts, 1)
+
+	// Sync同样受最小间隔限制，Close发送剩余的告警
+	require.NoError(t, hook.Sync())
+	assert.Len(t, server.received(), 1)
+	require.NoError(t, hook.Close())
+	assert.Len(t, server.received(), 5)
+}
+
+func TestWebhookMaxPending(t *testing.T) {
+	server := newWebhookServer(t, 0)
+	hook := log.NewWebhookHook(log.WebhookConfig{URL: server.URL, MaxPending: 3, FlushInterval: time.Hour})
+	defer hook.Close()
+
+	for i := 0; i < 5; i++ {
+		hook.Fire(log.Entry{Level: log.ErrorLevel, Message: string(rune('a' + i))})
+		hook.Fire(log.Entry{Level: log.ErrorLevel, Message: "a"})
+	}
+	require.NoError(t, hook.Sync())
+
+	payloads := server.received()
+	require.Len(t, payloads, 1)
+	require.Len(t, payloads[0].Alerts, 3)
+	assert.Equal(t, 6, payloads[0].Alerts[0].Count)
+	assert.Equal(t, int64(2), payloads[0].Dropped)
+}
@@ -0,0 +1,191 @@
+// Package irislog 将iris框架日志与访问日志接入log包
+//
+// iris的启动信息、路由错误与恢复的panic等通过golog输出，不经过log包。
+// 使用Install或app.Logger().Install(NewAdapter(nil))后这些日志写入log目录，
+// 并带有log包统一添加的pid等字段；NewAccessLog创建的访问日志中间件同样写入log包。
+package irislog
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kataras/golog"
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/middleware/accesslog"
+	"github.com/space-ark-x/infra-common/log"
+)
+
+const (
+	// ModuleName iris框架日志的默认模块名
+	ModuleName = "iris"
+	// AccessModuleName 访问日志的默认模块名
+	AccessModuleName = "access"
+)
+
+// Adapter 将golog日志写入log.Logger的适配器
+// 实现了golog.ExternalLogger，可直接用于app.Logger().Install；
+// Handle保留golog字段并处理Fatal级别，推荐通过Install函数使用
+type Adapter struct {
+	logger log.Logger
+}
+
+// NewAdapter 创建golog适配器，logger为nil时使用模块名为iris的日志记录器
+func NewAdapter(logger log.Logger) *Adapter {
+	if logger == nil {
+		logger = log.NewZapLoggerWithModule(ModuleName)
+	}
+	return &Adapter{logger: logger}
+}
+
+// Install 将iris应用的框架日志接入log包，返回使用的适配器
+func Install(app *iris.Application, logger log.Logger) *Adapter {
+	adapter := NewAdapter(logger)
+	app.Logger().Handle(adapter.Handle)
+	return adapter
+}
+
+// Handle 实现golog.Handler，按级别将日志及其字段写入log.Logger
+// golog的Fatal级别会先执行log包的致命错误处理，golog随后仍会退出进程
+func (a *Adapter) Handle(l *golog.Log) bool {
+	in := make(map[string]any, len(l.Fields)+1)
+	for k, v := range l.Fields {
+		in[k] = v
+	}
+	in["msg"] = l.Message
+	a.write(l.Level, in)
+	return true
+}
+
+// write 按golog级别写入日志，无级别的Print输出记录为信息级别
+func (a *Adapter) write(level golog.Level, in map[string]any) {
+	switch level {
+	case golog.FatalLevel:
+		a.logger.Fatal(in)
+	case golog.ErrorLevel:
+		a.logger.Error(in)
+	case golog.WarnLevel:
+		a.logger.Warn(in)
+	case golog.DebugLevel:
+		a.logger.Debug(in)
+	default:
+		a.logger.Info(in)
+	}
+}
+
+// Print 实现golog.ExternalLogger，记录信息级别日志
+func (a *Adapter) Print(v ...any) {
+	a.write(golog.InfoLevel, message(fmt.Sprint(v...)))
+}
+
+// Println 实现golog.ExternalLogger，记录信息级别日志
+func (a *Adapter) Println(v ...any) {
+	a.write(golog.InfoLevel, message(fmt.Sprint(v...)))
+}
+
+// Error 实现golog.ExternalLogger，记录错误级别日志
+func (a *Adapter) Error(v ...any) {
+	a.write(golog.ErrorLevel, message(fmt.Sprint(v...)))
+}
+
+// Warn 实现golog.ExternalLogger，记录警告级别日志
+func (a *Adapter) Warn(v ...any) {
+	a.write(golog.WarnLevel, message(fmt.Sprint(v...)))
+}
+
+// Info 实现golog.ExternalLogger，记录信息级别日志
+func (a *Adapter) Info(v ...any) {
+	a.write(golog.InfoLevel, message(fmt.Sprint(v...)))
+}
+
+// Debug 实现golog.ExternalLogger，记录调试级别日志
+func (a *Adapter) Debug(v ...any) {
+	a.write(golog.DebugLevel, message(fmt.Sprint(v...)))
+}
+
+// message 创建只包含消息的日志字段
+func message(msg string) map[string]any {
+	return map[string]any{"msg": msg}
+}
+
+// AccessLogFormatter 将iris访问日志写入log.Logger的格式化器
+// 状态码5xx记录为错误级别，4xx为警告级别，其他为信息级别
+type AccessLogFormatter struct {
+	logger log.Logger
+}
+
+// NewAccessLogFormatter 创建访问日志格式化器，logger为nil时使用模块名为access的日志记录器
+func NewAccessLogFormatter(logger log.Logger) *AccessLogFormatter {
+	if logger == nil {
+		logger = log.NewZapLoggerWithModule(AccessModuleName)
+	}
+	return &AccessLogFormatter{logger: logger}
+}
+
+// NewAccessLog 创建写入log包的访问日志中间件，使用app.UseRouter(ac.Handler)注册
+func NewAccessLog(logger log.Logger) *accesslog.AccessLog {
+	ac := accesslog.New(io.Discard)
+	ac.SetFormatter(NewAccessLogFormatter(logger))
+	return ac
+}
+
+// SetOutput 实现accesslog.Formatter，输出由log包决定，忽略访问日志的输出
+func (f *AccessLogFormatter) SetOutput(io.Writer) {}
+
+// Format 实现accesslog.Formatter，将访问记录写入log.Logger
+func (f *AccessLogFormatter) Format(l *accesslog.Log) (bool, error) {
+	in := map[string]any{
+		"msg":        "access",
+		"method":     l.Method,
+		"path":       l.Path,
+		"code":       l.Code,
+		"latency_ms": float64(l.Latency) / float64(time.Millisecond),
+	}
+	if l.IP != "" {
+		in["ip"] = l.IP
+	}
+	if len(l.Query) > 0 {
+		query := make(map[string]any, len(l.Query))
+		for _, q := range l.Query {
+			query[q.Key] = q.Value
+		}
+		in["query"] = query
+	}
+	if len(l.PathParams) > 0 {
+		params := make(map[string]any, len(l.PathParams))
+		for _, p := range l.PathParams {
+			params[p.Key] = p.ValueRaw
+		}
+		in["params"] = params
+	}
+	for _, field := range l.Fields {
+		in[field.Key] = field.ValueRaw
+	}
+	if l.Request != "" {
+		in["request"] = l.Request
+	}
+	if l.Response != "" {
+		in["response"] = l.Response
+	}
+	if l.BytesReceived > 0 {
+		in["bytes_received"] = l.BytesReceived
+	}
+	if l.BytesSent > 0 {
+		in["bytes_sent"] = l.BytesSent
+	}
+	if l.Ctx != nil {
+		if traceId := l.Ctx.Values().GetString("traceId"); traceId != "" {
+			in["traceId"] = traceId
+		}
+	}
+
+	switch {
+	case l.Code >= 500:
+		f.logger.Error(in)
+	case l.Code >= 400:
+		f.logger.Warn(in)
+	default:
+		f.logger.Info(in)
+	}
+	return true, nil
+}
@@ -0,0 +1,89 @@
+package irislog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kataras/golog"
+	"github.com/kataras/iris/v12"
+	"github.com/space-ark-x/infra-common/log"
+	"github.com/space-ark-x/infra-common/log/irislog"
+	"github.com/space-ark-x/infra-common/log/logtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstall(t *testing.T) {
+	tests := []struct {
+		name  string
+		print func(l *golog.Logger)
+		level log.Level
+	}{
+		{"error", func(l *golog.Logger) { l.Error("route failed") }, log.ErrorLevel},
+		{"warn", func(l *golog.Logger) { l.Warn("route failed") }, log.WarnLevel},
+		{"info", func(l *golog.Logger) { l.Info("route failed") }, log.InfoLevel},
+		{"debug", func(l *golog.Logger) { l.Debug("route failed") }, log.DebugLevel},
+		{"print", func(l *golog.Logger) { l.Print("route failed") }, log.InfoLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := logtest.New(t)
+			app := iris.New()
+			app.Logger().SetLevel("debug")
+			irislog.Install(app, nil)
+
+			tt.print(app.Logger())
+
+			entry := rec.AssertLogged(t, tt.level, map[string]any{"msg": "route failed"})
+			assert.Equal(t, irislog.ModuleName, entry.Module)
+			assert.Contains(t, entry.Fields, "pid")
+		})
+	}
+}
+
+func TestInstallExternalLogger(t *testing.T) {
+	rec := logtest.New(t)
+	app := iris.New()
+	app.Logger().Install(irislog.NewAdapter(nil))
+
+	app.Logger().Warn("slow start")
+
+	rec.AssertLogged(t, log.WarnLevel, map[string]any{"msg": "slow start"})
+}
+
+func TestAccessLog(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		code  int
+		level log.Level
+	}{
+		{"ok", "/users/42?active=true", http.StatusOK, log.InfoLevel},
+		{"client error", "/users/42", http.StatusNotFound, log.WarnLevel},
+		{"server error", "/users/42", http.StatusBadGateway, log.ErrorLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := logtest.New(t)
+			app := iris.New()
+			app.UseRouter(irislog.NewAccessLog(nil).Handler)
+			app.Get("/users/{id}", func(ctx iris.Context) {
+				ctx.Values().Set("traceId", "t-1")
+				ctx.StatusCode(tt.code)
+			})
+			assert.NoError(t, app.Build())
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			app.ServeHTTP(httptest.NewRecorder(), req)
+
+			entry := rec.AssertLogged(t, tt.level, map[string]any{
+				"method":  http.MethodGet,
+				"path":    "/users/42",
+				"code":    tt.code,
+				"traceId": "t-1",
+			})
+			assert.Equal(t, irislog.AccessModuleName, entry.Module)
+			assert.Contains(t, entry.Fields, "latency_ms")
+		})
+	}
+}
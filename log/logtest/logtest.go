@@ -0,0 +1,195 @@
+// Package logtest 提供测试用的内存日志记录器
+//
+// New 会将log包的默认日志记录器及之后创建的模块日志记录器替换为内存记录，
+// 测试结束时自动恢复，期间不会创建日志目录或写入文件。
+package logtest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/space-ark-x/infra-common/log"
+	"go.uber.org/zap/zapcore"
+)
+
+// Recorder 内存日志记录器
+type Recorder struct {
+	mu      sync.Mutex
+	entries []log.Entry
+}
+
+// New 创建内存日志记录器并替换log包的日志输出
+// Fatal不会退出进程，测试结束时恢复原日志记录器与退出行为
+func New(t testing.TB) *Recorder {
+	t.Helper()
+	r := &Recorder{}
+	restore := log.UseCore(&recordCore{recorder: r, LevelEnabler: zapcore.DebugLevel})
+	prevExit := log.SetExitBehavior(log.ExitNoop())
+	t.Cleanup(func() {
+		log.SetExitBehavior(prevExit)
+		restore()
+	})
+	return r
+}
+
+// Entries 返回已记录的所有日志
+func (r *Recorder) Entries() Entries {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append(Entries(nil), r.entries...)
+}
+
+// Len 返回已记录的日志数量
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+// Reset 清空已记录的日志
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+// Module 返回指定模块的日志
+func (r *Recorder) Module(module string) Entries {
+	return r.Entries().Module(module)
+}
+
+// AssertLogged 断言存在指定级别且包含所有给定字段的日志，返回第一条匹配的日志
+func (r *Recorder) AssertLogged(t testing.TB, level log.Level, fields map[string]any) log.Entry {
+	t.Helper()
+	matched := r.Entries().Level(level).Fields(fields)
+	if len(matched) == 0 {
+		t.Errorf("no %s entry with fields %v, got:\n%s", level, fields, r.Entries())
+		return log.Entry{}
+	}
+	return matched[0]
+}
+
+// AssertMessage 断言存在指定级别与消息的日志，返回第一条匹配的日志
+func (r *Recorder) AssertMessage(t testing.TB, level log.Level, message string) log.Entry {
+	t.Helper()
+	matched := r.Entries().Level(level).Message(message)
+	if len(matched) == 0 {
+		t.Errorf("no %s entry with message %q, got:\n%s", level, message, r.Entries())
+		return log.Entry{}
+	}
+	return matched[0]
+}
+
+// AssertNotLogged 断言不存在指定级别的日志
+func (r *Recorder) AssertNotLogged(t testing.TB, level log.Level) {
+	t.Helper()
+	if matched := r.Entries().Level(level); len(matched) > 0 {
+		t.Errorf("unexpected %s entries:\n%s", level, matched)
+	}
+}
+
+// record 追加一条日志
+func (r *Recorder) record(entry log.Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+// Entries 日志列表，支持链式过滤
+type Entries []log.Entry
+
+// Level 过滤指定级别的日志
+func (e Entries) Level(level log.Level) Entries {
+	return e.Filter(func(entry log.Entry) bool {
+		return entry.Level == level
+	})
+}
+
+// Module 过滤指定模块的日志
+func (e Entries) Module(module string) Entries {
+	return e.Filter(func(entry log.Entry) bool {
+		return entry.Module == module
+	})
+}
+
+// Message 过滤指定消息的日志
+func (e Entries) Message(message string) Entries {
+	return e.Filter(func(entry log.Entry) bool {
+		return entry.Message == message
+	})
+}
+
+// Field 过滤字段值相等的日志
+func (e Entries) Field(key string, value any) Entries {
+	return e.Fields(map[string]any{key: value})
+}
+
+// Fields 过滤包含所有给定字段的日志
+func (e Entries) Fields(fields map[string]any) Entries {
+	return e.Filter(func(entry log.Entry) bool {
+		for k, want := range fields {
+			got, ok := entry.Fields[k]
+			if !ok || !log.FieldEqual(got, want) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Filter 按自定义条件过滤日志
+func (e Entries) Filter(match func(entry log.Entry) bool) Entries {
+	matched := make(Entries, 0, len(e))
+	for _, entry := range e {
+		if match(entry) {
+			matched = append(matched, entry)
+		}
+	}
+	return matched
+}
+
+// String 以便于阅读的格式输出日志列表
+func (e Entries) String() string {
+	s := ""
+	for _, entry := range e {
+		s += fmt.Sprintf("  %s [%s] %q %v\n", entry.Level, entry.Module, entry.Message, entry.Fields)
+	}
+	return s
+}
+
+// recordCore 将日志写入Recorder的zap核心
+type recordCore struct {
+	zapcore.LevelEnabler
+	recorder *Recorder
+	context  []zapcore.Field
+}
+
+// With 返回附加了上下文字段的核心
+func (c *recordCore) With(fields []zapcore.Field) zapcore.Core {
+	return &recordCore{
+		LevelEnabler: c.LevelEnabler,
+		recorder:     c.recorder,
+		context:      append(append([]zapcore.Field(nil), c.context...), fields...),
+	}
+}
+
+// Check 级别启用时将自身加入检查结果
+func (c *recordCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write 记录日志
+func (c *recordCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	all := append(append([]zapcore.Field(nil), c.context...), fields...)
+	c.recorder.record(log.NewEntry(ent, all))
+	return nil
+}
+
+// Sync 内存记录无需刷新
+func (c *recordCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,64 @@
+package logtest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/space-ark-x/infra-common/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Chdir(t.TempDir())
+	rec := New(t)
+
+	log.Info(map[string]any{"user": "alice", "count": 3})
+	log.Error(map[string]any{"error": errors.New("boom")})
+	log.NewZapLoggerWithModule("order").Warn(map[string]any{"orderId": "o-1"})
+
+	assert.Equal(t, 3, rec.Len())
+	rec.AssertLogged(t, log.InfoLevel, map[string]any{"user": "alice", "count": 3})
+	rec.AssertLogged(t, log.ErrorLevel, map[string]any{"error": errors.New("boom")})
+	rec.AssertNotLogged(t, log.DebugLevel)
+
+	order := rec.Module("order")
+	assert.Len(t, order, 1)
+	assert.Equal(t, log.WarnLevel, order[0].Level)
+	assert.Len(t, rec.Entries().Field("orderId", "o-1"), 1)
+	assert.Len(t, rec.Module("default"), 2)
+
+	entries, err := os.ReadDir(".")
+	assert.NoError(t, err)
+	assert.Empty(t, entries, "recorder must not write to disk")
+
+	rec.Reset()
+	assert.Equal(t, 0, rec.Len())
+}
+
+func TestRecorderFatal(t *testing.T) {
+	rec := New(t)
+	called := false
+	defer log.RegisterFatalHook(func(context.Context) {
+		called = true
+	})()
+
+	assert.True(t, log.Fatal(map[string]any{"reason": "shutdown"}))
+	assert.True(t, called)
+	rec.AssertLogged(t, log.FatalLevel, map[string]any{"reason": "shutdown"})
+}
+
+func TestRecorderRestore(t *testing.T) {
+	var inner *Recorder
+	t.Run("inner", func(t *testing.T) {
+		inner = New(t)
+		log.Info(map[string]any{"step": 1})
+	})
+	outer := New(t)
+	log.Info(map[string]any{"step": 2})
+
+	assert.Equal(t, 1, inner.Len())
+	assert.Equal(t, 1, outer.Len())
+	outer.AssertLogged(t, log.InfoLevel, map[string]any{"step": 2})
+}
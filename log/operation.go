@@ -0,0 +1,182 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// OutcomeOK 操作成功
+	OutcomeOK = "ok"
+	// OutcomeError 操作返回错误
+	OutcomeError = "error"
+	// OutcomePanic 操作发生panic
+	OutcomePanic = "panic"
+)
+
+// slowThreshold 操作耗时达到该值时以警告级别记录，单位纳秒
+var slowThreshold atomic.Int64
+
+// SetSlowThreshold 设置默认的慢操作阈值，之后结束的操作生效
+func SetSlowThreshold(threshold time.Duration) {
+	loadSettings()
+	slowThreshold.Store(int64(threshold))
+}
+
+// getSlowThresholdFromEnv 从配置LOG_SLOW_THRESHOLD读取慢操作阈值，默认1秒
+func getSlowThresholdFromEnv() time.Duration {
+	threshold, err := time.ParseDuration(configValue("LOG_SLOW_THRESHOLD", "1s"))
+	if err != nil || threshold <= 0 {
+		return time.Second
+	}
+	return threshold
+}
+
+// Operation 计时中的操作，End时输出一条包含耗时与结果的日志
+type Operation struct {
+	logger    Logger
+	name      string
+	caller    zapcore.EntryCaller
+	start     time.Time
+	threshold time.Duration
+	mu        sync.Mutex
+	fields    map[string]any
+	ended     atomic.Bool
+}
+
+// Start 使用默认日志记录器开始计时一个操作
+//
+//	op := log.Start(ctx, "import_users", map[string]any{"file": name})
+//	defer op.Finish(&err)
+func Start(ctx context.Context, name string, fields ...map[string]any) *Operation {
+	return startOperation(ctx, GetLogger(), name, fields)
+}
+
+// StartWithLogger 使用指定日志记录器开始计时一个操作，上下文中的追踪ID会记录为traceId字段
+// 操作日志的调用位置为开始操作的位置
+func StartWithLogger(ctx context.Context, logger Logger, name string, fields ...map[string]any) *Operation {
+	return startOperation(ctx, logger, name, fields)
+}
+
+// startOperation 创建操作并记录Start或StartWithLogger的调用位置
+func startOperation(ctx context.Context, logger Logger, name string, fields []map[string]any) *Operation {
+	loadSettings()
+	op := &Operation{
+		logger:    withRequestBuffer(ctx, logger),
+		name:      name,
+		caller:    zapcore.NewEntryCaller(runtime.Caller(2)),
+		start:     time.Now(),
+		threshold: time.Duration(slowThreshold.Load()),
+		fields:    make(map[string]any),
+	}
+	if traceId := TraceIdFromContext(ctx); traceId != "" {
+		op.fields["traceId"] = traceId
+	}
+	for _, f := range fields {
+		op.Add(f)
+	}
+	return op
+}
+
+// Add 附加字段，同名字段以最后一次为准
+func (o *Operation) Add(fields map[string]any) *Operation {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for k, v := range fields {
+		o.fields[k] = v
+	}
+	return o
+}
+
+// SlowAfter 设置该操作的慢操作阈值
+func (o *Operation) SlowAfter(threshold time.Duration) *Operation {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.threshold = threshold
+	return o
+}
+
+// End 结束操作并输出日志，只有第一次调用生效
+// 成功且未超过阈值时为调试级别，超过阈值时为警告级别，err不为nil时为错误级别
+func (o *Operation) End(err error) {
+	if err != nil {
+		o.end(OutcomeError, map[string]any{"error": err})
+		return
+	}
+	o.end(OutcomeOK, nil)
+}
+
+// Finish 在defer中结束操作，errp指向函数的命名返回值，可以为nil
+// 发生panic时记录panic值与调用栈后继续panic
+//
+//	func importUsers(ctx context.Context) (err error) {
+//		op := log.Start(ctx, "import_users")
+//		defer op.Finish(&err)
+//		...
+//	}
+func (o *Operation) Finish(errp *error) {
+	if r := recover(); r != nil {
+		o.end(OutcomePanic, map[string]any{
+			"panic":      fmt.Sprint(r),
+			"stacktrace": string(debug.Stack()),
+		})
+		panic(r)
+	}
+	var err error
+	if errp != nil {
+		err = *errp
+	}
+	o.End(err)
+}
+
+// end 输出操作日志
+func (o *Operation) end(outcome string, extra map[string]any) {
+	if !o.ended.CompareAndSwap(false, true) {
+		return
+	}
+	duration := time.Since(o.start)
+
+	o.mu.Lock()
+	in := make(map[string]any, len(o.fields)+len(extra)+3)
+	for k, v := range o.fields {
+		in[k] = v
+	}
+	threshold := o.threshold
+	o.mu.Unlock()
+	for k, v := range extra {
+		in[k] = v
+	}
+	in["op"] = o.name
+	in["outcome"] = outcome
+	in["duration_ms"] = float64(duration) / float64(time.Millisecond)
+
+	level := DebugLevel
+	switch {
+	case outcome != OutcomeOK:
+		level = ErrorLevel
+	case threshold > 0 && duration >= threshold:
+		in["slow_threshold_ms"] = float64(threshold) / float64(time.Millisecond)
+		level = WarnLevel
+	}
+
+	// zap日志记录器使用开始操作的调用位置，采样等按调用位置区分的功能才能区分不同操作
+	if z, ok := o.logger.(*ZapLogger); ok && o.caller.Defined {
+		z.writeAt(level, o.caller, in)
+		return
+	}
+	switch level {
+	case ErrorLevel:
+		o.logger.Error(in)
+	case WarnLevel:
+		o.logger.Warn(in)
+	default:
+		o.logger.Debug(in)
+	}
+}
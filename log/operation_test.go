@@ -0,0 +1,110 @@
+package log_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/space-ark-x/infra-common/log"
+	"github.com/space-ark-x/infra-common/log/logtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperation(t *testing.T) {
+	tests := []struct {
+		name        string
+		threshold   time.Duration
+		run         func(op *log.Operation)
+		wantLevel   log.Level
+		wantOutcome string
+	}{
+		{
+			name:        "fast",
+			threshold:   time.Hour,
+			run:         func(op *log.Operation) { op.End(nil) },
+			wantLevel:   log.DebugLevel,
+			wantOutcome: log.OutcomeOK,
+		},
+		{
+			name:      "slow",
+			threshold: time.Millisecond,
+			run: func(op *log.Operation) {
+				time.Sleep(2 * time.Millisecond)
+				op.End(nil)
+			},
+			wantLevel:   log.WarnLevel,
+			wantOutcome: log.OutcomeOK,
+		},
+		{
+			name:        "error",
+			threshold:   time.Hour,
+			run:         func(op *log.Operation) { op.End(errors.New("disk full")) },
+			wantLevel:   log.ErrorLevel,
+			wantOutcome: log.OutcomeError,
+		},
+		{
+			name:      "panic",
+			threshold: time.Hour,
+			run: func(op *log.Operation) {
+				defer func() { _ = recover() }()
+				func() (err error) {
+					defer op.Finish(&err)
+					panic("bad row")
+				}()
+			},
+			wantLevel:   log.ErrorLevel,
+			wantOutcome: log.OutcomePanic,
+		},
+		{
+			name:      "finish with error",
+			threshold: time.Hour,
+			run: func(op *log.Operation) {
+				_ = func() (err error) {
+					defer op.Finish(&err)
+					return errors.New("disk full")
+				}()
+			},
+			wantLevel:   log.ErrorLevel,
+			wantOutcome: log.OutcomeError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := logtest.New(t)
+			ctx := log.ContextWithTraceId(context.Background(), "t-1")
+
+			op := log.Start(ctx, "import_users", map[string]any{"file": "users.csv"}).SlowAfter(tt.threshold)
+			op.Add(map[string]any{"rows": 42})
+			tt.run(op)
+			op.End(nil)
+
+			assert.Equal(t, 1, rec.Len(), "only the first End is logged")
+			entry := rec.AssertLogged(t, tt.wantLevel, map[string]any{
+				"op":      "import_users",
+				"outcome": tt.wantOutcome,
+				"file":    "users.csv",
+				"rows":    42,
+				"traceId": "t-1",
+			})
+			assert.Contains(t, entry.Fields, "duration_ms")
+			if tt.wantOutcome == log.OutcomePanic {
+				assert.Equal(t, "bad row", entry.Fields["panic"])
+				assert.Contains(t, entry.Fields, "stacktrace")
+			}
+		})
+	}
+}
+
+func TestOperationCaller(t *testing.T) {
+	rec := logtest.New(t)
+
+	log.Start(context.Background(), "first").End(nil)
+	log.StartWithLogger(context.Background(), log.GetLogger(), "second").End(errors.New("failed"))
+
+	entries := rec.Entries()
+	assert.Len(t, entries, 2)
+	for _, entry := range entries {
+		assert.Contains(t, entry.Caller, "log/operation_test.go", "调用位置为开始操作的位置")
+	}
+}
@@ -0,0 +1,521 @@
+package log
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// FormatOTLP 以OpenTelemetry日志数据模型(OTLP JSON)格式写入日志文件
+// 每行是一个完整的LogsData，可由collector的otlpjsonfile接收器读取
+const FormatOTLP = "otlp"
+
+// spanIdKeys 作为OTLP spanId输出的字段名
+var spanIdKeys = []string{"spanId", "span_id"}
+
+// otlpSeverity 日志级别对应的OTLP严重性编号
+var otlpSeverity = map[zapcore.Level]int{
+	zapcore.DebugLevel:  5,
+	zapcore.InfoLevel:   9,
+	zapcore.WarnLevel:   13,
+	zapcore.ErrorLevel:  17,
+	zapcore.DPanicLevel: 18,
+	zapcore.PanicLevel:  19,
+	zapcore.FatalLevel:  21,
+}
+
+// otlpLogsData OTLP LogsData
+type otlpLogsData struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+// otlpResourceLogs OTLP ResourceLogs
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+// otlpResource OTLP Resource
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+// otlpScopeLogs OTLP ScopeLogs，模块名作为scope名称
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+// otlpScope OTLP InstrumentationScope
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+// otlpLogRecord OTLP LogRecord
+type otlpLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 *otlpAnyValue  `json:"body,omitempty"`
+	Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+	TraceId              string         `json:"traceId,omitempty"`
+	SpanId               string         `json:"spanId,omitempty"`
+}
+
+// otlpKeyValue OTLP KeyValue
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+// otlpAnyValue OTLP AnyValue，只设置其中一个字段
+type otlpAnyValue struct {
+	StringValue *string          `json:"stringValue,omitempty"`
+	BoolValue   *bool            `json:"boolValue,omitempty"`
+	IntValue    *string          `json:"intValue,omitempty"`
+	DoubleValue *float64         `json:"doubleValue,omitempty"`
+	BytesValue  []byte           `json:"bytesValue,omitempty"`
+	ArrayValue  *otlpArrayValue  `json:"arrayValue,omitempty"`
+	KvlistValue *otlpKvlistValue `json:"kvlistValue,omitempty"`
+}
+
+// otlpArrayValue OTLP ArrayValue
+type otlpArrayValue struct {
+	Values []otlpAnyValue `json:"values"`
+}
+
+// otlpKvlistValue OTLP KeyValueList
+type otlpKvlistValue struct {
+	Values []otlpKeyValue `json:"values"`
+}
+
+// otlpResourceAttributes 进程的资源属性，首次使用时从配置读取
+// service.name 来自APP_NAME，deployment.environment 来自环境变量Env
+var otlpResourceAttributes = sync.OnceValue(func() []otlpKeyValue {
+	serviceName := configValue("APP_NAME", "")
+	if serviceName == "" {
+		serviceName = "unknown_service"
+	}
+	attrs := []otlpKeyValue{
+		{Key: "service.name", Value: otlpValue(serviceName)},
+	}
+	if env := os.Getenv("Env"); env != "" {
+		attrs = append(attrs, otlpKeyValue{Key: "deployment.environment", Value: otlpValue(env)})
+	}
+	if host, err := os.Hostname(); err == nil {
+		attrs = append(attrs, otlpKeyValue{Key: "host.name", Value: otlpValue(host)})
+	}
+	return append(attrs, otlpKeyValue{Key: "process.pid", Value: otlpValue(os.Getpid())})
+})
+
+// newOTLPRecord 将日志条目与字段转换为OTLP LogRecord，fields会被修改
+// 消息为空时使用msg字段作为body；traceId与spanId字段为合法的十六进制ID时作为记录的追踪ID
+func newOTLPRecord(ent zapcore.Entry, fields map[string]any) otlpLogRecord {
+	record := otlpLogRecord{
+		TimeUnixNano:         strconv.FormatInt(ent.Time.UnixNano(), 10),
+		ObservedTimeUnixNano: strconv.FormatInt(time.Now().UnixNano(), 10),
+		SeverityNumber:       otlpSeverity[ent.Level],
+		SeverityText:         ent.Level.CapitalString(),
+	}
+
+	message := ent.Message
+	if msg, ok := fields["msg"].(string); ok && message == "" {
+		message = msg
+		delete(fields, "msg")
+	}
+	if message != "" {
+		body := otlpValue(message)
+		record.Body = &body
+	}
+	record.TraceId = takeHexId(fields, traceIdKeys, 16)
+	record.SpanId = takeHexId(fields, spanIdKeys, 8)
+
+	for _, k := range sortedKeys(fields) {
+		record.Attributes = append(record.Attributes, otlpKeyValue{Key: k, Value: otlpValue(fields[k])})
+	}
+	if ent.Caller.Defined {
+		record.Attributes = append(record.Attributes,
+			otlpKeyValue{Key: "code.filepath", Value: otlpValue(ent.Caller.File)},
+			otlpKeyValue{Key: "code.lineno", Value: otlpValue(ent.Caller.Line)},
+		)
+		if ent.Caller.Function != "" {
+			record.Attributes = append(record.Attributes, otlpKeyValue{Key: "code.function", Value: otlpValue(ent.Caller.Function)})
+		}
+	}
+	if ent.Stack != "" {
+		record.Attributes = append(record.Attributes, otlpKeyValue{Key: "exception.stacktrace", Value: otlpValue(ent.Stack)})
+	}
+	return record
+}
+
+// takeHexId 取出第一个存在的ID字段，去掉连字符后为size字节的十六进制时从字段中移除并返回
+// 例如traceId中间件生成的UUID会转换为32位十六进制的OTLP traceId
+func takeHexId(fields map[string]any, keys []string, size int) string {
+	for _, key := range keys {
+		value, ok := fields[key].(string)
+		if !ok {
+			continue
+		}
+		id := strings.ToLower(strings.ReplaceAll(value, "-", ""))
+		if decoded, err := hex.DecodeString(id); err != nil || len(decoded) != size {
+			return ""
+		}
+		delete(fields, key)
+		return id
+	}
+	return ""
+}
+
+// otlpValue 将字段值转换为OTLP AnyValue
+func otlpValue(v any) otlpAnyValue {
+	switch value := v.(type) {
+	case nil:
+		return otlpAnyValue{}
+	case string:
+		return otlpAnyValue{StringValue: &value}
+	case bool:
+		return otlpAnyValue{BoolValue: &value}
+	case []byte:
+		return otlpAnyValue{BytesValue: value}
+	case time.Time:
+		return otlpValue(value.Format(time.RFC3339Nano))
+	case time.Duration:
+		return otlpValue(value.String())
+	case error:
+		return otlpValue(value.Error())
+	case []any:
+		values := make([]otlpAnyValue, 0, len(value))
+		for _, item := range value {
+			values = append(values, otlpValue(item))
+		}
+		return otlpAnyValue{ArrayValue: &otlpArrayValue{Values: values}}
+	case map[string]any:
+		values := make([]otlpKeyValue, 0, len(value))
+		for _, k := range sortedKeys(value) {
+			values = append(values, otlpKeyValue{Key: k, Value: otlpValue(value[k])})
+		}
+		return otlpAnyValue{KvlistValue: &otlpKvlistValue{Values: values}}
+	case fmt.Stringer:
+		return otlpValue(value.String())
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s := strconv.FormatInt(rv.Int(), 10)
+		return otlpAnyValue{IntValue: &s}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		s := strconv.FormatUint(rv.Uint(), 10)
+		return otlpAnyValue{IntValue: &s}
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		return otlpAnyValue{DoubleValue: &f}
+	}
+
+	// 其他类型按JSON结构转换
+	data, err := json.Marshal(v)
+	if err != nil {
+		return otlpValue(fmt.Sprintf("%v", v))
+	}
+	var decoded any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return otlpValue(string(data))
+	}
+	return otlpValue(decoded)
+}
+
+// newOTLPLogsData 创建只包含一个scope的LogsData
+func newOTLPLogsData(scope string, records ...otlpLogRecord) otlpLogsData {
+	return otlpLogsData{ResourceLogs: []otlpResourceLogs{{
+		Resource:  otlpResource{Attributes: otlpResourceAttributes()},
+		ScopeLogs: []otlpScopeLogs{{Scope: otlpScope{Name: scope}, LogRecords: records}},
+	}}}
+}
+
+var otlpBufferPool = buffer.NewPool()
+
+// otlpEncoder 以OTLP JSON格式编码日志的编码器
+type otlpEncoder struct {
+	*zapcore.MapObjectEncoder
+}
+
+// NewOTLPEncoder 创建OTLP JSON编码器，每条日志编码为一行LogsData
+func NewOTLPEncoder() zapcore.Encoder {
+	return &otlpEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder()}
+}
+
+// Clone 复制编码器及其上下文字段
+func (o *otlpEncoder) Clone() zapcore.Encoder {
+	clone := &otlpEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder()}
+	for k, v := range o.Fields {
+		clone.Fields[k] = v
+	}
+	return clone
+}
+
+// EncodeEntry 将日志条目编码为一行OTLP JSON
+func (o *otlpEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	enc := zapcore.NewMapObjectEncoder()
+	for k, v := range o.Fields {
+		enc.Fields[k] = v
+	}
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+
+	data, err := json.Marshal(newOTLPLogsData(ent.LoggerName, newOTLPRecord(ent, enc.Fields)))
+	if err != nil {
+		return nil, err
+	}
+	line := otlpBufferPool.Get()
+	line.AppendBytes(data)
+	line.AppendByte('\n')
+	return line, nil
+}
+
+// OTLPExporterConfig OTLP/HTTP导出配置，零值字段使用默认值
+type OTLPExporterConfig struct {
+	Endpoint      string        // collector的日志接收地址，例如http://localhost:4318/v1/logs
+	BatchSize     int           // 单次发送的最大日志数，默认512
+	FlushInterval time.Duration // 定期发送的间隔，默认1秒
+	MaxQueue      int           // 等待发送的日志上限，超出后丢弃并计数，默认2048
+	MaxRetries    int           // 发送失败后的重试次数，默认3
+	RetryBackoff  time.Duration // 首次重试的等待时间，之后每次翻倍，默认500毫秒
+	Timeout       time.Duration // 单次请求超时，默认5秒
+	Client        *http.Client  // 自定义HTTP客户端
+}
+
+// withDefaults 填充默认值
+func (c OTLPExporterConfig) withDefaults() OTLPExporterConfig {
+	if c.BatchSize <= 0 {
+		c.BatchSize = 512
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = time.Second
+	}
+	if c.MaxQueue <= 0 {
+		c.MaxQueue = 2048
+	}
+	if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	} else if c.MaxRetries == 0 {
+		c.MaxRetries = 3
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = 500 * time.Millisecond
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 5 * time.Second
+	}
+	if c.Client == nil {
+		c.Client = &http.Client{}
+	}
+	return c
+}
+
+// otlpQueued 等待发送的日志
+type otlpQueued struct {
+	scope  string
+	record otlpLogRecord
+}
+
+// OTLPExporter 以OTLP/HTTP JSON批量发送日志的导出器
+type OTLPExporter struct {
+	config  OTLPExporterConfig
+	mu      sync.Mutex
+	queue   []otlpQueued
+	dropped atomic.Int64
+	sendMu  sync.Mutex
+	notify  chan struct{}
+	done    chan struct{}
+	closed  sync.Once
+}
+
+// NewOTLPExporter 创建导出器并启动后台发送，通过Core接入日志记录器
+//
+//	exporter := log.NewOTLPExporter(log.OTLPExporterConfig{Endpoint: "http://localhost:4318/v1/logs"})
+//	logger := log.NewZapLoggerWithCore("order", exporter.Core(log.InfoLevel))
+func NewOTLPExporter(config OTLPExporterConfig) *OTLPExporter {
+	e := &OTLPExporter{
+		config: config.withDefaults(),
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// getOTLPExporterFromEnv 配置了LOG_OTLP_ENDPOINT时创建导出器，所有日志文件同时发送到该地址
+func getOTLPExporterFromEnv() *OTLPExporter {
+	endpoint := configValue("LOG_OTLP_ENDPOINT", "")
+	if endpoint == "" {
+		return nil
+	}
+	return NewOTLPExporter(OTLPExporterConfig{Endpoint: endpoint})
+}
+
+// Core 返回将日志加入导出队列的zap核心
+func (e *OTLPExporter) Core(enabler zapcore.LevelEnabler) zapcore.Core {
+	return &otlpCore{LevelEnabler: enabler, exporter: e}
+}
+
+// Dropped 返回因队列已满或发送失败丢弃的日志数
+func (e *OTLPExporter) Dropped() int64 {
+	return e.dropped.Load()
+}
+
+// Sync 立即发送队列中的所有日志，致命错误处理期间重试不超过致命错误钩子的超时时间
+func (e *OTLPExporter) Sync() error {
+	return e.send(flushContext(), true)
+}
+
+// Close 停止后台发送并发送剩余日志
+func (e *OTLPExporter) Close() error {
+	e.closed.Do(func() {
+		close(e.done)
+	})
+	return e.Sync()
+}
+
+// enqueue 将日志加入队列，达到批量大小时通知发送
+func (e *OTLPExporter) enqueue(scope string, record otlpLogRecord) {
+	e.mu.Lock()
+	if len(e.queue) >= e.config.MaxQueue {
+		e.mu.Unlock()
+		e.dropped.Add(1)
+		return
+	}
+	e.queue = append(e.queue, otlpQueued{scope: scope, record: record})
+	full := len(e.queue) >= e.config.BatchSize
+	e.mu.Unlock()
+
+	if full {
+		select {
+		case e.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run 定期或在日志达到批量大小时发送
+func (e *OTLPExporter) run() {
+	ticker := time.NewTicker(e.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-ticker.C:
+		case <-e.notify:
+		}
+		_ = e.send(context.Background(), false)
+	}
+}
+
+// send 发送队列中的日志，all为false时只发送一批，ctx取消后不再重试
+func (e *OTLPExporter) send(ctx context.Context, all bool) error {
+	e.sendMu.Lock()
+	defer e.sendMu.Unlock()
+
+	for {
+		batch := e.takeBatch()
+		if len(batch) == 0 {
+			return nil
+		}
+		body, err := json.Marshal(otlpBatch(batch))
+		if err == nil {
+			err = postWithRetry(ctx, e.config.Client, e.config.Endpoint, body, e.config.Timeout, e.config.MaxRetries, e.config.RetryBackoff)
+		}
+		if err != nil {
+			e.dropped.Add(int64(len(batch)))
+			return err
+		}
+		if !all {
+			return nil
+		}
+	}
+}
+
+// takeBatch 取出一批待发送的日志
+func (e *OTLPExporter) takeBatch() []otlpQueued {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	n := min(len(e.queue), e.config.BatchSize)
+	batch := append([]otlpQueued(nil), e.queue[:n]...)
+	e.queue = append(e.queue[:0], e.queue[n:]...)
+	return batch
+}
+
+// otlpBatch 将日志按模块分组为一个LogsData，保持模块首次出现的顺序
+func otlpBatch(batch []otlpQueued) otlpLogsData {
+	data := newOTLPLogsData("")
+	resourceLogs := &data.ResourceLogs[0]
+	resourceLogs.ScopeLogs = resourceLogs.ScopeLogs[:0]
+	index := make(map[string]int)
+	for _, queued := range batch {
+		i, ok := index[queued.scope]
+		if !ok {
+			i = len(resourceLogs.ScopeLogs)
+			index[queued.scope] = i
+			resourceLogs.ScopeLogs = append(resourceLogs.ScopeLogs, otlpScopeLogs{Scope: otlpScope{Name: queued.scope}})
+		}
+		resourceLogs.ScopeLogs[i].LogRecords = append(resourceLogs.ScopeLogs[i].LogRecords, queued.record)
+	}
+	return data
+}
+
+// otlpCore 将日志加入OTLP导出队列的核心
+type otlpCore struct {
+	zapcore.LevelEnabler
+	exporter *OTLPExporter
+	context  []zapcore.Field
+}
+
+// With 返回附加了上下文字段的核心
+func (c *otlpCore) With(fields []zapcore.Field) zapcore.Core {
+	return &otlpCore{
+		LevelEnabler: c.LevelEnabler,
+		exporter:     c.exporter,
+		context:      append(append([]zapcore.Field(nil), c.context...), fields...),
+	}
+}
+
+// Check 级别启用时将自身加入检查结果
+func (c *otlpCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write 转换为OTLP记录后加入导出队列
+func (c *otlpCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, field := range c.context {
+		field.AddTo(enc)
+	}
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+	c.exporter.enqueue(ent.LoggerName, newOTLPRecord(ent, enc.Fields))
+	return nil
+}
+
+// Sync 发送队列中的日志
+func (c *otlpCore) Sync() error {
+	return c.exporter.Sync()
+}
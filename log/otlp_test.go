@@ -0,0 +1,168 @@
+package log_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/space-ark-x/infra-common/log"
+	"github.com/space-ark-x/infra-common/log/logtest"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+// otlpAttributes 将OTLP属性列表转换为map，值保留AnyValue结构
+func otlpAttributes(attrs []any) map[string]any {
+	out := make(map[string]any, len(attrs))
+	for _, attr := range attrs {
+		kv := attr.(map[string]any)
+		out[kv["key"].(string)] = kv["value"]
+	}
+	return out
+}
+
+func TestOTLPEncoder(t *testing.T) {
+	t.Setenv("APP_NAME", "order-service")
+	t.Setenv("Env", "staging")
+
+	tests := []struct {
+		name         string
+		log          func(logger log.Logger)
+		wantSeverity float64
+		wantText     string
+		wantBody     any
+		wantTraceId  string
+		wantAttrs    map[string]any
+	}{
+		{
+			name: "info with message and trace",
+			log: func(logger log.Logger) {
+				logger.Info(map[string]any{
+					"msg":     "created",
+					"traceId": "3F2504E0-4F89-11D3-9A0C-0305E82C3301",
+					"count":   3,
+				})
+			},
+			wantSeverity: 9,
+			wantText:     "INFO",
+			wantBody:     map[string]any{"stringValue": "created"},
+			wantTraceId:  "3f2504e04f8911d39a0c0305e82c3301",
+			wantAttrs: map[string]any{
+				"count": map[string]any{"intValue": "3"},
+			},
+		},
+		{
+			name: "error with invalid trace id",
+			log: func(logger log.Logger) {
+				logger.Error(map[string]any{"traceId": "t-1", "ok": false, "ratio": 0.5})
+			},
+			wantSeverity: 17,
+			wantText:     "ERROR",
+			wantAttrs: map[string]any{
+				"traceId": map[string]any{"stringValue": "t-1"},
+				"ok":      map[string]any{"boolValue": false},
+				"ratio":   map[string]any{"doubleValue": 0.5},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			core := zapcore.NewCore(log.NewOTLPEncoder(), zapcore.AddSync(&buf), zapcore.DebugLevel)
+			tt.log(log.NewZapLoggerWithCore("order", core))
+
+			var data map[string]any
+			assert.NoError(t, json.Unmarshal(buf.Bytes(), &data))
+			resourceLogs := data["resourceLogs"].([]any)[0].(map[string]any)
+			resource := otlpAttributes(resourceLogs["resource"].(map[string]any)["attributes"].([]any))
+			assert.Equal(t, map[string]any{"stringValue": "order-service"}, resource["service.name"])
+			assert.Equal(t, map[string]any{"stringValue": "staging"}, resource["deployment.environment"])
+
+			scopeLogs := resourceLogs["scopeLogs"].([]any)[0].(map[string]any)
+			assert.Equal(t, "order", scopeLogs["scope"].(map[string]any)["name"])
+			record := scopeLogs["logRecords"].([]any)[0].(map[string]any)
+			assert.Equal(t, tt.wantSeverity, record["severityNumber"])
+			assert.Equal(t, tt.wantText, record["severityText"])
+			assert.Equal(t, tt.wantBody, record["body"])
+			if tt.wantTraceId != "" {
+				assert.Equal(t, tt.wantTraceId, record["traceId"])
+			} else {
+				assert.NotContains(t, record, "traceId")
+			}
+			assert.NotEmpty(t, record["timeUnixNano"])
+
+			attrs := otlpAttributes(record["attributes"].([]any))
+			for k, want := range tt.wantAttrs {
+				assert.Equal(t, want, attrs[k], k)
+			}
+			assert.Contains(t, attrs, "pid")
+			assert.Contains(t, attrs, "code.filepath")
+		})
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []map[string]any
+		attempts int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var data map[string]any
+		assert.NoError(t, json.Unmarshal(body, &data))
+		requests = append(requests, data)
+	}))
+	defer server.Close()
+
+	exporter := log.NewOTLPExporter(log.OTLPExporterConfig{Endpoint: server.URL, RetryBackoff: 1})
+	defer exporter.Close()
+	log.NewZapLoggerWithCore("order", exporter.Core(zapcore.InfoLevel)).Info(map[string]any{"msg": "a"})
+	log.NewZapLoggerWithCore("user", exporter.Core(zapcore.InfoLevel)).Warn(map[string]any{"msg": "b"})
+	log.NewZapLoggerWithCore("order", exporter.Core(zapcore.InfoLevel)).Debug(map[string]any{"msg": "c"})
+	assert.NoError(t, exporter.Sync())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 2, attempts, "retried after 503")
+	assert.Len(t, requests, 1)
+	scopeLogs := requests[0]["resourceLogs"].([]any)[0].(map[string]any)["scopeLogs"].([]any)
+	assert.Len(t, scopeLogs, 2)
+	assert.Equal(t, "order", scopeLogs[0].(map[string]any)["scope"].(map[string]any)["name"])
+	assert.Len(t, scopeLogs[0].(map[string]any)["logRecords"], 1)
+	assert.Equal(t, int64(0), exporter.Dropped())
+}
+
+func TestOTLPExporterFatalDeadline(t *testing.T) {
+	logtest.New(t)
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	exporter := log.NewOTLPExporter(log.OTLPExporterConfig{Endpoint: server.URL, FlushInterval: time.Hour, MaxRetries: 10, RetryBackoff: 200 * time.Millisecond})
+	defer exporter.Close()
+	log.SetFatalHookTimeout(50 * time.Millisecond)
+	defer log.SetFatalHookTimeout(5 * time.Second)
+
+	// 采集端不可用时，刷新与重试不超过致命错误钩子的超时时间
+	start := time.Now()
+	log.NewZapLoggerWithCore("otlp-fatal", exporter.Core(zapcore.InfoLevel)).Fatal(map[string]any{"reason": "shutdown"})
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(1), attempts.Load())
+}
@@ -0,0 +1,287 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SamplingRule 采样规则
+// 每个统计周期内同一key的前First条全部记录，之后每Thereafter条记录1条；
+// Thereafter为0时丢弃之后的所有记录，即按调用位置限流
+type SamplingRule struct {
+	First      int `json:"first"`
+	Thereafter int `json:"thereafter"`
+}
+
+// SamplingConfig 采样配置
+// 规则优先级: Modules > Levels > Default，均未配置时不采样；Fatal等级别从不丢弃
+type SamplingConfig struct {
+	Tick     time.Duration           // 统计周期，默认1秒
+	KeyField string                  // 参与采样key的字段名，key始终包含模块、级别、调用位置与消息
+	Default  *SamplingRule           // 默认规则
+	Levels   map[Level]SamplingRule  // 按级别的规则
+	Modules  map[string]SamplingRule // 按模块的规则
+}
+
+// rule 返回日志条目适用的规则
+func (c *SamplingConfig) rule(ent zapcore.Entry) (SamplingRule, bool) {
+	if rule, ok := c.Modules[ent.LoggerName]; ok {
+		return rule, true
+	}
+	if rule, ok := c.Levels[ent.Level]; ok {
+		return rule, true
+	}
+	if c.Default != nil {
+		return *c.Default, true
+	}
+	return SamplingRule{}, false
+}
+
+// sampleCounter 单个采样key在当前周期的计数
+type sampleCounter struct {
+	core       zapcore.Core
+	ent        zapcore.Entry
+	tick       int64
+	count      int64
+	suppressed int64
+}
+
+// sampler 所有日志记录器共享的采样状态
+type sampler struct {
+	config   atomic.Pointer[SamplingConfig]
+	mu       sync.Mutex
+	counters map[string]*sampleCounter
+	flusher  sync.Once
+}
+
+// defaultSampler 包级别的采样器
+var defaultSampler = &sampler{counters: make(map[string]*sampleCounter)}
+
+// SetSampling 设置采样配置，nil表示关闭采样
+// 对已创建的日志记录器同样生效，设置时会输出并清空之前的计数
+func SetSampling(config *SamplingConfig) {
+	loadSettings()
+	defaultSampler.set(config)
+}
+
+// getSamplingFromEnv 从配置读取采样设置，LOG_SAMPLING为true时启用
+// LOG_SAMPLING_FIRST、LOG_SAMPLING_THEREAFTER、LOG_SAMPLING_TICK、LOG_SAMPLING_KEY
+// 以及LOG_SAMPLING_LEVELS(逗号分隔，默认debug,info,warn,error)
+func getSamplingFromEnv() *SamplingConfig {
+	enabled, err := strconv.ParseBool(configValue("LOG_SAMPLING", "false"))
+	if err != nil || !enabled {
+		return nil
+	}
+
+	rule := SamplingRule{First: 100, Thereafter: 100}
+	if first, err := strconv.Atoi(configValue("LOG_SAMPLING_FIRST", "100")); err == nil && first >= 0 {
+		rule.First = first
+	}
+	if thereafter, err := strconv.Atoi(configValue("LOG_SAMPLING_THEREAFTER", "100")); err == nil && thereafter >= 0 {
+		rule.Thereafter = thereafter
+	}
+	tick, err := time.ParseDuration(configValue("LOG_SAMPLING_TICK", "1s"))
+	if err != nil || tick <= 0 {
+		tick = time.Second
+	}
+
+	levels := make(map[Level]SamplingRule)
+	for _, name := range strings.Split(configValue("LOG_SAMPLING_LEVELS", "debug,info,warn,error"), ",") {
+		var level Level
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(name))); err == nil {
+			levels[level] = rule
+		}
+	}
+	return &SamplingConfig{
+		Tick:     tick,
+		KeyField: configValue("LOG_SAMPLING_KEY", ""),
+		Levels:   levels,
+	}
+}
+
+// set 替换采样配置并清空计数
+func (s *sampler) set(config *SamplingConfig) {
+	if config != nil && config.Tick <= 0 {
+		clone := *config
+		clone.Tick = time.Second
+		config = &clone
+	}
+	s.flush(true)
+	s.config.Store(config)
+	if config != nil {
+		s.flusher.Do(func() {
+			go s.run()
+		})
+	}
+}
+
+// run 定期输出已结束周期的抑制统计
+func (s *sampler) run() {
+	for {
+		tick := time.Second
+		if config := s.config.Load(); config != nil {
+			tick = config.Tick
+		}
+		time.Sleep(tick)
+		s.flush(false)
+	}
+}
+
+// allow 判断日志条目是否需要记录
+func (s *sampler) allow(core zapcore.Core, ent zapcore.Entry, fields []zapcore.Field) bool {
+	config := s.config.Load()
+	if config == nil || ent.Level >= zapcore.DPanicLevel {
+		return true
+	}
+	rule, ok := config.rule(ent)
+	if !ok {
+		return true
+	}
+
+	key := sampleKey(ent, fields, config.KeyField)
+	tick := ent.Time.UnixNano() / int64(config.Tick)
+
+	s.mu.Lock()
+	counter, ok := s.counters[key]
+	var summary *sampleCounter
+	if !ok || counter.tick != tick {
+		if ok && counter.suppressed > 0 {
+			summary = counter
+		}
+		counter = &sampleCounter{core: core, tick: tick}
+		s.counters[key] = counter
+	}
+	counter.ent = ent
+	counter.count++
+	n := counter.count
+	allowed := n <= int64(rule.First) ||
+		(rule.Thereafter > 0 && (n-int64(rule.First))%int64(rule.Thereafter) == 0)
+	if !allowed {
+		counter.suppressed++
+	}
+	s.mu.Unlock()
+
+	if summary != nil {
+		writeSummary(summary)
+	}
+	return allowed
+}
+
+// flush 输出抑制统计，all为false时只处理已结束的周期
+func (s *sampler) flush(all bool) {
+	config := s.config.Load()
+	now := time.Now().UnixNano()
+
+	s.mu.Lock()
+	summaries := make([]*sampleCounter, 0)
+	for key, counter := range s.counters {
+		if !all && config != nil && counter.tick >= now/int64(config.Tick) {
+			continue
+		}
+		if counter.suppressed > 0 {
+			summaries = append(summaries, counter)
+		}
+		delete(s.counters, key)
+	}
+	s.mu.Unlock()
+
+	for _, summary := range summaries {
+		writeSummary(summary)
+	}
+}
+
+// sampleKey 计算采样key: 模块、级别、调用位置、消息以及可选的字段值
+func sampleKey(ent zapcore.Entry, fields []zapcore.Field, keyField string) string {
+	var sb strings.Builder
+	sb.WriteString(ent.LoggerName)
+	sb.WriteByte('|')
+	sb.WriteString(ent.Level.String())
+	sb.WriteByte('|')
+	sb.WriteString(ent.Caller.String())
+	sb.WriteByte('|')
+	sb.WriteString(ent.Message)
+	if keyField != "" {
+		for _, field := range fields {
+			if field.Key == keyField {
+				sb.WriteByte('|')
+				sb.WriteString(fieldString(field))
+				break
+			}
+		}
+	}
+	return sb.String()
+}
+
+// fieldString 将字段值转换为字符串
+func fieldString(field zapcore.Field) string {
+	enc := zapcore.NewMapObjectEncoder()
+	field.AddTo(enc)
+	return fmt.Sprint(enc.Fields[field.Key])
+}
+
+// writeSummary 输出抑制统计记录，级别、模块与调用位置与被抑制的日志相同
+func writeSummary(counter *sampleCounter) {
+	ent := counter.ent
+	ent.Time = time.Now()
+	ent.Message = fmt.Sprintf("suppressed %s similar entries", formatCount(counter.suppressed))
+	ent.Stack = ""
+	_ = counter.core.Write(ent, []zapcore.Field{
+		zap.Int("pid", os.Getpid()),
+		zap.Int64("suppressed", counter.suppressed),
+	})
+}
+
+// formatCount 以千分位格式化数量，例如12,345
+func formatCount(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// samplingCore 在写入前进行采样的核心
+type samplingCore struct {
+	zapcore.Core
+	sampler *sampler
+}
+
+// newSamplingCore 使用包级别采样器包装核心
+func newSamplingCore(core zapcore.Core) zapcore.Core {
+	return &samplingCore{Core: core, sampler: defaultSampler}
+}
+
+// With 返回附加了上下文字段的核心
+func (c *samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplingCore{Core: c.Core.With(fields), sampler: c.sampler}
+}
+
+// Check 级别启用时将自身加入检查结果，采样在Write时根据调用位置与字段决定
+func (c *samplingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write 采样通过时写入被包装的核心
+func (c *samplingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.sampler.allow(c.Core, ent, fields) {
+		return nil
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// Sync 输出所有抑制统计后刷新被包装的核心
+func (c *samplingCore) Sync() error {
+	c.sampler.flush(true)
+	return c.Core.Sync()
+}
@@ -0,0 +1,127 @@
+package log_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/space-ark-x/infra-common/log"
+	"github.com/space-ark-x/infra-common/log/logtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampling(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     *log.SamplingConfig
+		module     string
+		level      log.Level
+		wantLogged int
+		wantSuffix string
+	}{
+		{
+			name:       "first then thereafter",
+			config:     &log.SamplingConfig{Tick: time.Hour, Levels: map[log.Level]log.SamplingRule{log.ErrorLevel: {First: 10, Thereafter: 100}}},
+			module:     "default",
+			level:      log.ErrorLevel,
+			wantLogged: 19,
+			wantSuffix: "suppressed 981 similar entries",
+		},
+		{
+			name:       "rate limit per call site",
+			config:     &log.SamplingConfig{Tick: time.Hour, Default: &log.SamplingRule{First: 5}},
+			module:     "default",
+			level:      log.InfoLevel,
+			wantLogged: 5,
+			wantSuffix: "suppressed 995 similar entries",
+		},
+		{
+			name:       "module rule overrides level rule",
+			config:     &log.SamplingConfig{Tick: time.Hour, Default: &log.SamplingRule{First: 1}, Modules: map[string]log.SamplingRule{"job": {First: 500}}},
+			module:     "job",
+			level:      log.WarnLevel,
+			wantLogged: 500,
+			wantSuffix: "suppressed 500 similar entries",
+		},
+		{
+			name:       "level without rule is not sampled",
+			config:     &log.SamplingConfig{Tick: time.Hour, Levels: map[log.Level]log.SamplingRule{log.DebugLevel: {First: 1}}},
+			module:     "default",
+			level:      log.ErrorLevel,
+			wantLogged: 1000,
+		},
+		{
+			name:       "fatal is never dropped",
+			config:     &log.SamplingConfig{Tick: time.Hour, Default: &log.SamplingRule{First: 1}},
+			module:     "default",
+			level:      log.FatalLevel,
+			wantLogged: 1000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := logtest.New(t)
+			log.SetSampling(tt.config)
+			defer log.SetSampling(nil)
+
+			logger := log.NewZapLoggerWithModule(tt.module)
+			write := map[log.Level]func(map[string]any) bool{
+				log.InfoLevel:  logger.Info,
+				log.WarnLevel:  logger.Warn,
+				log.ErrorLevel: logger.Error,
+				log.FatalLevel: logger.Fatal,
+			}[tt.level]
+			for i := 0; i < 1000; i++ {
+				write(map[string]any{"i": i})
+			}
+			assert.Equal(t, tt.wantLogged, rec.Len())
+
+			assert.NoError(t, log.Sync())
+			if tt.wantSuffix == "" {
+				assert.Equal(t, tt.wantLogged, rec.Len())
+				return
+			}
+			summary := rec.AssertMessage(t, tt.level, tt.wantSuffix)
+			assert.Equal(t, tt.module, summary.Module)
+		})
+	}
+}
+
+func TestSamplingKeyField(t *testing.T) {
+	rec := logtest.New(t)
+	log.SetSampling(&log.SamplingConfig{Tick: time.Hour, KeyField: "tenant", Default: &log.SamplingRule{First: 2}})
+	defer log.SetSampling(nil)
+
+	for i := 0; i < 10; i++ {
+		for _, tenant := range []string{"a", "b", "c"} {
+			log.Info(map[string]any{"tenant": tenant})
+		}
+	}
+	assert.Equal(t, 6, rec.Len())
+	for _, tenant := range []string{"a", "b", "c"} {
+		assert.Len(t, rec.Entries().Field("tenant", tenant), 2)
+	}
+}
+
+func TestSamplingPerCallSite(t *testing.T) {
+	rec := logtest.New(t)
+	log.SetSampling(&log.SamplingConfig{Tick: time.Hour, Default: &log.SamplingRule{First: 1}})
+	defer log.SetSampling(nil)
+
+	siteA := func() { log.Info(map[string]any{"site": "a"}) }
+	siteB := func() { log.Info(map[string]any{"site": "b"}) }
+	siteA()
+	siteA()
+	siteB()
+
+	entries := rec.Entries()
+	require.Len(t, entries, 2, "不同调用位置分别采样")
+	assert.Equal(t, "a", entries[0].Fields["site"])
+	assert.Equal(t, "b", entries[1].Fields["site"])
+	for _, entry := range entries {
+		assert.True(t, strings.HasPrefix(entry.Caller, "log/sampling_test.go:"), "包级别函数的调用位置为%s", entry.Caller)
+	}
+	assert.NotEqual(t, entries[0].Caller, entries[1].Caller)
+}
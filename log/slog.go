@@ -0,0 +1,320 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LevelFatal slog中表示致命错误的级别
+// slog没有内置的致命级别，NewSlogLogger以该级别记录Fatal日志
+const LevelFatal = slog.Level(12)
+
+// NewSlogHandler 创建由日志记录器支撑的slog.Handler
+// 传入ZapLogger时直接写入其zap核心，保留时间、调用者、级别与输出文件；
+// 其他Logger实现会将属性展开为map后调用对应级别的方法
+//
+//	slog.SetDefault(slog.New(log.NewSlogHandler(log.GetLogger())))
+func NewSlogHandler(logger Logger) slog.Handler {
+	switch l := logger.(type) {
+	case *ZapLogger:
+		return &zapSlogHandler{
+			logger: l.logger.WithOptions(zap.WithCaller(false)).With(zap.Int("pid", os.Getpid())),
+		}
+	case *slogLogger:
+		return l.handler
+	default:
+		return &mapSlogHandler{logger: logger}
+	}
+}
+
+// slogLevelToZap 将slog级别映射为zap级别
+// 高于Error的级别统一视为Error，避免通过slog触发进程退出
+func slogLevelToZap(level slog.Level) zapcore.Level {
+	switch {
+	case level >= slog.LevelError:
+		return zapcore.ErrorLevel
+	case level >= slog.LevelWarn:
+		return zapcore.WarnLevel
+	case level >= slog.LevelInfo:
+		return zapcore.InfoLevel
+	default:
+		return zapcore.DebugLevel
+	}
+}
+
+// zapSlogHandler 写入zap核心的slog.Handler
+type zapSlogHandler struct {
+	logger *zap.Logger
+	// groups 已打开但尚未写入字段的分组，没有属性的分组不输出
+	groups []string
+}
+
+// Enabled 判断级别是否启用
+func (h *zapSlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.Core().Enabled(slogLevelToZap(level))
+}
+
+// Handle 将slog记录写入zap核心
+func (h *zapSlogHandler) Handle(_ context.Context, r slog.Record) error {
+	ce := h.logger.Check(slogLevelToZap(r.Level), r.Message)
+	if ce == nil {
+		return nil
+	}
+	if !r.Time.IsZero() {
+		ce.Time = r.Time
+	}
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		ce.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+	}
+
+	fields := make([]zap.Field, 0, r.NumAttrs()+len(h.groups))
+	r.Attrs(func(a slog.Attr) bool {
+		fields = append(fields, attrToFields(a)...)
+		return true
+	})
+	if len(fields) > 0 {
+		fields = append(groupNamespaces(h.groups), fields...)
+	}
+	ce.Write(fields...)
+	return nil
+}
+
+// WithAttrs 返回附加了属性的Handler
+func (h *zapSlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make([]zap.Field, 0, len(attrs))
+	for _, a := range attrs {
+		fields = append(fields, attrToFields(a)...)
+	}
+	if len(fields) == 0 {
+		return h
+	}
+	fields = append(groupNamespaces(h.groups), fields...)
+	return &zapSlogHandler{logger: h.logger.With(fields...)}
+}
+
+// WithGroup 返回打开了分组的Handler
+func (h *zapSlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	groups := append(append([]string(nil), h.groups...), name)
+	return &zapSlogHandler{logger: h.logger, groups: groups}
+}
+
+// groupNamespaces 将分组转换为zap命名空间
+func groupNamespaces(groups []string) []zap.Field {
+	fields := make([]zap.Field, 0, len(groups))
+	for _, group := range groups {
+		fields = append(fields, zap.Namespace(group))
+	}
+	return fields
+}
+
+// attrToFields 将slog属性转换为zap字段
+// 遵循slog约定：忽略空属性和空分组，key为空的分组展开到上一级
+func attrToFields(a slog.Attr) []zap.Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return nil
+	}
+
+	v := a.Value
+	switch v.Kind() {
+	case slog.KindGroup:
+		attrs := v.Group()
+		if len(attrs) == 0 {
+			return nil
+		}
+		if a.Key == "" {
+			fields := make([]zap.Field, 0, len(attrs))
+			for _, attr := range attrs {
+				fields = append(fields, attrToFields(attr)...)
+			}
+			return fields
+		}
+		return []zap.Field{zap.Object(a.Key, slogGroup(attrs))}
+	case slog.KindString:
+		return []zap.Field{zap.String(a.Key, v.String())}
+	case slog.KindInt64:
+		return []zap.Field{zap.Int64(a.Key, v.Int64())}
+	case slog.KindUint64:
+		return []zap.Field{zap.Uint64(a.Key, v.Uint64())}
+	case slog.KindFloat64:
+		return []zap.Field{zap.Float64(a.Key, v.Float64())}
+	case slog.KindBool:
+		return []zap.Field{zap.Bool(a.Key, v.Bool())}
+	case slog.KindDuration:
+		return []zap.Field{zap.Duration(a.Key, v.Duration())}
+	case slog.KindTime:
+		return []zap.Field{zap.Time(a.Key, v.Time())}
+	default:
+		return []zap.Field{anyField(a.Key, v.Any())}
+	}
+}
+
+// slogGroup 将slog分组编码为JSON对象
+type slogGroup []slog.Attr
+
+// MarshalLogObject 实现zapcore.ObjectMarshaler
+func (g slogGroup) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, a := range g {
+		for _, field := range attrToFields(a) {
+			field.AddTo(enc)
+		}
+	}
+	return nil
+}
+
+// mapSlogHandler 将slog记录转换为map后写入任意Logger
+// 分组以"."连接展开为字段名
+type mapSlogHandler struct {
+	logger Logger
+	attrs  map[string]any
+	prefix string
+}
+
+// Enabled Logger接口没有级别判断，始终启用
+func (h *mapSlogHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle 将slog记录转换为map后写入Logger
+func (h *mapSlogHandler) Handle(_ context.Context, r slog.Record) error {
+	in := make(map[string]any, len(h.attrs)+r.NumAttrs()+1)
+	for k, v := range h.attrs {
+		in[k] = v
+	}
+	if r.Message != "" {
+		in["msg"] = r.Message
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		flattenAttr(in, h.prefix, a)
+		return true
+	})
+
+	switch slogLevelToZap(r.Level) {
+	case zapcore.DebugLevel:
+		h.logger.Debug(in)
+	case zapcore.InfoLevel:
+		h.logger.Info(in)
+	case zapcore.WarnLevel:
+		h.logger.Warn(in)
+	default:
+		h.logger.Error(in)
+	}
+	return nil
+}
+
+// WithAttrs 返回附加了属性的Handler
+func (h *mapSlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	merged := make(map[string]any, len(h.attrs)+len(attrs))
+	for k, v := range h.attrs {
+		merged[k] = v
+	}
+	for _, a := range attrs {
+		flattenAttr(merged, h.prefix, a)
+	}
+	return &mapSlogHandler{logger: h.logger, attrs: merged, prefix: h.prefix}
+}
+
+// WithGroup 返回打开了分组的Handler
+func (h *mapSlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &mapSlogHandler{logger: h.logger, attrs: h.attrs, prefix: h.prefix + name + "."}
+}
+
+// flattenAttr 将slog属性以带前缀的字段名写入map
+func flattenAttr(in map[string]any, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix = prefix + a.Key + "."
+		}
+		for _, attr := range a.Value.Group() {
+			flattenAttr(in, groupPrefix, attr)
+		}
+		return
+	}
+	in[prefix+a.Key] = a.Value.Any()
+}
+
+// slogLogger 基于slog.Handler实现的Logger
+type slogLogger struct {
+	handler slog.Handler
+}
+
+// NewSlogLogger 创建写入任意slog.Handler的Logger
+// map中的字段按key排序后作为属性写入，Fatal以LevelFatal记录后执行致命错误处理
+func NewSlogLogger(handler slog.Handler) Logger {
+	return &slogLogger{handler: handler}
+}
+
+// Debug 记录调试级别日志
+func (s *slogLogger) Debug(in map[string]any) bool {
+	return s.log(slog.LevelDebug, in)
+}
+
+// Info 记录信息级别日志
+func (s *slogLogger) Info(in map[string]any) bool {
+	return s.log(slog.LevelInfo, in)
+}
+
+// Warn 记录警告级别日志
+func (s *slogLogger) Warn(in map[string]any) bool {
+	return s.log(slog.LevelWarn, in)
+}
+
+// Error 记录错误级别日志
+func (s *slogLogger) Error(in map[string]any) bool {
+	return s.log(slog.LevelError, in)
+}
+
+// Fatal 记录致命错误日志，执行致命错误钩子并按退出行为终止程序
+func (s *slogLogger) Fatal(in map[string]any) bool {
+	s.log(LevelFatal, in)
+	handleFatal(in)
+	return true
+}
+
+// log 将map转换为slog记录并交给Handler处理
+func (s *slogLogger) log(level slog.Level, in map[string]any) bool {
+	ctx := context.Background()
+	if !s.handler.Enabled(ctx, level) {
+		return true
+	}
+
+	// 跳过runtime.Callers、log与对应级别的方法
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+
+	r := slog.NewRecord(time.Now(), level, "", pcs[0])
+	r.AddAttrs(slog.Int("pid", os.Getpid()))
+	for _, k := range sortedKeys(in) {
+		r.AddAttrs(slog.Any(k, in[k]))
+	}
+	return s.handler.Handle(ctx, r) == nil
+}
+
+// sortedKeys 返回按字典序排序的map key
+func sortedKeys(in map[string]any) []string {
+	keys := make([]string, 0, len(in))
+	for k := range in {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
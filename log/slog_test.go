@@ -0,0 +1,53 @@
+package log
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+type user struct{ name string }
+
+func (u user) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("name", u.name))
+}
+
+func TestSlogHandler(t *testing.T) {
+	zapLogger, logs := observedLogger(t)
+	logger := slog.New(NewSlogHandler(zapLogger))
+
+	logger.WithGroup("req").With("id", 7).Info("handled",
+		"user", user{name: "alice"},
+		slog.Group("", "inline", true),
+		slog.Group("empty"),
+	)
+	logger.WithGroup("unused").Debug("plain")
+	logger.Log(t.Context(), slog.LevelError+4, "above error")
+
+	entries := logs.AllUntimed()
+	assert.Len(t, entries, 3)
+	assert.Equal(t, "handled", entries[0].Message)
+	assert.Equal(t, zapcore.InfoLevel, entries[0].Level)
+	assert.Equal(t, map[string]any{
+		"id":     int64(7),
+		"user":   map[string]any{"name": "alice"},
+		"inline": true,
+	}, entries[0].ContextMap()["req"])
+
+	assert.Equal(t, zapcore.DebugLevel, entries[1].Level)
+	assert.NotContains(t, entries[1].ContextMap(), "unused")
+	assert.Equal(t, zapcore.ErrorLevel, entries[2].Level, "高于Error的级别不会触发退出")
+}
+
+func TestSlogLogger(t *testing.T) {
+	zapLogger, logs := observedLogger(t)
+	logger := NewSlogLogger(NewSlogHandler(zapLogger))
+
+	logger.Warn(map[string]any{"key": "value"})
+
+	entries := logs.FilterLevelExact(zapcore.WarnLevel).AllUntimed()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "value", entries[0].ContextMap()["key"])
+}
@@ -0,0 +1,75 @@
+package log
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// traceIdContextKey 上下文中追踪ID的key
+type traceIdContextKey struct{}
+
+// ContextWithTraceId 返回携带追踪ID的上下文
+func ContextWithTraceId(ctx context.Context, traceId string) context.Context {
+	return context.WithValue(ctx, traceIdContextKey{}, traceId)
+}
+
+// TraceIdFromContext 返回上下文中的追踪ID，不存在时返回空字符串
+func TraceIdFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	traceId, _ := ctx.Value(traceIdContextKey{}).(string)
+	return traceId
+}
+
+// FromContext 返回记录上下文中追踪ID的默认日志记录器
+// 日志带有traceId字段，可被StartBuffer开始的缓冲关联；上下文来自StartRequestBuffer时写入该请求的缓冲
+func FromContext(ctx context.Context) Logger {
+	return withRequestBuffer(ctx, WithTraceId(GetLogger(), TraceIdFromContext(ctx)))
+}
+
+// WithTraceId 返回每条日志都带有traceId字段的日志记录器，traceId为空时返回logger本身
+func WithTraceId(logger Logger, traceId string) Logger {
+	if traceId == "" {
+		return logger
+	}
+	if z, ok := logger.(*ZapLogger); ok {
+		return wrapZapLogger(z.logger.With(zap.String("traceId", traceId)))
+	}
+	return &traceLogger{Logger: logger, traceId: traceId}
+}
+
+// traceLogger 为非zap日志记录器的每条日志添加traceId字段
+type traceLogger struct {
+	Logger
+	traceId string
+}
+
+// with 返回添加了traceId字段的副本，已有traceId字段时保持不变
+func (t *traceLogger) with(in map[string]any) map[string]any {
+	if _, ok := in["traceId"]; ok {
+		return in
+	}
+	out := make(map[string]any, len(in)+1)
+	for k, v := range in {
+		out[k] = v
+	}
+	out["traceId"] = t.traceId
+	return out
+}
+
+// Debug 记录调试级别日志
+func (t *traceLogger) Debug(in map[string]any) bool { return t.Logger.Debug(t.with(in)) }
+
+// Info 记录信息级别日志
+func (t *traceLogger) Info(in map[string]any) bool { return t.Logger.Info(t.with(in)) }
+
+// Warn 记录警告级别日志
+func (t *traceLogger) Warn(in map[string]any) bool { return t.Logger.Warn(t.with(in)) }
+
+// Error 记录错误级别日志
+func (t *traceLogger) Error(in map[string]any) bool { return t.Logger.Error(t.with(in)) }
+
+// Fatal 记录致命错误日志
+func (t *traceLogger) Fatal(in map[string]any) bool { return t.Logger.Fatal(t.with(in)) }
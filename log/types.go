@@ -1,5 +1,84 @@
 package log
 
+import (
+	"reflect"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Level 日志级别
+type Level = zapcore.Level
+
+const (
+	DebugLevel = zapcore.DebugLevel // 调试
+	InfoLevel  = zapcore.InfoLevel  // 信息
+	WarnLevel  = zapcore.WarnLevel  // 警告
+	ErrorLevel = zapcore.ErrorLevel // 错误
+	FatalLevel = zapcore.FatalLevel // 致命错误
+)
+
+// Entry 一条已记录的日志
+type Entry struct {
+	Time    time.Time      `json:"time"`    // 记录时间
+	Level   Level          `json:"level"`   // 日志级别
+	Module  string         `json:"module"`  // 模块名
+	Message string         `json:"message"` // 日志消息，map形式的日志为空
+	Caller  string         `json:"caller"`  // 调用位置
+	Fields  map[string]any `json:"fields"`  // 日志字段
+}
+
+// NewEntry 将zap日志条目及其字段转换为Entry
+func NewEntry(ent zapcore.Entry, fields []zapcore.Field) Entry {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+	entry := Entry{
+		Time:    ent.Time,
+		Level:   ent.Level,
+		Module:  ent.LoggerName,
+		Message: ent.Message,
+		Fields:  enc.Fields,
+	}
+	if ent.Caller.Defined {
+		entry.Caller = ent.Caller.TrimmedPath()
+	}
+	return entry
+}
+
+// FieldEqual 比较记录的字段值与期望值，用于钩子条件与logtest的断言
+// 数值按数值比较，期望值为error时与结构化错误的message比较
+func FieldEqual(got, want any) bool {
+	if reflect.DeepEqual(got, want) {
+		return true
+	}
+	if err, ok := want.(error); ok {
+		if obj, ok := got.(map[string]any); ok {
+			return obj["message"] == err.Error()
+		}
+		return false
+	}
+	gf, gok := toFloat(got)
+	wf, wok := toFloat(want)
+	return gok && wok && gf == wf
+}
+
+// toFloat 将数值类型转换为float64
+func toFloat(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
+
 // LoggerConfig 日志配置结构体
 type LoggerConfig struct{}
 
@@ -18,5 +97,6 @@ type Logger interface {
 	Error(in map[string]any) bool
 
 	// Fatal 记录致命错误日志并终止程序
+	// 终止前执行已注册的致命错误钩子，退出方式由SetExitBehavior决定
 	Fatal(in map[string]any) bool
 }
@@ -0,0 +1,282 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WebhookConfig 告警Webhook配置，零值字段使用默认值
+type WebhookConfig struct {
+	URL           string        // 接收告警的地址
+	BatchSize     int           // 单次发送的最大告警数，默认100
+	FlushInterval time.Duration // 定期发送的间隔，默认5秒
+	MinInterval   time.Duration // 两次发送之间的最小间隔，用于节流，默认1秒
+	MaxPending    int           // 等待发送的不同告警上限，超出后丢弃并计数，默认1000
+	MaxRetries    int           // 发送失败后的重试次数，默认3
+	RetryBackoff  time.Duration // 首次重试的等待时间，之后每次翻倍，默认500毫秒
+	Timeout       time.Duration // 单次请求超时，默认5秒
+	Client        *http.Client  // 自定义HTTP客户端
+}
+
+// withDefaults 填充默认值
+func (c WebhookConfig) withDefaults() WebhookConfig {
+	if c.BatchSize <= 0 {
+		c.BatchSize = 100
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = 5 * time.Second
+	}
+	if c.MinInterval <= 0 {
+		c.MinInterval = time.Second
+	}
+	if c.MaxPending <= 0 {
+		c.MaxPending = 1000
+	}
+	if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	} else if c.MaxRetries == 0 {
+		c.MaxRetries = 3
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = 500 * time.Millisecond
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 5 * time.Second
+	}
+	if c.Client == nil {
+		c.Client = &http.Client{}
+	}
+	return c
+}
+
+// Alert 去重合并后的告警
+type Alert struct {
+	Level     string         `json:"level"`     // 日志级别
+	Module    string         `json:"module"`    // 模块名
+	Caller    string         `json:"caller"`    // 调用位置
+	Message   string         `json:"message"`   // 日志消息
+	Fields    map[string]any `json:"fields"`    // 第一条日志的字段
+	Count     int            `json:"count"`     // 合并的日志数量
+	FirstSeen time.Time      `json:"firstSeen"` // 第一条日志的时间
+	LastSeen  time.Time      `json:"lastSeen"`  // 最后一条日志的时间
+}
+
+// WebhookPayload 发送给Webhook的请求体
+type WebhookPayload struct {
+	Alerts  []*Alert `json:"alerts"`  // 本批告警
+	Dropped int64    `json:"dropped"` // 自上次发送以来因超出上限或发送失败丢弃的日志数
+}
+
+// WebhookHook 批量、去重并节流地将告警POST到Webhook的钩子
+type WebhookHook struct {
+	config   WebhookConfig
+	mu       sync.Mutex
+	pending  map[string]*Alert
+	order    []string
+	dropped  int64
+	sendMu   sync.Mutex
+	lastSend time.Time
+	notify   chan struct{}
+	done     chan struct{}
+	closed   sync.Once
+}
+
+// NewWebhookHook 创建Webhook钩子并启动后台发送，需通过RegisterHook注册
+//
+//	log.RegisterHook(log.HookMatcher{}, log.NewWebhookHook(log.WebhookConfig{URL: url}))
+func NewWebhookHook(config WebhookConfig) *WebhookHook {
+	w := &WebhookHook{
+		config:  config.withDefaults(),
+		pending: make(map[string]*Alert),
+		notify:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Fire 将日志合并到待发送的告警中
+// 相同级别、模块、调用位置、消息与错误信息的日志合并为一条告警并计数
+func (w *WebhookHook) Fire(entry Entry) {
+	key := alertKey(entry)
+
+	w.mu.Lock()
+	if alert, ok := w.pending[key]; ok {
+		alert.Count++
+		alert.LastSeen = entry.Time
+		w.mu.Unlock()
+		return
+	}
+	if len(w.pending) >= w.config.MaxPending {
+		w.dropped++
+		w.mu.Unlock()
+		return
+	}
+	w.pending[key] = &Alert{
+		Level:     entry.Level.String(),
+		Module:    entry.Module,
+		Caller:    entry.Caller,
+		Message:   entry.Message,
+		Fields:    entry.Fields,
+		Count:     1,
+		FirstSeen: entry.Time,
+		LastSeen:  entry.Time,
+	}
+	w.order = append(w.order, key)
+	full := len(w.pending) >= w.config.BatchSize
+	w.mu.Unlock()
+
+	if full {
+		select {
+		case w.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Sync 发送所有待发送的告警，距上次发送不足最小间隔时留给后台发送
+// 致命错误处理期间不受最小间隔限制，发送与重试受致命错误钩子的超时时间限制
+func (w *WebhookHook) Sync() error {
+	return w.send(flushContext(), !fatalFlushing(), true)
+}
+
+// Close 停止后台发送并发送剩余告警，不受最小间隔限制
+func (w *WebhookHook) Close() error {
+	w.closed.Do(func() {
+		close(w.done)
+	})
+	return w.send(flushContext(), false, true)
+}
+
+// run 定期或在告警达到批量大小时发送
+func (w *WebhookHook) run() {
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		case <-w.notify:
+		}
+		_ = w.send(context.Background(), true, false)
+	}
+}
+
+// send 发送待发送的告警，throttle为true时距上次发送不足最小间隔则不发送，all为false时只发送一批
+func (w *WebhookHook) send(ctx context.Context, throttle, all bool) error {
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+
+	if throttle && time.Since(w.lastSend) < w.config.MinInterval {
+		return nil
+	}
+	for {
+		payload := w.takeBatch()
+		if payload == nil {
+			return nil
+		}
+
+		w.lastSend = time.Now()
+		body, err := json.Marshal(payload)
+		if err == nil {
+			err = postWithRetry(ctx, w.config.Client, w.config.URL, body, w.config.Timeout, w.config.MaxRetries, w.config.RetryBackoff)
+		}
+		if err != nil {
+			w.mu.Lock()
+			w.dropped += payload.Dropped
+			for _, alert := range payload.Alerts {
+				w.dropped += int64(alert.Count)
+			}
+			w.mu.Unlock()
+			return err
+		}
+		if !all {
+			return nil
+		}
+	}
+}
+
+// takeBatch 取出一批待发送的告警，没有告警时返回nil
+func (w *WebhookHook) takeBatch() *WebhookPayload {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.order) == 0 {
+		return nil
+	}
+	n := min(len(w.order), w.config.BatchSize)
+	payload := &WebhookPayload{Alerts: make([]*Alert, 0, n), Dropped: w.dropped}
+	for _, key := range w.order[:n] {
+		payload.Alerts = append(payload.Alerts, w.pending[key])
+		delete(w.pending, key)
+	}
+	w.order = append([]string(nil), w.order[n:]...)
+	w.dropped = 0
+	return payload
+}
+
+// alertKey 计算告警的去重key
+func alertKey(entry Entry) string {
+	errMsg := ""
+	switch e := entry.Fields["error"].(type) {
+	case map[string]any:
+		errMsg = fmt.Sprint(e["message"])
+	case nil:
+	default:
+		errMsg = fmt.Sprint(e)
+	}
+	return entry.Level.String() + "|" + entry.Module + "|" + entry.Caller + "|" + entry.Message + "|" + errMsg
+}
+
+// postWithRetry 以JSON格式POST请求体，网络错误、429与5xx响应按指数退避重试
+// ctx取消后不再重试
+func postWithRetry(ctx context.Context, client *http.Client, url string, body []byte, timeout time.Duration, maxRetries int, backoff time.Duration) error {
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return lastErr
+			case <-time.After(backoff << (attempt - 1)):
+			}
+		}
+		retry, err := post(ctx, client, url, body, timeout)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return lastErr
+}
+
+// post 发送一次请求，返回失败时是否可以重试
+func post(ctx context.Context, client *http.Client, url string, body []byte, timeout time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("post %s: unexpected status %s", url, resp.Status)
+}
@@ -1,11 +1,14 @@
 package log
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/space-ark-x/infra-common/config"
@@ -16,15 +19,60 @@ import (
 var (
 	// defaultLogger 是包级别的默认日志记录器
 	defaultLogger Logger
-	// once 用于确保默认日志记录器只初始化一次
-	once sync.Once
+	// loggerMu 保护默认日志记录器与替换核心
+	loggerMu sync.RWMutex
+	// overrideCore 替换核心，设置后日志记录器不再创建日志文件
+	overrideCore zapcore.Core
+	// settingsOnce 用于确保日志设置只从配置加载一次
+	settingsOnce sync.Once
 	// enableConsole 控制是否启用控制台输出
-	enableConsole = getConsoleOutputFromEnv()
+	enableConsole bool
+	// consoleFormat 控制台输出格式
+	consoleFormat string
+	// fileFormat 日志文件格式，JSON或OTLP
+	fileFormat string
+	// otlpExporter 配置了LOG_OTLP_ENDPOINT时所有日志文件同时发送到collector
+	otlpExporter *OTLPExporter
+	// enableErrorStack 控制Error级别日志是否附加调用栈
+	enableErrorStack atomic.Bool
 )
 
+// loadSettings 在首次使用时从配置加载日志设置，导入包时不读取配置
+func loadSettings() {
+	settingsOnce.Do(func() {
+		enableConsole = getConsoleOutputFromEnv()
+		consoleFormat = getConsoleFormatFromEnv()
+		fileFormat = getFileFormatFromEnv()
+		otlpExporter = getOTLPExporterFromEnv()
+		enableErrorStack.Store(getErrorStackFromEnv())
+		defaultSampler.set(getSamplingFromEnv())
+		defaultBuffers.setConfig(getRequestBufferFromEnv())
+		slowThreshold.Store(int64(getSlowThresholdFromEnv()))
+		registerWebhookFromEnv()
+	})
+}
+
+// configErrOnce 配置文件无法加载的提示只输出一次
+var configErrOnce sync.Once
+
+// configValue 读取日志配置项，没有配置文件时仅读取环境变量
+// 配置文件存在但无法读取或解析时同样回退为环境变量，并在标准错误输出一次原因
+func configValue(record string, defaultValue string) string {
+	value, err := config.Lookup(record, defaultValue)
+	if err == nil {
+		return value
+	}
+	if !errors.Is(err, config.ErrNoEnv) {
+		configErrOnce.Do(func() {
+			fmt.Fprintf(os.Stderr, "log: %v, using environment variables\n", err)
+		})
+	}
+	return defaultValue
+}
+
 // getConsoleOutputFromEnv 从环境变量获取控制台输出设置
 func getConsoleOutputFromEnv() bool {
-	envValue := config.LoadConfig().Get("LOG", "true")
+	envValue := configValue("LOG", "true")
 	if envValue == "" {
 		return true // 默认启用控制台输出
 	}
@@ -38,84 +86,270 @@ func getConsoleOutputFromEnv() bool {
 	return consoleEnabled
 }
 
+// getConsoleFormatFromEnv 从环境变量获取控制台输出格式
+// 标准输出不是终端时回退为JSON格式
+func getConsoleFormatFromEnv() string {
+	format := configValue("LOG_FORMAT", FormatJSON)
+	if format != FormatConsole || !isTerminal(os.Stdout) {
+		return FormatJSON
+	}
+	return FormatConsole
+}
+
+// getFileFormatFromEnv 从环境变量LOG_FILE_FORMAT获取日志文件格式，默认JSON
+func getFileFormatFromEnv() string {
+	if configValue("LOG_FILE_FORMAT", FormatJSON) == FormatOTLP {
+		return FormatOTLP
+	}
+	return FormatJSON
+}
+
+// getErrorStackFromEnv 从环境变量获取Error级别调用栈设置，默认关闭
+func getErrorStackFromEnv() bool {
+	enabled, err := strconv.ParseBool(configValue("LOG_ERROR_STACK", "false"))
+	if err != nil {
+		return false
+	}
+	return enabled
+}
+
+// registerWebhookFromEnv 配置了LOG_ALERT_WEBHOOK时注册告警Webhook
+// LOG_ALERT_LEVELS 为逗号分隔的触发级别，默认error,fatal
+func registerWebhookFromEnv() {
+	url := configValue("LOG_ALERT_WEBHOOK", "")
+	if url == "" {
+		return
+	}
+	matcher := HookMatcher{}
+	for _, name := range strings.Split(configValue("LOG_ALERT_LEVELS", "error,fatal"), ",") {
+		var level Level
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(name))); err == nil {
+			matcher.Levels = append(matcher.Levels, level)
+		}
+	}
+	RegisterHook(matcher, NewWebhookHook(WebhookConfig{URL: url}))
+}
+
 // GetLogger 获取默认的日志记录器实例
 // 外部可以直接调用此函数进行简单日志记录
 func GetLogger() Logger {
-	once.Do(func() {
-		defaultLogger = NewZapLogger()
-	})
+	loggerMu.RLock()
+	logger := defaultLogger
+	loggerMu.RUnlock()
+	if logger != nil {
+		return logger
+	}
+
+	loggerMu.Lock()
+	defer loggerMu.Unlock()
+	if defaultLogger == nil {
+		defaultLogger = newZapLogger("default", consoleEnabled(), overrideCore)
+	}
 	return defaultLogger
 }
 
+// SetLogger 替换默认的日志记录器，返回恢复原日志记录器的函数
+func SetLogger(logger Logger) (restore func()) {
+	loggerMu.Lock()
+	prev := defaultLogger
+	defaultLogger = logger
+	loggerMu.Unlock()
+
+	return func() {
+		loggerMu.Lock()
+		defaultLogger = prev
+		loggerMu.Unlock()
+	}
+}
+
+// UseCore 将日志写入指定的zap核心，不再创建日志目录和文件
+// 影响默认日志记录器与之后创建的日志记录器，返回恢复函数
+func UseCore(core zapcore.Core) (restore func()) {
+	loggerMu.Lock()
+	prevCore, prevLogger := overrideCore, defaultLogger
+	overrideCore = core
+	defaultLogger = newZapLogger("default", false, core)
+	loggerMu.Unlock()
+
+	return func() {
+		loggerMu.Lock()
+		overrideCore, defaultLogger = prevCore, prevLogger
+		loggerMu.Unlock()
+	}
+}
+
+// consoleEnabled 返回当前是否启用控制台输出
+func consoleEnabled() bool {
+	loadSettings()
+	return enableConsole
+}
+
 // EnableConsoleOutput 启用或禁用控制台输出
 func EnableConsoleOutput(enable bool) {
+	loadSettings()
 	enableConsole = enable
 }
 
+// SetConsoleFormat 设置控制台输出格式，仅影响之后创建的日志记录器
+// 标准输出不是终端时console格式会回退为JSON格式
+func SetConsoleFormat(format string) {
+	loadSettings()
+	if format != FormatConsole || !isTerminal(os.Stdout) {
+		consoleFormat = FormatJSON
+		return
+	}
+	consoleFormat = FormatConsole
+}
+
+// EnableErrorStack 启用或禁用Error级别日志的调用栈
+// 字段中的错误已携带调用栈时不会重复记录
+func EnableErrorStack(enable bool) {
+	loadSettings()
+	enableErrorStack.Store(enable)
+}
+
+// packageLogger 返回包级别函数使用的默认日志记录器，调用位置为包级别函数的调用者
+func packageLogger() Logger {
+	logger := GetLogger()
+	if z, ok := logger.(*ZapLogger); ok {
+		return z.packageLogger()
+	}
+	return logger
+}
+
 // Debug 记录调试级别日志
 func Debug(in map[string]any) bool {
-	return GetLogger().Debug(in)
+	return packageLogger().Debug(in)
 }
 
 // Info 记录信息级别日志
 func Info(in map[string]any) bool {
-	return GetLogger().Info(in)
+	return packageLogger().Info(in)
 }
 
 // Warn 记录警告级别日志
 func Warn(in map[string]any) bool {
-	return GetLogger().Warn(in)
+	return packageLogger().Warn(in)
 }
 
 // Error 记录错误级别日志
 func Error(in map[string]any) bool {
-	return GetLogger().Error(in)
+	return packageLogger().Error(in)
 }
 
 // Fatal 记录致命错误日志并终止程序
+// 终止前会执行RegisterFatalHook注册的钩子并刷新所有日志
 func Fatal(in map[string]any) bool {
-	return GetLogger().Fatal(in)
+	return packageLogger().Fatal(in)
 }
 
 type ZapLogger struct {
 	logger *zap.Logger
+	// skip 方法与实际调用位置之间额外的调用层数，包级别函数使用的记录器为1
+	skip int
+	// pkg 包级别函数使用的记录器，调用位置多跳过一层
+	pkg *ZapLogger
+}
+
+// wrapZapLogger 创建ZapLogger及包级别函数使用的记录器
+func wrapZapLogger(logger *zap.Logger) *ZapLogger {
+	return &ZapLogger{
+		logger: logger,
+		pkg:    &ZapLogger{logger: logger.WithOptions(zap.AddCallerSkip(1)), skip: 1},
+	}
+}
+
+// packageLogger 返回包级别函数使用的记录器
+func (z *ZapLogger) packageLogger() *ZapLogger {
+	if z.pkg != nil {
+		return z.pkg
+	}
+	return wrapZapLogger(z.logger).pkg
 }
 
 // NewZapLogger 创建一个新的ZapLogger实例
 // 日志将根据时间戳写入./log/目录下
 func NewZapLogger() Logger {
-	return NewZapLoggerWithConfig("default", enableConsole)
+	return NewZapLoggerWithConfig("default", consoleEnabled())
 }
 
 // NewZapLoggerWithModule 创建一个带模块名的ZapLogger实例
 // moduleName 用于标识日志来源模块
 func NewZapLoggerWithModule(moduleName string) Logger {
-	return NewZapLoggerWithConfig(moduleName, enableConsole)
+	return NewZapLoggerWithConfig(moduleName, consoleEnabled())
 }
 
 // NewZapLoggerWithConfig 创建一个自定义配置的ZapLogger实例
+// 通过UseCore设置了替换核心时，日志写入替换核心而不创建文件
 func NewZapLoggerWithConfig(moduleName string, consoleOutput bool) Logger {
-	// 确保log目录存在
-	logDir := "log"
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		panic(fmt.Sprintf("failed to create log directory: %v", err))
+	loggerMu.RLock()
+	core := overrideCore
+	loggerMu.RUnlock()
+	return newZapLogger(moduleName, consoleOutput, core)
+}
+
+// NewZapLoggerWithCore 创建一个写入指定zap核心的ZapLogger实例
+func NewZapLoggerWithCore(moduleName string, core zapcore.Core) Logger {
+	return newZapLogger(moduleName, false, core)
+}
+
+// newZapLogger 创建ZapLogger，core为nil时创建日志文件核心
+func newZapLogger(moduleName string, consoleOutput bool, core zapcore.Core) Logger {
+	loadSettings()
+	if core == nil {
+		core = newFileCore(moduleName, consoleOutput)
 	}
+	// 采样配置由所有日志记录器共享，可通过SetSampling随时调整
+	// 钩子在采样之前触发，能看到所有日志；请求缓冲的日志在写入时才参与采样
+	core = newHookCore(newBufferCore(newSamplingCore(core)))
 
-	// 生成基于时间戳的文件名
-	timestamp := time.Now().Format("2006-01-02")
-	var filename string
+	// 创建zap logger并添加调用者信息，模块名作为logger名称
+	// 致命错误通过fatalWriteHook执行钩子、刷新缓冲后再退出
+	logger := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1), zap.WithFatalHook(fatalWriteHook{})).Named(moduleName)
+	register(moduleName, logger)
+
+	return wrapZapLogger(logger)
+}
+
+// LogDir 日志文件所在目录
+const LogDir = "log"
+
+// FileName 返回模块在指定日期的日志文件路径
+// 默认模块为log/log_<日期>.log，其他模块为log/log_<日期>_<模块>.log
+func FileName(moduleName string, t time.Time) string {
+	timestamp := t.Format("2006-01-02")
 	if moduleName == "default" {
-		filename = filepath.Join(logDir, fmt.Sprintf("log_%s.log", timestamp))
-	} else {
-		filename = filepath.Join(logDir, fmt.Sprintf("log_%s_%s.log", timestamp, moduleName))
+		return filepath.Join(LogDir, fmt.Sprintf("log_%s.log", timestamp))
 	}
+	return filepath.Join(LogDir, fmt.Sprintf("log_%s_%s.log", timestamp, moduleName))
+}
 
-	// 配置zap日志
+// NewEncoderConfig 返回日志文件使用的JSON编码配置
+// 文件名已包含模块名，文件中的每行不再记录logger字段
+func NewEncoderConfig() zapcore.EncoderConfig {
 	encoderConfig := zap.NewProductionEncoderConfig()
 	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	encoderConfig.NameKey = zapcore.OmitKey
+	return encoderConfig
+}
 
-	// 创建JSON编码器
-	encoder := zapcore.NewJSONEncoder(encoderConfig)
+// newFileCore 创建写入./log/目录的核心，可同时输出到控制台
+func newFileCore(moduleName string, consoleOutput bool) zapcore.Core {
+	loadSettings()
+
+	// 确保log目录存在
+	if err := os.MkdirAll(LogDir, 0755); err != nil {
+		panic(fmt.Sprintf("failed to create log directory: %v", err))
+	}
+
+	// 生成基于时间戳的文件名
+	filename := FileName(moduleName, time.Now())
+
+	// 创建JSON编码器，LOG_FILE_FORMAT为otlp时使用OTLP编码器
+	encoder := zapcore.NewJSONEncoder(NewEncoderConfig())
+	if fileFormat == FormatOTLP {
+		encoder = NewOTLPEncoder()
+	}
 
 	// 创建文件写入器
 	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
@@ -127,24 +361,23 @@ func NewZapLoggerWithConfig(moduleName string, consoleOutput bool) Logger {
 	fileWriteSyncer := zapcore.AddSync(file)
 
 	// 创建核心
-	var core zapcore.Core
+	core := zapcore.NewCore(encoder, fileWriteSyncer, zapcore.DebugLevel)
 	if consoleOutput {
-		// 同时输出到控制台和文件
+		// 同时输出到控制台，控制台可使用独立的编码器
+		// 多个模块共用标准输出，控制台的JSON输出保留logger字段
+		consoleConfig := NewEncoderConfig()
+		consoleConfig.NameKey = "logger"
+		consoleEncoder := zapcore.NewJSONEncoder(consoleConfig)
+		if consoleFormat == FormatConsole {
+			consoleEncoder = newConsoleEncoder(true)
+		}
 		consoleWriteSyncer := zapcore.AddSync(os.Stdout)
-		// 创建多写入器
-		multiWriteSyncer := zapcore.NewMultiWriteSyncer(fileWriteSyncer, consoleWriteSyncer)
-		core = zapcore.NewCore(encoder, multiWriteSyncer, zapcore.DebugLevel)
-	} else {
-		// 只输出到文件
-		core = zapcore.NewCore(encoder, fileWriteSyncer, zapcore.DebugLevel)
+		core = zapcore.NewTee(core, zapcore.NewCore(consoleEncoder, consoleWriteSyncer, zapcore.DebugLevel))
 	}
-
-	// 创建zap logger并添加调用者信息
-	logger := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1))
-
-	return &ZapLogger{
-		logger: logger,
+	if otlpExporter != nil {
+		core = zapcore.NewTee(core, otlpExporter.Core(zapcore.DebugLevel))
 	}
+	return core
 }
 
 // Debug 记录调试级别日志
@@ -171,15 +404,35 @@ func (z *ZapLogger) Warn(in map[string]any) bool {
 // Error 记录错误级别日志
 func (z *ZapLogger) Error(in map[string]any) bool {
 	fields := mapToFields(in)
+	loadSettings()
+	if enableErrorStack.Load() && !hasErrorStack(in) {
+		fields = append(fields, zap.StackSkip("stacktrace", 1+z.skip))
+	}
 	z.logger.Error("", fields...)
 	return true
 }
 
-// Fatal 记录致命错误日志并终止程序
+// Fatal 记录致命错误日志，执行致命错误钩子并按退出行为终止程序
+// 退出行为为ExitNoop时返回true
 func (z *ZapLogger) Fatal(in map[string]any) bool {
 	fields := mapToFields(in)
 	z.logger.Fatal("", fields...)
-	panic(fmt.Sprintf("fatal error: %v", in))
+	return true
+}
+
+// writeAt 以指定的调用位置记录日志，用于在其他位置结束的操作日志
+func (z *ZapLogger) writeAt(level Level, caller zapcore.EntryCaller, in map[string]any) {
+	fields := mapToFields(in)
+	if level >= ErrorLevel {
+		loadSettings()
+		if enableErrorStack.Load() && !hasErrorStack(in) {
+			fields = append(fields, zap.StackSkip("stacktrace", 1))
+		}
+	}
+	if ce := z.logger.Check(level, ""); ce != nil {
+		ce.Caller = caller
+		ce.Write(fields...)
+	}
 }
 
 // mapToFields 将map转换为zap字段
@@ -192,7 +445,16 @@ func mapToFields(data map[string]any) []zap.Field {
 
 	// 添加用户提供的字段
 	for k, v := range data {
-		fields = append(fields, zap.Any(k, v))
+		fields = append(fields, anyField(k, v))
 	}
 	return fields
 }
+
+// anyField 将任意值转换为zap字段，错误值展开为结构化对象
+// nil指针类型的错误与zap相同记录为"<nil>"
+func anyField(key string, value any) zap.Field {
+	if err, ok := value.(error); ok && !isNilError(err) {
+		return errorField(key, err)
+	}
+	return zap.Any(key, value)
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"github.com/kataras/iris/v12"
+	"github.com/space-ark-x/infra-common/log"
+)
+
+// NewLogBufferMiddleware 缓冲请求的Debug与Info日志，需注册在NewTraceIdMiddleware之后
+// 请求以5xx结束或发生panic时写入缓冲的日志，否则丢弃；请求中的Error日志会立即写入之前缓冲的日志
+// 每个请求单独缓冲，X-Trace-Id相同的并发请求互不影响；处理函数中应通过log.FromContext(ctx.Request().Context())记录日志
+func NewLogBufferMiddleware() iris.Handler {
+	return func(ctx iris.Context) {
+		reqCtx, buf := log.StartRequestBuffer(ctx.Request().Context())
+		ctx.ResetRequest(ctx.Request().WithContext(reqCtx))
+		defer func() {
+			if r := recover(); r != nil {
+				buf.Flush()
+				panic(r)
+			}
+			if ctx.GetStatusCode() >= iris.StatusInternalServerError {
+				buf.Flush()
+			} else {
+				buf.Discard()
+			}
+		}()
+		ctx.Next()
+	}
+}
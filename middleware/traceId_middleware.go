@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"github.com/kataras/iris/v12"
+	"github.com/space-ark-x/infra-common/log"
 	"github.com/space-ark-x/infra-common/utils"
 )
 
@@ -13,6 +14,8 @@ func NewTraceIdMiddleware() iris.Handler {
 			traceId = utils.GenUUID()
 		}
 		ctx.Values().Set("traceId", traceId)
+		// 同时写入请求上下文，log.Start等通过ctx读取追踪ID
+		ctx.ResetRequest(ctx.Request().WithContext(log.ContextWithTraceId(ctx.Request().Context(), traceId)))
 		ctx.Next()
 		ctx.Header("X-Trace-Id", traceId)
 	}
@@ -0,0 +1,133 @@
+package mongo
+
+import (
+	"context"
+	"slices"
+	"strconv"
+
+	"github.com/space-ark-x/infra-common/dto"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// aggregateOperators 聚合函数对应的$group累加器
+var aggregateOperators = map[dto.AggFunc]string{
+	dto.AggSum: "$sum",
+	dto.AggAvg: "$avg",
+	dto.AggMin: "$min",
+	dto.AggMax: "$max",
+}
+
+// AggregatePipeline 按schema校验分组聚合请求并生成聚合管道：$match、$group、$sort、$skip与$limit
+// qb中已有的条件与查询请求的条件一起写入$match，校验失败时返回dto.ValidationErrors
+// $group中分组字段命名为_id.g0、_id.g1，聚合命名为a0、a1，与qr.GroupBy、qr.Aggregations()的顺序对应
+//
+//	qr, err := dto.ParseQueryRequest("status=paid&group_by=city&agg=count,sum(amount)&sort=count.desc")
+//	...
+//	pipeline, err := mongo.AggregatePipeline(mongo.NewQueryBuilder(client, "app"), qr, orderSchema)
+func AggregatePipeline(qb *QueryBuilder, qr *dto.QueryRequest, schema *dto.QuerySchema) (mongo.Pipeline, error) {
+	if err := qr.Validate(schema); err != nil {
+		return nil, err
+	}
+	filter, err := exprFilter(qr.Expr(), schema)
+	if err != nil {
+		return nil, err
+	}
+	qb.addFilter(filter)
+
+	var pipeline mongo.Pipeline
+	if len(qb.filter) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: qb.filter}})
+	}
+
+	var id any
+	if len(qr.GroupBy) > 0 {
+		keys := make(bson.D, 0, len(qr.GroupBy))
+		for i, key := range qr.GroupBy {
+			keys = append(keys, bson.E{Key: "g" + strconv.Itoa(i), Value: "$" + schema.Path(key)})
+		}
+		id = keys
+	}
+	group := bson.D{{Key: "_id", Value: id}}
+	aggs := qr.Aggregations()
+	for i, agg := range aggs {
+		var accumulator bson.D
+		if agg.Func == dto.AggCount {
+			accumulator = bson.D{{Key: "$sum", Value: 1}}
+		} else {
+			accumulator = bson.D{{Key: aggregateOperators[agg.Func], Value: "$" + schema.Path(agg.Field)}}
+		}
+		group = append(group, bson.E{Key: "a" + strconv.Itoa(i), Value: accumulator})
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$group", Value: group}})
+
+	// 分页时追加_id作为排序的最后一个字段，保证结果顺序稳定
+	if len(qr.Order) > 0 || qr.PageSize > 0 {
+		sort := make(bson.D, 0, len(qr.Order)+1)
+		for _, order := range qr.Order {
+			direction := 1
+			if order.Direction == dto.Descending {
+				direction = -1
+			}
+			sort = append(sort, bson.E{Key: aggregateSortKey(qr, aggs, order.Key), Value: direction})
+		}
+		pipeline = append(pipeline, bson.D{{Key: "$sort", Value: append(sort, bson.E{Key: "_id", Value: 1})}})
+	}
+	if qr.PageSize > 0 {
+		pipeline = append(pipeline,
+			bson.D{{Key: "$skip", Value: int64(qr.Offset())}},
+			bson.D{{Key: "$limit", Value: int64(qr.PageSize)}},
+		)
+	}
+	return pipeline, nil
+}
+
+// aggregateSortKey 返回排序字段在$group结果中的字段名，key已通过校验
+func aggregateSortKey(qr *dto.QueryRequest, aggs []dto.Aggregation, key string) string {
+	if i := slices.Index(qr.GroupBy, key); i >= 0 {
+		return "_id.g" + strconv.Itoa(i)
+	}
+	i := slices.IndexFunc(aggs, func(agg dto.Aggregation) bool { return agg.String() == key })
+	return "a" + strconv.Itoa(i)
+}
+
+// Aggregate 执行分组聚合查询，qb提供连接与已有的条件
+//
+//	qr, err := dto.ParseQueryRequest(ctx.Request().URL.RawQuery)
+//	...
+//	resp, err := mongo.Aggregate(mongo.NewQueryBuilder(client, "app"), "orders", qr, orderSchema)
+func Aggregate(qb *QueryBuilder, collection string, qr *dto.QueryRequest, schema *dto.QuerySchema) (dto.AggregateResponse, error) {
+	pipeline, err := AggregatePipeline(qb, qr, schema)
+	if err != nil {
+		return dto.AggregateResponse{}, err
+	}
+
+	ctx := context.Background()
+	coll := qb.client.Database(qb.database).Collection(collection)
+	cursor, err := coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return dto.AggregateResponse{}, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []bson.M
+	if err = cursor.All(ctx, &rows); err != nil {
+		return dto.AggregateResponse{}, err
+	}
+	aggs := qr.Aggregations()
+	resp := dto.AggregateResponse{Buckets: make([]dto.AggregateBucket, 0, len(rows))}
+	for _, row := range rows {
+		keys := make([]any, len(qr.GroupBy))
+		if id, ok := row["_id"].(bson.M); ok {
+			for i := range keys {
+				keys[i] = id["g"+strconv.Itoa(i)]
+			}
+		}
+		values := make([]any, len(aggs))
+		for i := range values {
+			values[i] = row["a"+strconv.Itoa(i)]
+		}
+		resp.Buckets = append(resp.Buckets, dto.NewAggregateBucket(qr, schema, keys, values))
+	}
+	return resp, nil
+}
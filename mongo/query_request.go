@@ -0,0 +1,233 @@
+package mongo
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/space-ark-x/infra-common/dto"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// FromQueryRequest 按schema校验查询请求，并将条件、排序、分页、游标与返回字段写入查询构建器
+// 只有schema中声明的字段可以查询与排序，字段名按FieldSchema.Path映射为存储路径
+// 校验会把qr中条件的值转换为字段类型，校验失败时返回dto.ValidationErrors且不修改qb
+// 有游标时从游标记录之后开始查询，忽略Page
+//
+//	qr, err := dto.ParseQueryRequest(ctx.Request().URL.RawQuery)
+//	...
+//	qb, err = mongo.FromQueryRequest(mongo.NewQueryBuilder(client, "app"), qr, userSchema)
+//	if err != nil {
+//		...
+//	}
+//	err = qb.Find("users", &users)
+func FromQueryRequest(qb *QueryBuilder, qr *dto.QueryRequest, schema *dto.QuerySchema) (*QueryBuilder, error) {
+	if err := qr.Validate(schema); err != nil {
+		return qb, err
+	}
+
+	cursor, err := qr.DecodeCursor(schema)
+	if err != nil {
+		return qb, err
+	}
+	filter, err := exprFilter(qr.Expr(), schema)
+	if err != nil {
+		return qb, err
+	}
+	qb.addFilter(filter)
+
+	// 分页时追加_id作为排序的最后一个字段，保证结果顺序稳定
+	if len(qr.Order) > 0 || qr.PageSize > 0 {
+		keys := qr.CursorKeys(schema)
+		sort := make(bson.D, 0, len(keys))
+		for _, key := range keys {
+			path, desc := strings.CutPrefix(key, "-")
+			direction := 1
+			if desc {
+				direction = -1
+			}
+			sort = append(sort, bson.E{Key: path, Value: direction})
+		}
+		qb.Sort(sort)
+	}
+
+	if paths := qr.Projection(schema); paths != nil {
+		projection := make(bson.D, 0, len(paths))
+		for _, path := range paths {
+			projection = append(projection, bson.E{Key: path, Value: 1})
+		}
+		qb.Project(projection)
+	}
+
+	if cursor != nil {
+		qb.After(cursor)
+	}
+	if qr.PageSize > 0 {
+		if cursor == nil {
+			qb.Skip(int64(qr.Offset()))
+		}
+		qb.Limit(int64(qr.PageSize))
+	}
+	return qb, nil
+}
+
+// After 添加游标条件，只返回按游标的排序字段排在游标记录之后的文档
+// 条件为各排序字段的字典序范围，例如排序字段为-age、_id时为
+// {$or: [{age: {$lt: v1}}, {age: v1, _id: {$gt: v2}}]}
+func (q *QueryBuilder) After(cursor *dto.Cursor) *QueryBuilder {
+	branches := make(bson.A, 0, len(cursor.Keys))
+	for i, key := range cursor.Keys {
+		branch := make(bson.D, 0, i+1)
+		for j := range i {
+			branch = append(branch, bson.E{Key: strings.TrimPrefix(cursor.Keys[j], "-"), Value: cursor.Values[j]})
+		}
+		path, desc := strings.CutPrefix(key, "-")
+		op := "$gt"
+		if desc {
+			op = "$lt"
+		}
+		branches = append(branches, append(branch, bson.E{Key: path, Value: bson.D{{Key: op, Value: cursor.Values[i]}}}))
+	}
+	q.addFilter(bson.D{{Key: "$or", Value: branches}})
+	return q
+}
+
+// FindCursorPage 按查询请求的游标分页查询，qb通常由FromQueryRequest创建
+// 查询PageSize+1条以判断是否还有下一页
+//
+//	qb, err := mongo.FromQueryRequest(mongo.NewQueryBuilder(client, "app"), qr, userSchema)
+//	...
+//	page, err := mongo.FindCursorPage[User](qb, "users", qr, userSchema)
+func FindCursorPage[T any](qb *QueryBuilder, collection string, qr *dto.QueryRequest, schema *dto.QuerySchema) (dto.CursorListResponse[T], error) {
+	if qr.PageSize > 0 {
+		qb.Limit(int64(qr.PageSize + 1))
+	}
+	var items []T
+	if err := qb.Find(collection, &items); err != nil {
+		return dto.CursorListResponse[T]{}, err
+	}
+	return dto.NewCursorPage(items, qr, schema)
+}
+
+// addFilter 将过滤文档合并到查询条件中，字段与已有条件重复时使用$and保留两者
+func (q *QueryBuilder) addFilter(filter bson.D) {
+	if len(filter) == 0 {
+		return
+	}
+	if and, ok := filter[0].Value.(bson.A); ok && len(filter) == 1 && filter[0].Key == "$and" {
+		// 顶层and的各个子条件字段不重复时直接展开
+		var merged bson.D
+		for _, child := range and {
+			merged = append(merged, child.(bson.D)...)
+		}
+		if !hasDuplicateKeys(append(q.filter, merged...)) {
+			q.filter = append(q.filter, merged...)
+			return
+		}
+	}
+	if hasDuplicateKeys(append(q.filter, filter...)) {
+		q.filter = append(q.filter, bson.E{Key: "$and", Value: bson.A{filter}})
+		return
+	}
+	q.filter = append(q.filter, filter...)
+}
+
+// hasDuplicateKeys 判断文档中是否有重复的字段
+func hasDuplicateKeys(d bson.D) bool {
+	seen := make(map[string]struct{}, len(d))
+	for _, e := range d {
+		if _, ok := seen[e.Key]; ok {
+			return true
+		}
+		seen[e.Key] = struct{}{}
+	}
+	return false
+}
+
+// exprFilter 将过滤表达式转换为MongoDB过滤文档，expr为nil时返回nil
+func exprFilter(expr *dto.Expr, schema *dto.QuerySchema) (bson.D, error) {
+	if expr == nil {
+		return nil, nil
+	}
+	switch expr.Kind {
+	case dto.ExprCond:
+		return conditionFilter(expr.Condition, schema)
+	case dto.ExprAnd, dto.ExprOr, dto.ExprNot:
+		children := make(bson.A, 0, len(expr.Children))
+		for _, child := range expr.Children {
+			filter, err := exprFilter(child, schema)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, filter)
+		}
+		key := map[dto.ExprKind]string{dto.ExprAnd: "$and", dto.ExprOr: "$or", dto.ExprNot: "$nor"}[expr.Kind]
+		return bson.D{{Key: key, Value: children}}, nil
+	default:
+		return nil, fmt.Errorf("mongo: unsupported filter node %q", expr.Kind)
+	}
+}
+
+// comparisonOperators 直接对应MongoDB比较操作符的条件操作符
+var comparisonOperators = map[dto.Operator]string{
+	dto.NotEqual:       "$ne",
+	dto.Greater:        "$gt",
+	dto.GreaterOrEqual: "$gte",
+	dto.Less:           "$lt",
+	dto.LessOrEqual:    "$lte",
+	dto.In:             "$in",
+	dto.NotIn:          "$nin",
+	dto.All:            "$all",
+	dto.Exists:         "$exists",
+}
+
+// conditionFilter 将单个条件转换为MongoDB过滤文档
+// like类操作符的值按字面匹配，正则特殊字符会被转义
+func conditionFilter(c *dto.Condition, schema *dto.QuerySchema) (bson.D, error) {
+	path := schema.Path(c.Key)
+	field := func(value any) bson.D {
+		return bson.D{{Key: path, Value: value}}
+	}
+	regex := func(pattern string, options string) bson.D {
+		if options == "" {
+			return field(bson.D{{Key: "$regex", Value: pattern}})
+		}
+		return field(bson.D{{Key: "$regex", Value: pattern}, {Key: "$options", Value: options}})
+	}
+
+	if op, ok := comparisonOperators[c.Op]; ok {
+		return field(bson.D{{Key: op, Value: c.Value}}), nil
+	}
+	switch c.Op {
+	case dto.Equal, dto.Or, dto.Contains:
+		return field(c.Value), nil
+	case dto.Like:
+		return regex(regexp.QuoteMeta(fmt.Sprint(c.Value)), ""), nil
+	case dto.ILike:
+		return regex(regexp.QuoteMeta(fmt.Sprint(c.Value)), "i"), nil
+	case dto.StartsWith:
+		return regex("^"+regexp.QuoteMeta(fmt.Sprint(c.Value)), ""), nil
+	case dto.EndsWith:
+		return regex(regexp.QuoteMeta(fmt.Sprint(c.Value))+"$", ""), nil
+	case dto.IsNull:
+		if isNull, _ := c.Value.(bool); !isNull {
+			return field(bson.D{{Key: "$ne", Value: nil}}), nil
+		}
+		return field(nil), nil
+	case dto.Between:
+		r, ok := c.Value.(dto.Range)
+		if !ok {
+			return nil, fmt.Errorf("mongo: between value of %q is not a range", c.Key)
+		}
+		bounds := bson.D{}
+		if r.Min != nil {
+			bounds = append(bounds, bson.E{Key: "$gte", Value: r.Min})
+		}
+		if r.Max != nil {
+			bounds = append(bounds, bson.E{Key: "$lte", Value: r.Max})
+		}
+		return field(bounds), nil
+	default:
+		return nil, fmt.Errorf("mongo: unsupported operator %q", c.Op)
+	}
+}
@@ -0,0 +1,194 @@
+package mongo
+
+import (
+	"testing"
+
+	"github.com/space-ark-x/infra-common/dto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// testSchema 测试用的查询定义
+var testSchema = &dto.QuerySchema{
+	Fields: map[string]dto.FieldSchema{
+		"name":    {Type: dto.TypeString, Sortable: true},
+		"age":     {Type: dto.TypeInt, Sortable: true},
+		"city":    {Type: dto.TypeString},
+		"ownerId": {Type: dto.TypeString, Path: "owner._id"},
+		"tags":    {Type: dto.TypeString, Array: true},
+		"deleted": {Type: dto.TypeBool},
+	},
+}
+
+// TestFromQueryRequest 测试查询请求转换为查询构建器，不需要连接数据库
+func TestFromQueryRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		rawQuery  string
+		want      bson.D
+		wantSort  bson.D
+		wantSkip  int64
+		wantLimit int64
+	}{
+		{
+			name:      "比较操作符",
+			rawQuery:  "name=Alice&age_gte=18&age_lt=30",
+			want:      bson.D{{Key: "$and", Value: bson.A{bson.D{{Key: "name", Value: "Alice"}}, bson.D{{Key: "age", Value: bson.D{{Key: "$gte", Value: int64(18)}}}}, bson.D{{Key: "age", Value: bson.D{{Key: "$lt", Value: int64(30)}}}}}}},
+			wantSkip:  0,
+			wantLimit: 10,
+		},
+		{
+			name:      "字段不重复时展开",
+			rawQuery:  "name_ne=Bob&age_in=20,30&page=3&page_size=20",
+			want:      bson.D{{Key: "name", Value: bson.D{{Key: "$ne", Value: "Bob"}}}, {Key: "age", Value: bson.D{{Key: "$in", Value: []any{int64(20), int64(30)}}}}},
+			wantSkip:  40,
+			wantLimit: 20,
+		},
+		{
+			name:      "like转义正则字符",
+			rawQuery:  "name_like=a.b*",
+			want:      bson.D{{Key: "name", Value: bson.D{{Key: "$regex", Value: `a\.b\*`}}}},
+			wantLimit: 10,
+		},
+		{
+			name:      "回溯模式按字面量匹配",
+			rawQuery:  "name_like=(a%2B)%2B$&city_startswith=.*.*.*=",
+			want:      bson.D{{Key: "name", Value: bson.D{{Key: "$regex", Value: `\(a\+\)\+\$`}}}, {Key: "city", Value: bson.D{{Key: "$regex", Value: `^\.\*\.\*\.\*=`}}}},
+			wantLimit: 10,
+		},
+		{
+			name:      "ilike与前后缀",
+			rawQuery:  "filter=" + "name ilike 'al' and city startswith 'New (' and ownerId endswith '$x'",
+			want:      bson.D{{Key: "name", Value: bson.D{{Key: "$regex", Value: "al"}, {Key: "$options", Value: "i"}}}, {Key: "city", Value: bson.D{{Key: "$regex", Value: `^New \(`}}}, {Key: "owner._id", Value: bson.D{{Key: "$regex", Value: `\$x$`}}}},
+			wantLimit: 10,
+		},
+		{
+			name:      "字段路径映射",
+			rawQuery:  "ownerId=u1",
+			want:      bson.D{{Key: "owner._id", Value: "u1"}},
+			wantLimit: 10,
+		},
+		{
+			name:      "or条件",
+			rawQuery:  "city_or=Paris&city_or=Rome",
+			want:      bson.D{{Key: "$or", Value: bson.A{bson.D{{Key: "city", Value: "Paris"}}, bson.D{{Key: "city", Value: "Rome"}}}}},
+			wantLimit: 10,
+		},
+		{
+			name:      "not与between",
+			rawQuery:  "filter=" + "not (age between 10 and null or deleted isnull true)",
+			want:      bson.D{{Key: "$nor", Value: bson.A{bson.D{{Key: "$or", Value: bson.A{bson.D{{Key: "age", Value: bson.D{{Key: "$gte", Value: int64(10)}}}}, bson.D{{Key: "deleted", Value: nil}}}}}}}},
+			wantLimit: 10,
+		},
+		{
+			name:      "数组与存在性",
+			rawQuery:  "tags_contains=go&tags_all=a,b&city_exists=false&deleted_isnull=false",
+			want:      bson.D{{Key: "$and", Value: bson.A{bson.D{{Key: "tags", Value: "go"}}, bson.D{{Key: "tags", Value: bson.D{{Key: "$all", Value: []any{"a", "b"}}}}}, bson.D{{Key: "city", Value: bson.D{{Key: "$exists", Value: false}}}}, bson.D{{Key: "deleted", Value: bson.D{{Key: "$ne", Value: nil}}}}}}},
+			wantLimit: 10,
+		},
+		{
+			name:      "排序",
+			rawQuery:  "sort=age.desc,name.asc",
+			want:      bson.D{},
+			wantSort:  bson.D{{Key: "age", Value: -1}, {Key: "name", Value: 1}, {Key: "_id", Value: 1}},
+			wantLimit: 10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qr, err := dto.ParseQueryRequest(tt.rawQuery)
+			require.NoError(t, err)
+
+			qb, err := FromQueryRequest(NewQueryBuilder(nil, "test_db"), qr, testSchema)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, qb.filter)
+			wantSort := tt.wantSort
+			if wantSort == nil {
+				// 分页时总是以_id作为最后的排序字段
+				wantSort = bson.D{{Key: "_id", Value: 1}}
+			}
+			assert.Equal(t, wantSort, qb.sort)
+			require.NotNil(t, qb.skip)
+			require.NotNil(t, qb.limit)
+			assert.Equal(t, tt.wantSkip, *qb.skip)
+			assert.Equal(t, tt.wantLimit, *qb.limit)
+		})
+	}
+}
+
+// TestFromQueryRequestRejects 测试未声明的字段与不允许的操作符
+func TestFromQueryRequestRejects(t *testing.T) {
+	for _, rawQuery := range []string{"password=x", "city_gt=a", "sort=city.asc", "filter=secret eq 1"} {
+		t.Run(rawQuery, func(t *testing.T) {
+			qr, err := dto.ParseQueryRequest(rawQuery)
+			require.NoError(t, err)
+
+			qb := NewQueryBuilder(nil, "test_db")
+			_, err = FromQueryRequest(qb, qr, testSchema)
+			var validationErrs dto.ValidationErrors
+			assert.ErrorAs(t, err, &validationErrs)
+			assert.Empty(t, qb.filter)
+			assert.Nil(t, qb.limit)
+		})
+	}
+}
+
+// TestFromQueryRequestKeepsExistingFilter 测试与已有条件的字段重复时使用$and
+func TestFromQueryRequestKeepsExistingFilter(t *testing.T) {
+	qr, err := dto.ParseQueryRequest("name=Alice")
+	require.NoError(t, err)
+
+	qb, err := FromQueryRequest(NewQueryBuilder(nil, "test_db").Eq("tenant", "t1").Ne("name", "root"), qr, testSchema)
+	require.NoError(t, err)
+	assert.Equal(t, bson.D{
+		{Key: "tenant", Value: "t1"},
+		{Key: "name", Value: bson.D{{Key: "$ne", Value: "root"}}},
+		{Key: "$and", Value: bson.A{bson.D{{Key: "name", Value: "Alice"}}}},
+	}, qb.filter)
+}
+
+// TestFromQueryRequestCursor 测试游标转换为排序字段的范围条件
+func TestFromQueryRequestCursor(t *testing.T) {
+	dto.SetCursorSecret([]byte("test-secret"))
+	cursor, err := (&dto.Cursor{Keys: []string{"-age", "_id"}, Values: []any{int64(30), int64(2)}}).Encode()
+	require.NoError(t, err)
+	qr, err := dto.ParseQueryRequest("name_ne=root&sort=age.desc&page=3&page_size=2&cursor=" + cursor)
+	require.NoError(t, err)
+
+	qb, err := FromQueryRequest(NewQueryBuilder(nil, "test_db"), qr, testSchema)
+	require.NoError(t, err)
+	assert.Equal(t, bson.D{
+		{Key: "name", Value: bson.D{{Key: "$ne", Value: "root"}}},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "age", Value: bson.D{{Key: "$lt", Value: int64(30)}}}},
+			bson.D{{Key: "age", Value: int64(30)}, {Key: "_id", Value: bson.D{{Key: "$gt", Value: int64(2)}}}},
+		}},
+	}, qb.filter)
+	assert.Equal(t, bson.D{{Key: "age", Value: -1}, {Key: "_id", Value: 1}}, qb.sort)
+	assert.Nil(t, qb.skip, "游标分页忽略page")
+	require.NotNil(t, qb.limit)
+	assert.Equal(t, int64(2), *qb.limit)
+
+	// 游标与排序不一致
+	qr, err = dto.ParseQueryRequest("sort=name.asc&cursor=" + cursor)
+	require.NoError(t, err)
+	_, err = FromQueryRequest(NewQueryBuilder(nil, "test_db"), qr, testSchema)
+	var validationErrs dto.ValidationErrors
+	assert.ErrorAs(t, err, &validationErrs)
+}
+
+// TestFromQueryRequestProjection 测试fields参数转换为投影
+func TestFromQueryRequestProjection(t *testing.T) {
+	qr, err := dto.ParseQueryRequest("fields=name,ownerId&sort=age.desc")
+	require.NoError(t, err)
+
+	qb, err := FromQueryRequest(NewQueryBuilder(nil, "test_db"), qr, testSchema)
+	require.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "name", Value: 1}, {Key: "owner._id", Value: 1}, {Key: "age", Value: 1}}, qb.projection, "排序字段用于生成游标")
+
+	// 未指定fields时返回完整文档
+	qb, err = FromQueryRequest(NewQueryBuilder(nil, "test_db"), &dto.QueryRequest{}, testSchema)
+	require.NoError(t, err)
+	assert.Nil(t, qb.projection)
+}
@@ -0,0 +1,32 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/space-ark-x/infra-common/dto"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NewRelationLoader 返回按field批量查询集合的加载函数，用于dto.Relation.Load
+// 每次加载只执行一次{field: {$in: keys}}查询
+//
+//	postSchema.Relations = map[string]*dto.Relation{
+//		"owner": {LocalField: "ownerId", Schema: userSchema, Load: mongo.NewRelationLoader(client, "app", "users", "_id")},
+//	}
+func NewRelationLoader(client *mongo.Client, database, collection, field string) dto.LoadFunc {
+	return func(ctx context.Context, keys []any) ([]map[string]any, error) {
+		coll := client.Database(database).Collection(collection)
+		cursor, err := coll.Find(ctx, bson.D{{Key: field, Value: bson.D{{Key: "$in", Value: keys}}}})
+		if err != nil {
+			return nil, err
+		}
+		defer cursor.Close(ctx)
+
+		var docs []map[string]any
+		if err = cursor.All(ctx, &docs); err != nil {
+			return nil, err
+		}
+		return docs, nil
+	}
+}
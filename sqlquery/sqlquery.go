@@ -0,0 +1,274 @@
+// Package sqlquery 将dto.QueryRequest编译为参数化的SQL片段
+//
+//	q, err := sqlquery.Build(sqlquery.MySQL, qr, userSchema)
+//	if err != nil {
+//		...
+//	}
+//	fragment, args := q.SQL()
+//	rows, err := db.QueryContext(ctx, "SELECT id, name FROM users"+fragment, args...)
+package sqlquery
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/space-ark-x/infra-common/dto"
+)
+
+// Dialect SQL方言
+type Dialect string
+
+const (
+	MySQL      Dialect = "mysql"    // MySQL，占位符为?，标识符使用反引号
+	PostgreSQL Dialect = "postgres" // PostgreSQL，占位符为$n，标识符使用双引号
+)
+
+// QuoteIdent 按方言引用标识符，table.column形式的各部分分别引用
+func (d Dialect) QuoteIdent(name string) string {
+	quote := `"`
+	if d == MySQL {
+		quote = "`"
+	}
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = quote + strings.ReplaceAll(part, quote, quote+quote) + quote
+	}
+	return strings.Join(parts, ".")
+}
+
+// Placeholder 返回第n个参数的占位符，n从1开始
+func (d Dialect) Placeholder(n int) string {
+	if d == PostgreSQL {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// Query 编译后的查询片段
+type Query struct {
+	Where   string  // 不含WHERE关键字的条件，没有条件时为空
+	Args    []any   // Where中占位符对应的参数
+	OrderBy string  // 不含ORDER BY关键字的排序，没有排序时为空
+	Limit   int     // 每页数量，为0时不分页
+	Offset  int     // 跳过的行数
+	dialect Dialect // 生成占位符的方言
+}
+
+// SQL 返回以空格开头的WHERE … ORDER BY … LIMIT … OFFSET片段及全部参数
+// LIMIT与OFFSET同样使用占位符，参数排在Args之后
+func (q *Query) SQL() (string, []any) {
+	var b strings.Builder
+	args := append([]any(nil), q.Args...)
+	if q.Where != "" {
+		b.WriteString(" WHERE ")
+		b.WriteString(q.Where)
+	}
+	if q.OrderBy != "" {
+		b.WriteString(" ORDER BY ")
+		b.WriteString(q.OrderBy)
+	}
+	if q.Limit > 0 {
+		b.WriteString(" LIMIT " + q.dialect.Placeholder(len(args)+1))
+		b.WriteString(" OFFSET " + q.dialect.Placeholder(len(args)+2))
+		args = append(args, q.Limit, q.Offset)
+	}
+	return b.String(), args
+}
+
+// Build 按schema校验查询请求并编译为查询片段
+// 只有schema中声明的字段可以查询与排序，字段名按FieldSchema.Path映射为列名
+// 校验会把qr中条件的值转换为字段类型，校验失败时返回dto.ValidationErrors
+// 数组字段的contains与all在MySQL中按JSON列处理，在PostgreSQL中按数组列处理
+// 不支持游标分页、fields字段选择与分组聚合请求，包含这些参数时返回错误而不是忽略
+func Build(dialect Dialect, qr *dto.QueryRequest, schema *dto.QuerySchema) (*Query, error) {
+	if dialect != MySQL && dialect != PostgreSQL {
+		return nil, fmt.Errorf("sqlquery: unsupported dialect %q", dialect)
+	}
+	switch {
+	case qr.Cursor != "":
+		return nil, fmt.Errorf("sqlquery: cursor pagination is not supported")
+	case len(qr.Fields) > 0:
+		return nil, fmt.Errorf("sqlquery: fields selection is not supported")
+	case qr.IsAggregate():
+		return nil, fmt.Errorf("sqlquery: aggregate requests are not supported")
+	}
+	if err := qr.Validate(schema); err != nil {
+		return nil, err
+	}
+
+	c := &compiler{dialect: dialect, schema: schema}
+	where, err := c.expr(qr.Expr(), false)
+	if err != nil {
+		return nil, err
+	}
+
+	orders := make([]string, 0, len(qr.Order))
+	for _, order := range qr.Order {
+		direction := "ASC"
+		if order.Direction == dto.Descending {
+			direction = "DESC"
+		}
+		orders = append(orders, dialect.QuoteIdent(schema.Path(order.Key))+" "+direction)
+	}
+
+	q := &Query{
+		Where:   where,
+		Args:    c.args,
+		OrderBy: strings.Join(orders, ", "),
+		dialect: dialect,
+	}
+	if qr.PageSize > 0 {
+		q.Limit = qr.PageSize
+		q.Offset = qr.Offset()
+	}
+	return q, nil
+}
+
+// compiler 编译过滤表达式，收集参数
+type compiler struct {
+	dialect Dialect
+	schema  *dto.QuerySchema
+	args    []any
+}
+
+// arg 添加参数并返回其占位符
+func (c *compiler) arg(value any) string {
+	c.args = append(c.args, value)
+	return c.dialect.Placeholder(len(c.args))
+}
+
+// list 添加列表中的每个值并返回逗号分隔的占位符
+func (c *compiler) list(value any) string {
+	values, ok := value.([]any)
+	if !ok {
+		values = []any{value}
+	}
+	placeholders := make([]string, 0, len(values))
+	for _, v := range values {
+		placeholders = append(placeholders, c.arg(v))
+	}
+	return strings.Join(placeholders, ", ")
+}
+
+// expr 编译表达式，nested为true时and、or加括号
+func (c *compiler) expr(expr *dto.Expr, nested bool) (string, error) {
+	if expr == nil {
+		return "", nil
+	}
+	switch expr.Kind {
+	case dto.ExprCond:
+		return c.condition(expr.Condition)
+	case dto.ExprNot:
+		child, err := c.expr(expr.Children[0], true)
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + child, nil
+	case dto.ExprAnd, dto.ExprOr:
+		parts := make([]string, 0, len(expr.Children))
+		for _, child := range expr.Children {
+			part, err := c.expr(child, true)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, part)
+		}
+		sql := strings.Join(parts, " "+strings.ToUpper(string(expr.Kind))+" ")
+		if nested {
+			sql = "(" + sql + ")"
+		}
+		return sql, nil
+	default:
+		return "", fmt.Errorf("sqlquery: unsupported filter node %q", expr.Kind)
+	}
+}
+
+// comparisonOperators 直接对应SQL比较运算符的条件操作符
+var comparisonOperators = map[dto.Operator]string{
+	dto.Equal:          "=",
+	dto.Or:             "=",
+	dto.NotEqual:       "<>",
+	dto.Greater:        ">",
+	dto.GreaterOrEqual: ">=",
+	dto.Less:           "<",
+	dto.LessOrEqual:    "<=",
+}
+
+// condition 编译单个条件
+// like类操作符的值按字面匹配，%、_与\会被转义
+func (c *compiler) condition(cond *dto.Condition) (string, error) {
+	column := c.dialect.QuoteIdent(c.schema.Path(cond.Key))
+	if op, ok := comparisonOperators[cond.Op]; ok {
+		return column + " " + op + " " + c.arg(cond.Value), nil
+	}
+
+	switch cond.Op {
+	case dto.In, dto.NotIn:
+		if values, ok := cond.Value.([]any); ok && len(values) == 0 {
+			// 空列表：IN永远不满足，NOT IN永远满足
+			if cond.Op == dto.In {
+				return "1 = 0", nil
+			}
+			return "1 = 1", nil
+		}
+		keyword := " IN ("
+		if cond.Op == dto.NotIn {
+			keyword = " NOT IN ("
+		}
+		return column + keyword + c.list(cond.Value) + ")", nil
+	case dto.Like:
+		return column + " LIKE " + c.arg("%"+escapeLike(cond.Value)+"%"), nil
+	case dto.StartsWith:
+		return column + " LIKE " + c.arg(escapeLike(cond.Value)+"%"), nil
+	case dto.EndsWith:
+		return column + " LIKE " + c.arg("%"+escapeLike(cond.Value)), nil
+	case dto.ILike:
+		pattern := "%" + escapeLike(cond.Value) + "%"
+		if c.dialect == PostgreSQL {
+			return column + " ILIKE " + c.arg(pattern), nil
+		}
+		return "LOWER(" + column + ") LIKE LOWER(" + c.arg(pattern) + ")", nil
+	case dto.Between:
+		r, ok := cond.Value.(dto.Range)
+		if !ok {
+			return "", fmt.Errorf("sqlquery: between value of %q is not a range", cond.Key)
+		}
+		switch {
+		case r.Min == nil:
+			return column + " <= " + c.arg(r.Max), nil
+		case r.Max == nil:
+			return column + " >= " + c.arg(r.Min), nil
+		}
+		return column + " BETWEEN " + c.arg(r.Min) + " AND " + c.arg(r.Max), nil
+	case dto.Exists, dto.IsNull:
+		// SQL中列总是存在，exists按非NULL处理
+		isNull, _ := cond.Value.(bool)
+		if cond.Op == dto.Exists {
+			isNull = !isNull
+		}
+		if isNull {
+			return column + " IS NULL", nil
+		}
+		return column + " IS NOT NULL", nil
+	case dto.Contains, dto.All:
+		if values, ok := cond.Value.([]any); ok && len(values) == 0 {
+			// 空数组没有可以推断的元素类型，ARRAY[]在PostgreSQL中无法执行
+			return "", fmt.Errorf("sqlquery: %s value of %q is empty", cond.Op, cond.Key)
+		}
+		if c.dialect == PostgreSQL {
+			return column + " @> ARRAY[" + c.list(cond.Value) + "]", nil
+		}
+		return "JSON_CONTAINS(" + column + ", JSON_ARRAY(" + c.list(cond.Value) + "))", nil
+	default:
+		return "", fmt.Errorf("sqlquery: unsupported operator %q", cond.Op)
+	}
+}
+
+// likeEscaper 转义LIKE模式中的通配符，使用两种数据库默认的转义字符\
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike 转义LIKE模式中的通配符
+func escapeLike(value any) string {
+	return likeEscaper.Replace(fmt.Sprint(value))
+}
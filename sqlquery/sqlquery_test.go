@@ -0,0 +1,162 @@
+package sqlquery
+
+import (
+	"testing"
+
+	"github.com/space-ark-x/infra-common/dto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSchema 测试用的查询定义
+var testSchema = &dto.QuerySchema{
+	Fields: map[string]dto.FieldSchema{
+		"name":    {Type: dto.TypeString, Sortable: true},
+		"age":     {Type: dto.TypeInt, Sortable: true},
+		"city":    {Type: dto.TypeString},
+		"ownerId": {Type: dto.TypeString, Path: "u.owner_id"},
+		"tags":    {Type: dto.TypeString, Array: true},
+		"deleted": {Type: dto.TypeBool},
+	},
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name     string
+		rawQuery string
+		dialect  Dialect
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name:     "mysql comparisons",
+			rawQuery: "name=Alice&age_gte=18&age_lt=30&page=3&page_size=20",
+			dialect:  MySQL,
+			wantSQL:  " WHERE `name` = ? AND `age` >= ? AND `age` < ? LIMIT ? OFFSET ?",
+			wantArgs: []any{"Alice", int64(18), int64(30), 20, 40},
+		},
+		{
+			name:     "postgres numbered placeholders",
+			rawQuery: "name=Alice&age_gte=18&page=3&page_size=20",
+			dialect:  PostgreSQL,
+			wantSQL:  ` WHERE "name" = $1 AND "age" >= $2 LIMIT $3 OFFSET $4`,
+			wantArgs: []any{"Alice", int64(18), 20, 40},
+		},
+		{
+			name:     "in and nin",
+			rawQuery: "age_in=1,2&city_nin=Paris",
+			dialect:  PostgreSQL,
+			wantSQL:  ` WHERE "age" IN ($1, $2) AND "city" NOT IN ($3) LIMIT $4 OFFSET $5`,
+			wantArgs: []any{int64(1), int64(2), "Paris", 10, 0},
+		},
+		{
+			name:     "like escapes wildcards",
+			rawQuery: "name_like=50%25_off&city_startswith=New&ownerId_endswith=x",
+			dialect:  MySQL,
+			wantSQL:  " WHERE `name` LIKE ? AND `city` LIKE ? AND `u`.`owner_id` LIKE ? LIMIT ? OFFSET ?",
+			wantArgs: []any{`%50\%\_off%`, "New%", "%x", 10, 0},
+		},
+		{
+			name:     "ilike per dialect",
+			rawQuery: "name_ilike=al",
+			dialect:  MySQL,
+			wantSQL:  " WHERE LOWER(`name`) LIKE LOWER(?) LIMIT ? OFFSET ?",
+			wantArgs: []any{"%al%", 10, 0},
+		},
+		{
+			name:     "postgres ilike",
+			rawQuery: "name_ilike=al",
+			dialect:  PostgreSQL,
+			wantSQL:  ` WHERE "name" ILIKE $1 LIMIT $2 OFFSET $3`,
+			wantArgs: []any{"%al%", 10, 0},
+		},
+		{
+			name:     "filter expression",
+			rawQuery: "filter=" + "not (age between 10 and 20 or deleted isnull true) and city exists true",
+			dialect:  PostgreSQL,
+			wantSQL:  ` WHERE NOT ("age" BETWEEN $1 AND $2 OR "deleted" IS NULL) AND "city" IS NOT NULL LIMIT $3 OFFSET $4`,
+			wantArgs: []any{int64(10), int64(20), 10, 0},
+		},
+		{
+			name:     "or conditions",
+			rawQuery: "city_or=Paris&city_or=Rome&age_between=,30",
+			dialect:  MySQL,
+			wantSQL:  " WHERE `age` <= ? AND (`city` = ? OR `city` = ?) LIMIT ? OFFSET ?",
+			wantArgs: []any{int64(30), "Paris", "Rome", 10, 0},
+		},
+		{
+			name:     "mysql json arrays",
+			rawQuery: "tags_contains=go&tags_all=a,b",
+			dialect:  MySQL,
+			wantSQL:  " WHERE JSON_CONTAINS(`tags`, JSON_ARRAY(?)) AND JSON_CONTAINS(`tags`, JSON_ARRAY(?, ?)) LIMIT ? OFFSET ?",
+			wantArgs: []any{"go", "a", "b", 10, 0},
+		},
+		{
+			name:     "postgres arrays",
+			rawQuery: "tags_all=a,b",
+			dialect:  PostgreSQL,
+			wantSQL:  ` WHERE "tags" @> ARRAY[$1, $2] LIMIT $3 OFFSET $4`,
+			wantArgs: []any{"a", "b", 10, 0},
+		},
+		{
+			name:     "order by",
+			rawQuery: "sort=age.desc,name.asc",
+			dialect:  MySQL,
+			wantSQL:  " ORDER BY `age` DESC, `name` ASC LIMIT ? OFFSET ?",
+			wantArgs: []any{10, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qr, err := dto.ParseQueryRequest(tt.rawQuery)
+			require.NoError(t, err)
+
+			q, err := Build(tt.dialect, qr, testSchema)
+			require.NoError(t, err)
+			sql, args := q.SQL()
+			assert.Equal(t, tt.wantSQL, sql)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+func TestBuildRejects(t *testing.T) {
+	for _, rawQuery := range []string{"password=x", "city_gt=a", "sort=city.asc", "filter=secret eq 1", "age=abc", "page=4611686018427387904&page_size=10"} {
+		t.Run(rawQuery, func(t *testing.T) {
+			qr, err := dto.ParseQueryRequest(rawQuery)
+			require.NoError(t, err)
+
+			_, err = Build(MySQL, qr, testSchema)
+			var validationErrs dto.ValidationErrors
+			assert.ErrorAs(t, err, &validationErrs)
+		})
+	}
+}
+
+func TestBuildUnsupported(t *testing.T) {
+	tests := []struct {
+		name    string
+		qr      *dto.QueryRequest
+		wantErr string
+	}{
+		{name: "cursor", qr: &dto.QueryRequest{Cursor: "abc"}, wantErr: "sqlquery: cursor pagination is not supported"},
+		{name: "fields", qr: &dto.QueryRequest{Fields: []string{"name"}}, wantErr: "sqlquery: fields selection is not supported"},
+		{name: "aggregate", qr: &dto.QueryRequest{GroupBy: []string{"city"}}, wantErr: "sqlquery: aggregate requests are not supported"},
+		{
+			name:    "empty contains list",
+			qr:      &dto.QueryRequest{Condition: []dto.Condition{{Key: "tags", Op: dto.All, Value: []any{}}}},
+			wantErr: `sqlquery: all value of "tags" is empty`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Build(PostgreSQL, tt.qr, testSchema)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestQuoteIdent(t *testing.T) {
+	assert.Equal(t, "`a``b`.`c`", MySQL.QuoteIdent("a`b.c"))
+	assert.Equal(t, `"a""b"`, PostgreSQL.QuoteIdent(`a"b`))
+}